@@ -0,0 +1,13 @@
+package chi
+
+import (
+	"net/http"
+
+	"github.com/barisgit/goflux"
+)
+
+// CompressionMiddleware creates a Chi middleware using the shared compression logic
+// Responses are compressed with gzip or brotli when the client's Accept-Encoding allows it
+func CompressionMiddleware(compressor *goflux.Compressor) func(http.Handler) http.Handler {
+	return compressor.Handler
+}
@@ -0,0 +1,35 @@
+package chi
+
+import (
+	"net/http"
+
+	"github.com/barisgit/goflux"
+)
+
+// CORSMiddleware creates a Chi middleware using the shared CORS logic
+// Bind the same policy to the API with goflux.UseCORS so procedures can override it
+// Preflight requests are answered directly, actual requests get CORS headers before reaching the router
+func CORSMiddleware(policy *goflux.CORSPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response := policy.Evaluate(goflux.CORSRequest{
+				Method:                      r.Method,
+				Path:                        r.URL.Path,
+				Origin:                      r.Header.Get("Origin"),
+				AccessControlRequestMethod:  r.Header.Get("Access-Control-Request-Method"),
+				AccessControlRequestHeaders: r.Header.Get("Access-Control-Request-Headers"),
+			})
+
+			for key, value := range response.Headers {
+				w.Header().Set(key, value)
+			}
+
+			if response.Preflight {
+				w.WriteHeader(response.StatusCode)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -10,7 +10,10 @@ import (
 // StaticHandler creates a Chi handler using the shared static logic
 func StaticHandler(assets embed.FS, config goflux.StaticConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		response := goflux.ServeStaticFile(assets, config, r.URL.Path)
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           r.URL.Path,
+			AcceptEncoding: r.Header.Get("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			http.NotFound(w, r)
@@ -19,6 +22,12 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) http.HandlerFunc
 
 		w.Header().Set("Content-Type", response.ContentType)
 		w.Header().Set("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			w.Header().Set("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			w.Header().Add("Vary", response.Vary)
+		}
 		w.WriteHeader(response.StatusCode)
 		w.Write(response.Body)
 	}
@@ -0,0 +1,29 @@
+package echo
+
+import (
+	"github.com/barisgit/goflux"
+	"github.com/labstack/echo/v4"
+)
+
+// CompressionMiddleware creates an Echo middleware using the shared compression logic
+// Responses are compressed with gzip or brotli when the client's Accept-Encoding allows it
+func CompressionMiddleware(compressor *goflux.Compressor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Header.Get("Upgrade") != "" {
+				return next(c)
+			}
+
+			original := c.Response().Writer
+			writer := compressor.NewResponseWriter(original, req)
+			c.Response().Writer = writer
+			defer func() {
+				writer.Close()
+				c.Response().Writer = original
+			}()
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package echo
+
+import (
+	"github.com/barisgit/goflux"
+	"github.com/labstack/echo/v4"
+)
+
+// CORSMiddleware creates an Echo middleware using the shared CORS logic
+// Bind the same policy to the API with goflux.UseCORS so procedures can override it
+func CORSMiddleware(policy *goflux.CORSPolicy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			response := policy.Evaluate(goflux.CORSRequest{
+				Method:                      req.Method,
+				Path:                        req.URL.Path,
+				Origin:                      req.Header.Get("Origin"),
+				AccessControlRequestMethod:  req.Header.Get("Access-Control-Request-Method"),
+				AccessControlRequestHeaders: req.Header.Get("Access-Control-Request-Headers"),
+			})
+
+			for key, value := range response.Headers {
+				c.Response().Header().Set(key, value)
+			}
+
+			if response.Preflight {
+				return c.NoContent(response.StatusCode)
+			}
+
+			return next(c)
+		}
+	}
+}
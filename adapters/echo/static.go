@@ -10,7 +10,10 @@ import (
 // StaticHandler creates an Echo handler using the shared static logic
 func StaticHandler(assets embed.FS, config goflux.StaticConfig) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		response := goflux.ServeStaticFile(assets, config, c.Request().URL.Path)
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           c.Request().URL.Path,
+			AcceptEncoding: c.Request().Header.Get("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			return c.NoContent(404)
@@ -18,6 +21,12 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) echo.HandlerFunc
 
 		c.Response().Header().Set("Content-Type", response.ContentType)
 		c.Response().Header().Set("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			c.Response().Header().Set("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			c.Response().Header().Add("Vary", response.Vary)
+		}
 		c.Response().WriteHeader(response.StatusCode)
 		c.Response().Write(response.Body)
 		return nil
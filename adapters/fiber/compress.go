@@ -0,0 +1,42 @@
+package fiber
+
+import (
+	"github.com/barisgit/goflux"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CompressionMiddleware creates a Fiber middleware using the shared compression logic
+// Fiber buffers responses, so the complete body is compressed once the handler returns.
+// Streamed bodies are left untouched
+func CompressionMiddleware(compressor *goflux.Compressor) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		response := c.Response()
+		if response.IsBodyStream() || c.Get("Upgrade") != "" {
+			return nil
+		}
+
+		result := compressor.Apply(goflux.CompressionRequest{
+			Method:          c.Method(),
+			AcceptEncoding:  c.Get("Accept-Encoding"),
+			Status:          response.StatusCode(),
+			ContentType:     string(response.Header.ContentType()),
+			ContentEncoding: string(response.Header.ContentEncoding()),
+		}, response.Body())
+
+		if result.Vary {
+			c.Vary("Accept-Encoding")
+		}
+		if result.Encoding != "" {
+			response.SetBody(result.Body)
+			c.Set("Content-Encoding", result.Encoding)
+			if tag := c.GetRespHeader("ETag"); tag != "" {
+				c.Set("ETag", goflux.EncodeETag(tag, result.Encoding))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,30 @@
+package fiber
+
+import (
+	"github.com/barisgit/goflux"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CORSMiddleware creates a Fiber middleware using the shared CORS logic
+// Bind the same policy to the API with goflux.UseCORS so procedures can override it
+func CORSMiddleware(policy *goflux.CORSPolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		response := policy.Evaluate(goflux.CORSRequest{
+			Method:                      c.Method(),
+			Path:                        c.Path(),
+			Origin:                      c.Get("Origin"),
+			AccessControlRequestMethod:  c.Get("Access-Control-Request-Method"),
+			AccessControlRequestHeaders: c.Get("Access-Control-Request-Headers"),
+		})
+
+		for key, value := range response.Headers {
+			c.Set(key, value)
+		}
+
+		if response.Preflight {
+			return c.SendStatus(response.StatusCode)
+		}
+
+		return c.Next()
+	}
+}
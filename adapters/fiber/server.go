@@ -0,0 +1,27 @@
+package fiber
+
+import (
+	"context"
+	"net"
+
+	"github.com/barisgit/goflux"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Server lets goflux.Serve start and gracefully shut down a Fiber app
+// Example: goflux.Serve(ctx, api, goflux.ServeOptions{Server: gofluxfiber.Server(app)})
+func Server(app *fiber.App) goflux.Server {
+	return &server{app: app}
+}
+
+type server struct {
+	app *fiber.App
+}
+
+func (s *server) Serve(listener net.Listener) error {
+	return s.app.Listener(listener)
+}
+
+func (s *server) Shutdown(ctx context.Context) error {
+	return s.app.ShutdownWithContext(ctx)
+}
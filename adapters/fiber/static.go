@@ -10,7 +10,10 @@ import (
 // StaticHandler creates a Fiber handler using the shared static logic
 func StaticHandler(assets embed.FS, config goflux.StaticConfig) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		response := goflux.ServeStaticFile(assets, config, c.Path())
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           c.Path(),
+			AcceptEncoding: c.Get("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			return c.SendStatus(404)
@@ -18,6 +21,12 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) fiber.Handler {
 
 		c.Set("Content-Type", response.ContentType)
 		c.Set("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			c.Set("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			c.Vary(response.Vary)
+		}
 		c.Status(response.StatusCode)
 		return c.Send(response.Body)
 	}
@@ -0,0 +1,61 @@
+package gin
+
+import (
+	"bufio"
+	"net"
+
+	"github.com/barisgit/goflux"
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionMiddleware creates a Gin middleware using the shared compression logic
+// Responses are compressed with gzip or brotli when the client's Accept-Encoding allows it
+func CompressionMiddleware(compressor *goflux.Compressor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &compressWriter{ResponseWriter: original, compressed: compressor.NewResponseWriter(original, c.Request)}
+		c.Writer = writer
+		defer func() {
+			writer.compressed.Close()
+			c.Writer = original
+		}()
+
+		c.Next()
+	}
+}
+
+// compressWriter routes the body of a gin.ResponseWriter through the compressing writer
+type compressWriter struct {
+	gin.ResponseWriter
+	compressed *goflux.CompressionWriter
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	w.compressed.WriteHeader(status)
+}
+
+// WriteHeaderNow is used by gin for responses without a body, e.g. AbortWithStatus
+func (w *compressWriter) WriteHeaderNow() {
+	_ = w.compressed.Commit()
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	return w.compressed.Write(p)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.compressed.WriteString(s)
+}
+
+func (w *compressWriter) Flush() {
+	w.compressed.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.compressed.Hijack()
+}
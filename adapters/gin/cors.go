@@ -0,0 +1,31 @@
+package gin
+
+import (
+	"github.com/barisgit/goflux"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware creates a Gin middleware using the shared CORS logic
+// Bind the same policy to the API with goflux.UseCORS so procedures can override it
+func CORSMiddleware(policy *goflux.CORSPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response := policy.Evaluate(goflux.CORSRequest{
+			Method:                      c.Request.Method,
+			Path:                        c.Request.URL.Path,
+			Origin:                      c.GetHeader("Origin"),
+			AccessControlRequestMethod:  c.GetHeader("Access-Control-Request-Method"),
+			AccessControlRequestHeaders: c.GetHeader("Access-Control-Request-Headers"),
+		})
+
+		for key, value := range response.Headers {
+			c.Header(key, value)
+		}
+
+		if response.Preflight {
+			c.AbortWithStatus(response.StatusCode)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -10,7 +10,10 @@ import (
 // StaticHandler creates a Gin handler using the shared static logic
 func StaticHandler(assets embed.FS, config goflux.StaticConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		response := goflux.ServeStaticFile(assets, config, c.Request.URL.Path)
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           c.Request.URL.Path,
+			AcceptEncoding: c.GetHeader("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			c.AbortWithStatus(404)
@@ -19,6 +22,12 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) gin.HandlerFunc
 
 		c.Header("Content-Type", response.ContentType)
 		c.Header("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			c.Header("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			c.Writer.Header().Add("Vary", response.Vary)
+		}
 		c.Data(response.StatusCode, response.ContentType, response.Body)
 	}
 }
@@ -0,0 +1,19 @@
+package nethttp
+
+import (
+	"net/http"
+
+	"github.com/barisgit/goflux"
+)
+
+// CompressionHandler wraps a net/http handler with the shared compression logic
+// Responses are compressed with gzip or brotli when the client's Accept-Encoding allows it
+func CompressionHandler(compressor *goflux.Compressor, next http.Handler) http.Handler {
+	return compressor.Handler(next)
+}
+
+// CompressionMiddleware creates a net/http middleware using the shared compression logic
+// Alternative signature for routers that accept func(http.Handler) http.Handler (e.g. gorilla mux)
+func CompressionMiddleware(compressor *goflux.Compressor) func(http.Handler) http.Handler {
+	return compressor.Handler
+}
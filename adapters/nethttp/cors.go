@@ -0,0 +1,41 @@
+package nethttp
+
+import (
+	"net/http"
+
+	"github.com/barisgit/goflux"
+)
+
+// CORSHandler wraps a net/http handler with the shared CORS logic
+// Compatible with standard library mux, gorilla mux, and fasthttp (via adapter)
+// Bind the same policy to the API with goflux.UseCORS so procedures can override it
+func CORSHandler(policy *goflux.CORSPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := policy.Evaluate(goflux.CORSRequest{
+			Method:                      r.Method,
+			Path:                        r.URL.Path,
+			Origin:                      r.Header.Get("Origin"),
+			AccessControlRequestMethod:  r.Header.Get("Access-Control-Request-Method"),
+			AccessControlRequestHeaders: r.Header.Get("Access-Control-Request-Headers"),
+		})
+
+		for key, value := range response.Headers {
+			w.Header().Set(key, value)
+		}
+
+		if response.Preflight {
+			w.WriteHeader(response.StatusCode)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORSMiddleware creates a net/http middleware using the shared CORS logic
+// Alternative signature for routers that accept func(http.Handler) http.Handler (e.g. gorilla mux)
+func CORSMiddleware(policy *goflux.CORSPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return CORSHandler(policy, next)
+	}
+}
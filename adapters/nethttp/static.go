@@ -11,7 +11,10 @@ import (
 // Compatible with standard library mux, gorilla mux, and fasthttp (via adapter)
 func StaticHandler(assets embed.FS, config goflux.StaticConfig) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := goflux.ServeStaticFile(assets, config, r.URL.Path)
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           r.URL.Path,
+			AcceptEncoding: r.Header.Get("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			http.NotFound(w, r)
@@ -20,6 +23,12 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) http.Handler {
 
 		w.Header().Set("Content-Type", response.ContentType)
 		w.Header().Set("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			w.Header().Set("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			w.Header().Add("Vary", response.Vary)
+		}
 		w.WriteHeader(response.StatusCode)
 		w.Write(response.Body)
 	})
@@ -29,7 +38,10 @@ func StaticHandler(assets embed.FS, config goflux.StaticConfig) http.Handler {
 // Alternative function signature for cases where HandlerFunc is preferred
 func StaticHandlerFunc(assets embed.FS, config goflux.StaticConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		response := goflux.ServeStaticFile(assets, config, r.URL.Path)
+		response := goflux.ServeStaticRequest(assets, config, goflux.StaticRequest{
+			Path:           r.URL.Path,
+			AcceptEncoding: r.Header.Get("Accept-Encoding"),
+		})
 
 		if response.NotFound {
 			http.NotFound(w, r)
@@ -38,6 +50,12 @@ func StaticHandlerFunc(assets embed.FS, config goflux.StaticConfig) http.Handler
 
 		w.Header().Set("Content-Type", response.ContentType)
 		w.Header().Set("Cache-Control", response.CacheControl)
+		if response.ContentEncoding != "" {
+			w.Header().Set("Content-Encoding", response.ContentEncoding)
+		}
+		if response.Vary != "" {
+			w.Header().Add("Vary", response.Vary)
+		}
 		w.WriteHeader(response.StatusCode)
 		w.Write(response.Body)
 	}
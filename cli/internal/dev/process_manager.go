@@ -91,7 +91,13 @@ func (pm *ProcessManager) startBackend() error {
 	return pm.waitForBackendStartup(10 * time.Second)
 }
 
-// stopBackend stops the backend process gracefully
+// backendShutdownTimeout is the drain deadline handed to the backend through FLUX_SHUTDOWN_TIMEOUT.
+// stopBackend waits a little longer before it kills the backend
+const backendShutdownTimeout = 3 * time.Second
+
+// stopBackend stops the backend process gracefully. The backend's process group is interrupted, like
+// pressing Ctrl+C: go run waits for the server, which drains its requests and runs its teardown hooks
+// (see goflux.Serve) before exiting. Only a backend that outlives the timeout is killed
 func (pm *ProcessManager) stopBackend(timeout time.Duration) error {
 	o := pm.orchestrator
 
@@ -106,9 +112,9 @@ func (pm *ProcessManager) stopBackend(timeout time.Duration) error {
 	o.log(fmt.Sprintf("🛑 Stopping backend (PID: %d)...", pid), "\x1b[34m")
 
 	// Try graceful shutdown first
-	if err := backend.Process.Process.Signal(syscall.SIGTERM); err != nil {
-		o.log("⚠️  Failed to send SIGTERM, trying SIGKILL", "\x1b[33m")
-		backend.Process.Process.Kill()
+	if err := interruptProcessGroup(backend.Process); err != nil {
+		o.log("⚠️  Failed to interrupt backend, killing it", "\x1b[33m")
+		killProcessGroup(backend.Process)
 	}
 
 	// Wait for process to exit
@@ -126,7 +132,7 @@ func (pm *ProcessManager) stopBackend(timeout time.Duration) error {
 		}
 	case <-time.After(timeout):
 		o.log("💀 Force killing backend (timeout)...", "\x1b[31m")
-		backend.Process.Process.Kill()
+		killProcessGroup(backend.Process)
 
 		// Wait a bit more for force kill
 		select {
@@ -275,6 +281,7 @@ func (pm *ProcessManager) createBackendEnv() []string {
 		fmt.Sprintf("BACKEND_PORT=%d", o.backendPort),
 		fmt.Sprintf("PROXY_PORT=%d", o.config.Port),
 		fmt.Sprintf("FRONTEND_PORT=%d", o.frontendPort),
+		fmt.Sprintf("FLUX_SHUTDOWN_TIMEOUT=%s", backendShutdownTimeout),
 		"FLUX_DEV_MODE=true",
 		"GO_ENV=development")
 
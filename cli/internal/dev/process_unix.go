@@ -17,6 +17,24 @@ func (o *DevOrchestrator) setupProcessGroup(cmd *exec.Cmd) {
 	}
 }
 
+// interruptProcessGroup sends SIGINT to a process and its children, e.g. go run and the server it built
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-processGroup(cmd), syscall.SIGINT)
+}
+
+// killProcessGroup kills a process and its children
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-processGroup(cmd), syscall.SIGKILL)
+}
+
+func processGroup(cmd *exec.Cmd) int {
+	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	if err != nil {
+		return cmd.Process.Pid
+	}
+	return pgid
+}
+
 // shutdownProcesses handles graceful shutdown of frontend and other processes on Unix
 func (o *DevOrchestrator) shutdownProcesses() {
 	done := make(chan bool, len(o.processes))
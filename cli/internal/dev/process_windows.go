@@ -18,6 +18,17 @@ func (o *DevOrchestrator) setupProcessGroup(cmd *exec.Cmd) {
 	}
 }
 
+// interruptProcessGroup would interrupt a process and its children, but console processes cannot be
+// sent Ctrl+C from outside on Windows, so the caller falls back to killProcessGroup
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return fmt.Errorf("interrupting processes is not supported on Windows")
+}
+
+// killProcessGroup kills a process and its children
+func killProcessGroup(cmd *exec.Cmd) {
+	exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
 // shutdownProcesses handles graceful shutdown of frontend and other processes on Windows
 func (o *DevOrchestrator) shutdownProcesses() {
 	done := make(chan bool, len(o.processes))
@@ -258,11 +258,11 @@ func (o *DevOrchestrator) startProcess(processInfo *ProcessInfo) error {
 func (o *DevOrchestrator) startProxy() {
 	// Create proxy to backend
 	backendURL, _ := url.Parse(fmt.Sprintf("http://localhost:%d", o.backendPort))
-	backendProxy := httputil.NewSingleHostReverseProxy(backendURL)
+	backendProxy := o.newDevProxy(backendURL, "Backend")
 
 	// Create proxy to frontend
 	frontendURL, _ := url.Parse(fmt.Sprintf("http://localhost:%d", o.frontendPort))
-	frontendProxy := httputil.NewSingleHostReverseProxy(frontendURL)
+	frontendProxy := o.newDevProxy(frontendURL, "Frontend")
 
 	// Create HTTP handler
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -290,6 +290,26 @@ func (o *DevOrchestrator) startProxy() {
 	}
 }
 
+// newDevProxy creates a reverse proxy to a local development server. WebSocket upgrades (API
+// sockets and the frontend's hot reload) are tunnelled, streamed responses such as server-sent
+// events are flushed immediately, and the browser's Host is kept so same-origin checks still pass
+func (o *DevOrchestrator) newDevProxy(target *url.URL, name string) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Host = r.In.Host
+			r.SetXForwarded()
+		},
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if r.Header.Get("Upgrade") != "" {
+				o.log(fmt.Sprintf("⚠️  %s %s upgrade failed: %v", name, r.URL.Path, err), "\x1b[33m")
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
 func (o *DevOrchestrator) shutdown() {
 	o.log("🔄 Shutting down development environment...", "\x1b[33m")
 
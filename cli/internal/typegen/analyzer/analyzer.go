@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/barisgit/goflux/cli/internal/typegen/config"
@@ -32,10 +33,44 @@ type OpenAPISpec struct {
 	Info       map[string]interface{} `json:"info"`
 	Paths      map[string]PathItem    `json:"paths"`
 	Components *Components            `json:"components,omitempty"`
+	// Flags holds the x-goflux-flags extension listing the feature flags of the API
+	Flags []FlagExtension `json:"x-goflux-flags,omitempty"`
+	// Errors holds the x-goflux-errors extension listing the application error codes of the API
+	Errors []ErrorCodeExtension `json:"x-goflux-errors,omitempty"`
+}
+
+// ErrorCodeExtension is an application error code listed in the x-goflux-errors extension
+type ErrorCodeExtension struct {
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Message string `json:"message,omitempty"`
+	Schema  string `json:"schema"` // Component schema of the error body
+}
+
+// FlagExtension is a feature flag listed in the x-goflux-flags extension
+type FlagExtension struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
 }
 
 type Components struct {
-	Schemas map[string]Schema `json:"schemas,omitempty"`
+	Schemas         map[string]Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is the subset of an OpenAPI security scheme needed by the clients
+type SecurityScheme struct {
+	Type   string         `json:"type"`
+	Scheme string         `json:"scheme,omitempty"`
+	In     string         `json:"in,omitempty"`
+	Name   string         `json:"name,omitempty"`
+	CSRF   *CSRFExtension `json:"x-goflux-csrf,omitempty"`
+}
+
+// CSRFExtension names the cookie session schemes issue CSRF tokens in and the header they expect them back in
+type CSRFExtension struct {
+	Cookie string `json:"cookie"`
+	Header string `json:"header"`
 }
 
 type Schema struct {
@@ -52,6 +87,7 @@ type Schema struct {
 	AllOf                []Schema          `json:"allOf,omitempty"`
 	OneOf                []Schema          `json:"oneOf,omitempty"`
 	AnyOf                []Schema          `json:"anyOf,omitempty"`
+	Const                interface{}       `json:"const,omitempty"`
 }
 
 type PathItem struct {
@@ -71,6 +107,34 @@ type Operation struct {
 	RequestBody *RequestBody              `json:"requestBody,omitempty"`
 	Responses   map[string]ResponseObject `json:"responses,omitempty"`
 	Security    []map[string][]string     `json:"security,omitempty"`
+	// Authorization holds the x-goflux-authorization extension written by goflux procedures
+	Authorization *types.RouteAuthorization `json:"x-goflux-authorization,omitempty"`
+	// WebSocket holds the x-goflux-websocket extension of WebSocket endpoints
+	WebSocket *WebSocketExtension `json:"x-goflux-websocket,omitempty"`
+	// Batch holds the x-goflux-batch extension of the batch endpoint
+	Batch *types.BatchEndpoint `json:"x-goflux-batch,omitempty"`
+	// Flags holds the x-goflux-flag extension naming the feature flags that gate the operation
+	Flags []string `json:"x-goflux-flag,omitempty"`
+	// Pagination holds the x-goflux-pagination extension of paginated operations
+	Pagination *types.Pagination `json:"x-goflux-pagination,omitempty"`
+	// Filters holds the x-goflux-filters extension of filterable list operations
+	Filters *types.Filters `json:"x-goflux-filters,omitempty"`
+	// Deprecated is set on deprecated operations
+	Deprecated bool `json:"deprecated,omitempty"`
+	// Version holds the x-goflux-version extension with the version and deprecation of the operation
+	Version *VersionExtension `json:"x-goflux-version,omitempty"`
+}
+
+// VersionExtension is the x-goflux-version extension of versioned and deprecated operations
+type VersionExtension struct {
+	Version string `json:"version,omitempty"`
+	types.Deprecation
+}
+
+// WebSocketExtension lists the schemas of the messages a client sends (inbound) and receives (outbound)
+type WebSocketExtension struct {
+	Inbound  *Schema `json:"inbound,omitempty"`
+	Outbound *Schema `json:"outbound,omitempty"`
 }
 
 type Parameter struct {
@@ -173,6 +237,11 @@ func (a *Analyzer) parseOpenAPISpec(spec *OpenAPISpec) *types.APIAnalysis {
 		EnumTypes:        make(map[string]types.TypeDefinition),
 	}
 
+	var securitySchemes map[string]SecurityScheme
+	if spec.Components != nil {
+		securitySchemes = spec.Components.SecuritySchemes
+	}
+
 	// Extract routes from paths
 	for path, pathItem := range spec.Paths {
 		operations := map[string]*Operation{
@@ -212,7 +281,54 @@ func (a *Analyzer) parseOpenAPISpec(spec *OpenAPISpec) *types.APIAnalysis {
 			if len(operation.Security) > 0 {
 				route.RequiresAuth = true
 				route.SecuritySchemes = operation.Security
-				route.AuthType = a.extractAuthType(operation.Security)
+				route.AuthType = a.extractAuthType(operation.Security, securitySchemes)
+
+				// API keys can be sent in a header, query parameter or cookie
+				if route.AuthType == "ApiKey" {
+					route.APIKeyName, route.APIKeyIn = a.extractAPIKeyLocation(operation.Security, securitySchemes)
+				}
+
+				// Cookie sessions expect the CSRF token under configurable names
+				if route.AuthType == "Cookie" {
+					route.CSRFCookie, route.CSRFHeader = a.extractCSRFNames(operation.Security, securitySchemes)
+				}
+			}
+
+			// Extract declared scopes, roles and permissions
+			route.Authorization = a.extractAuthorization(operation)
+
+			// Extract the media types the route can respond with besides JSON
+			route.Formats = a.extractResponseFormats(operation.Responses)
+
+			// Extract the event types of server-sent event streams
+			route.Events = a.extractEvents(operation.Responses)
+
+			// Extract the message types of WebSocket endpoints
+			route.WebSocket = a.extractWebSocket(operation.WebSocket)
+
+			// Mark the endpoint that executes batched calls
+			route.Batch = operation.Batch
+
+			// Record the feature flags gating the route
+			route.Flags = operation.Flags
+
+			// Record how paginated routes page through their results
+			route.Pagination = operation.Pagination
+
+			// Type the filter and sort parameters of filterable routes
+			if operation.Filters != nil {
+				route.Filters = a.applyFilterTypes(&route, operation.Filters)
+			}
+
+			// Record the version of the route and when it goes away if deprecated
+			if operation.Version != nil {
+				route.Version = operation.Version.Version
+			}
+			if operation.Deprecated {
+				route.Deprecation = &types.Deprecation{}
+				if operation.Version != nil {
+					*route.Deprecation = operation.Version.Deprecation
+				}
 			}
 
 			analysis.Routes = append(analysis.Routes, route)
@@ -224,9 +340,156 @@ func (a *Analyzer) parseOpenAPISpec(spec *OpenAPISpec) *types.APIAnalysis {
 		analysis.TypeDefs = a.extractTypeDefinitions(spec.Components.Schemas)
 	}
 
+	// Export the flag keys as a union type for frontend gating
+	if flagType := a.extractFlagType(spec.Flags); flagType != nil {
+		analysis.TypeDefs = append(analysis.TypeDefs, *flagType)
+		a.processor.SortTypeDefinitions(analysis.TypeDefs)
+	}
+
+	// Export the error codes as a discriminated union of their error bodies
+	if errorTypes := a.extractErrorTypes(spec.Errors); len(errorTypes) > 0 {
+		analysis.TypeDefs = append(analysis.TypeDefs, errorTypes...)
+		a.processor.SortTypeDefinitions(analysis.TypeDefs)
+	}
+
+	// Export the filter and sort types of filterable routes
+	if filterTypes := a.extractFilterTypes(analysis.Routes); len(filterTypes) > 0 {
+		analysis.TypeDefs = append(analysis.TypeDefs, filterTypes...)
+		a.processor.SortTypeDefinitions(analysis.TypeDefs)
+	}
+
 	return analysis
 }
 
+// extractFlagType converts the flags of the x-goflux-flags extension to a FeatureFlag union type
+func (a *Analyzer) extractFlagType(flags []FlagExtension) *types.TypeDefinition {
+	if len(flags) == 0 {
+		return nil
+	}
+	values := make([]string, len(flags))
+	for i, flag := range flags {
+		values[i] = fmt.Sprintf("%q", flag.Key)
+	}
+	slices.Sort(values)
+	return &types.TypeDefinition{
+		Name:       "FeatureFlag",
+		IsEnum:     true,
+		EnumValues: values,
+	}
+}
+
+// extractErrorTypes converts the error codes of the x-goflux-errors extension to an AppErrorCode
+// union of the codes and an AppError union of their bodies, whose literal code field discriminates them
+func (a *Analyzer) extractErrorTypes(codes []ErrorCodeExtension) []types.TypeDefinition {
+	if len(codes) == 0 {
+		return nil
+	}
+	codeValues := make([]string, len(codes))
+	bodyTypes := make([]string, len(codes))
+	for i, code := range codes {
+		codeValues[i] = fmt.Sprintf("%q", code.Code)
+		bodyTypes[i] = a.processor.ProcessTypeName(code.Schema)
+	}
+	slices.Sort(codeValues)
+	slices.Sort(bodyTypes)
+	return []types.TypeDefinition{
+		{Name: "AppErrorCode", IsEnum: true, EnumValues: codeValues},
+		{Name: "AppError", IsEnum: true, EnumValues: bodyTypes},
+	}
+}
+
+// applyFilterTypes names the filter types of a route and types its filter and sort parameters with
+// them. Routes sharing a whitelist struct share its types
+func (a *Analyzer) applyFilterTypes(route *types.APIRoute, filters *types.Filters) *types.Filters {
+	named := *filters
+	if named.Name == "" {
+		named.Name = a.processor.ProcessTypeName(route.Handler) + "Filter"
+	} else {
+		named.Name = a.processor.ProcessTypeName(named.Name)
+	}
+
+	for i := range route.QueryParameters {
+		param := &route.QueryParameters[i]
+		switch param.Name {
+		case "filter":
+			param.Type = named.Name
+		case "sort":
+			if slices.ContainsFunc(named.Fields, func(f types.FilterField) bool { return f.Sortable }) {
+				param.Type = fmt.Sprintf("%sSort | %sSort[]", named.Name, named.Name)
+			}
+		}
+	}
+	return &named
+}
+
+// extractFilterTypes converts the filters of routes to TypeScript types: an object of field to
+// operator to value, and a union of the sort keys. Filter keys keep their server names, so the object
+// is declared as an alias rather than an interface, whose field names would be converted
+func (a *Analyzer) extractFilterTypes(routes []types.APIRoute) []types.TypeDefinition {
+	var typeDefs []types.TypeDefinition
+	seen := map[string]bool{}
+	for _, route := range routes {
+		if route.Filters == nil || seen[route.Filters.Name] {
+			continue
+		}
+		seen[route.Filters.Name] = true
+
+		var fields, sortKeys []string
+		for _, field := range route.Filters.Fields {
+			if field.Sortable {
+				sortKeys = append(sortKeys, fmt.Sprintf("%q", field.Name), fmt.Sprintf("%q", "-"+field.Name))
+			}
+			if len(field.Ops) == 0 {
+				continue
+			}
+			ops := make([]string, len(field.Ops))
+			for i, op := range field.Ops {
+				ops[i] = fmt.Sprintf("%s?: %s", op, filterValueType(field.Type, op))
+			}
+			fields = append(fields, fmt.Sprintf("%q?: { %s }", field.Name, strings.Join(ops, "; ")))
+		}
+
+		typeDefs = append(typeDefs, types.TypeDefinition{
+			Name:       route.Filters.Name,
+			IsEnum:     true,
+			EnumValues: []string{"{ " + strings.Join(fields, "; ") + " }"},
+		})
+		if len(sortKeys) > 0 {
+			typeDefs = append(typeDefs, types.TypeDefinition{
+				Name:       route.Filters.Name + "Sort",
+				IsEnum:     true,
+				EnumValues: sortKeys,
+			})
+		}
+	}
+	return typeDefs
+}
+
+// filterValueType is the TypeScript type of a filter value; in takes a list and null a boolean
+func filterValueType(fieldType, op string) string {
+	var valueType string
+	switch fieldType {
+	case "number", "integer":
+		valueType = "number"
+	case "boolean":
+		valueType = "boolean"
+	case "date":
+		valueType = "string | Date"
+	default:
+		valueType = "string"
+	}
+	switch op {
+	case "in":
+		if strings.Contains(valueType, "|") {
+			return "(" + valueType + ")[]"
+		}
+		return valueType + "[]"
+	case "null":
+		return "boolean"
+	}
+	return valueType
+}
+
 // extractTypeFromRequestBody extracts the type name from request body schema
 func (a *Analyzer) extractTypeFromRequestBody(requestBody *RequestBody) string {
 	for _, mediaType := range requestBody.Content {
@@ -253,6 +516,75 @@ func (a *Analyzer) extractTypeFromResponses(responses map[string]ResponseObject)
 	return ""
 }
 
+// extractResponseFormats lists the non-JSON media types of the successful responses, sorted
+func (a *Analyzer) extractResponseFormats(responses map[string]ResponseObject) []string {
+	var formats []string
+	for code, response := range responses {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		for mediaType := range response.Content {
+			if strings.Contains(mediaType, "json") || mediaType == "text/event-stream" || slices.Contains(formats, mediaType) {
+				continue
+			}
+			formats = append(formats, mediaType)
+		}
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// extractEvents maps the event names of a text/event-stream response to their payload types.
+// Streams are documented as an array whose items are oneOf {id, event, data, retry} messages
+func (a *Analyzer) extractEvents(responses map[string]ResponseObject) map[string]string {
+	response, exists := responses["200"]
+	if !exists {
+		return nil
+	}
+	mediaType, exists := response.Content["text/event-stream"]
+	if !exists || mediaType.Schema == nil || mediaType.Schema.Items == nil {
+		return nil
+	}
+
+	events := map[string]string{}
+	for _, message := range mediaType.Schema.Items.OneOf {
+		name, _ := message.Properties["event"].Const.(string)
+		if name == "" {
+			name = "message"
+		}
+		dataType := "unknown"
+		if data, ok := message.Properties["data"]; ok {
+			if typeName := a.extractTypeName(&data); typeName != "" {
+				dataType = typeName
+			}
+		}
+		events[name] = dataType
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return events
+}
+
+// extractWebSocket converts the message schemas of a WebSocket endpoint to type names
+func (a *Analyzer) extractWebSocket(extension *WebSocketExtension) *types.WebSocketMessages {
+	if extension == nil {
+		return nil
+	}
+	messages := &types.WebSocketMessages{Inbound: "unknown", Outbound: "unknown"}
+	if extension.Inbound != nil {
+		if typeName := a.extractTypeName(extension.Inbound); typeName != "" {
+			messages.Inbound = typeName
+		}
+	}
+	if extension.Outbound != nil {
+		if typeName := a.extractTypeName(extension.Outbound); typeName != "" {
+			messages.Outbound = typeName
+		}
+	}
+	return messages
+}
+
 // extractTypeName extracts the type name from a schema
 func (a *Analyzer) extractTypeName(schema *Schema) string {
 	if schema.Ref != "" {
@@ -356,6 +688,19 @@ func (a *Analyzer) schemaToTypeScriptType(schema Schema) string {
 		return a.processor.ExtractTypeFromRef(schema.Ref)
 	}
 
+	// Enumerated properties are unions of their literal values, e.g. the code of error bodies
+	if len(schema.Enum) > 0 {
+		literals := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			if literal, err := json.Marshal(value); err == nil {
+				literals = append(literals, string(literal))
+			}
+		}
+		if len(literals) > 0 {
+			return strings.Join(literals, " | ")
+		}
+	}
+
 	typeStr := a.processor.NormalizeTypeString(schema.Type)
 	switch typeStr {
 	case "string":
@@ -367,6 +712,9 @@ func (a *Analyzer) schemaToTypeScriptType(schema Schema) string {
 	case "array":
 		if schema.Items != nil {
 			itemType := a.schemaToTypeScriptType(*schema.Items)
+			if strings.Contains(itemType, " | ") {
+				itemType = "(" + itemType + ")"
+			}
 			return itemType + "[]"
 		}
 		return "unknown[]"
@@ -484,13 +832,32 @@ func (a *Analyzer) convertSchemaToTSType(schema *Schema) string {
 }
 
 // extractAuthType determines the authentication type from security schemes
-func (a *Analyzer) extractAuthType(security []map[string][]string) string {
+// The scheme definition in components is preferred; the scheme name is used as a fallback
+func (a *Analyzer) extractAuthType(security []map[string][]string, schemes map[string]SecurityScheme) string {
 	if len(security) == 0 {
 		return ""
 	}
 
 	// Check the first security requirement
 	for authType := range security[0] {
+		if scheme, ok := schemes[authType]; ok {
+			switch {
+			case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+				return "Bearer"
+			case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+				return "Basic"
+			case scheme.Type == "apiKey" && scheme.In == "cookie" && authType == "session":
+				return "Cookie"
+			case scheme.Type == "apiKey":
+				return "ApiKey"
+			case scheme.Type == "openIdConnect":
+				// Browsers use the session cookie created by the OIDC login flow
+				return "Cookie"
+			case scheme.Type == "oauth2":
+				return "Bearer"
+			}
+		}
+
 		switch authType {
 		case "Bearer", "bearer":
 			return "Bearer"
@@ -498,6 +865,8 @@ func (a *Analyzer) extractAuthType(security []map[string][]string) string {
 			return "Basic"
 		case "ApiKey", "apiKey", "api_key":
 			return "ApiKey"
+		case "Cookie", "cookie", "session":
+			return "Cookie"
 		default:
 			return authType // Return as-is for custom schemes
 		}
@@ -506,6 +875,49 @@ func (a *Analyzer) extractAuthType(security []map[string][]string) string {
 	return ""
 }
 
+// extractAPIKeyLocation returns the parameter name and location of an apiKey scheme
+func (a *Analyzer) extractAPIKeyLocation(security []map[string][]string, schemes map[string]SecurityScheme) (string, string) {
+	for name := range security[0] {
+		if scheme, ok := schemes[name]; ok && scheme.Type == "apiKey" && scheme.Name != "" {
+			return scheme.Name, scheme.In
+		}
+	}
+	return "X-API-Key", "header"
+}
+
+// extractCSRFNames returns the CSRF cookie and header names of a cookie session scheme
+func (a *Analyzer) extractCSRFNames(security []map[string][]string, schemes map[string]SecurityScheme) (string, string) {
+	for name := range security[0] {
+		if scheme, ok := schemes[name]; ok && scheme.CSRF != nil && scheme.CSRF.Cookie != "" && scheme.CSRF.Header != "" {
+			return scheme.CSRF.Cookie, scheme.CSRF.Header
+		}
+	}
+	return "", ""
+}
+
+// extractAuthorization combines the authorization extension with scopes listed in security requirements
+func (a *Analyzer) extractAuthorization(operation *Operation) *types.RouteAuthorization {
+	authorization := &types.RouteAuthorization{}
+	if operation.Authorization != nil {
+		*authorization = *operation.Authorization
+	}
+
+	for _, requirement := range operation.Security {
+		for _, scopes := range requirement {
+			for _, scope := range scopes {
+				if !slices.Contains(authorization.Scopes, scope) {
+					authorization.Scopes = append(authorization.Scopes, scope)
+				}
+			}
+		}
+	}
+
+	if len(authorization.Scopes) == 0 && len(authorization.Roles) == 0 && len(authorization.Permissions) == 0 {
+		return nil
+	}
+	return authorization
+}
+
 // AnalyzeProject is the main entry point (backwards compatibility)
 func AnalyzeProject(projectPath string, debug bool) (*types.APIAnalysis, error) {
 	analyzer := NewAnalyzer(config.DefaultCasingConfig(), debug)
@@ -1,8 +1,13 @@
 package generator
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/barisgit/goflux/cli/internal/typegen/types"
 	"github.com/barisgit/goflux/config"
@@ -18,7 +23,29 @@ func detectAuthRequirements(routes []types.APIRoute) (bool, string) {
 	return false, ""
 }
 
-// GenerateAPIClient generates the API client based on configuration
+// detectAPIKeyLocation returns where API keys are sent, defaulting to the X-API-Key header
+func detectAPIKeyLocation(routes []types.APIRoute) (string, string) {
+	for _, route := range routes {
+		if route.AuthType == "ApiKey" && route.APIKeyName != "" {
+			return route.APIKeyName, route.APIKeyIn
+		}
+	}
+	return "X-API-Key", "header"
+}
+
+// detectCSRFNames returns the CSRF cookie and header of cookie sessions, defaulting to goflux_csrf and X-CSRF-Token
+func detectCSRFNames(routes []types.APIRoute) (string, string) {
+	for _, route := range routes {
+		if route.CSRFCookie != "" && route.CSRFHeader != "" {
+			return route.CSRFCookie, route.CSRFHeader
+		}
+	}
+	return "goflux_csrf", "X-CSRF-Token"
+}
+
+// GenerateAPIClient generates the API client based on configuration. APIs with versions also get a
+// client per version next to it, e.g. api-client.v1.ts, holding the version's routes and the
+// unversioned ones
 func GenerateAPIClient(routes []types.APIRoute, typeDefs []types.TypeDefinition, config *config.APIClientConfig) error {
 	libDir := filepath.Join("frontend", "src", "lib")
 
@@ -32,7 +59,85 @@ func GenerateAPIClient(routes []types.APIRoute, typeDefs []types.TypeDefinition,
 		}
 	}
 
-	// Generate based on selected generator type
+	if err := generateClient(routes, typeDefs, config, libDir, outputFile); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(outputFile)
+	for _, version := range routeVersions(routes) {
+		versionFile := strings.TrimSuffix(outputFile, ext) + "." + version + ext
+		if err := generateClient(scopeRoutes(routes, version), typeDefs, config, libDir, versionFile); err != nil {
+			return fmt.Errorf("generating %s client: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// routeVersions returns the API versions of the routes
+func routeVersions(routes []types.APIRoute) []string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, route := range routes {
+		if route.Version != "" && !seen[route.Version] {
+			seen[route.Version] = true
+			versions = append(versions, route.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// compareVersions orders versions by their numbers like the server does, so v2 comes before v10.
+// Digit runs are compared numerically, other runs lexically
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		aRun, aRest := versionRun(a)
+		bRun, bRest := versionRun(b)
+		if isDigit(aRun[0]) && isDigit(bRun[0]) {
+			aRun, bRun = strings.TrimLeft(aRun, "0"), strings.TrimLeft(bRun, "0")
+			if len(aRun) != len(bRun) {
+				return len(aRun) - len(bRun)
+			}
+		}
+		if c := strings.Compare(aRun, bRun); c != 0 {
+			return c
+		}
+		a, b = aRest, bRest
+	}
+	return len(a) - len(b)
+}
+
+// versionRun splits the leading run of digits or non-digits off a version
+func versionRun(s string) (string, string) {
+	i := 1
+	for i < len(s) && isDigit(s[i]) == isDigit(s[0]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// scopeRoutes returns the routes of a version-scoped client: the version's routes, nested without
+// their version segment, and the unversioned routes
+func scopeRoutes(routes []types.APIRoute, version string) []types.APIRoute {
+	scoped := make([]types.APIRoute, 0, len(routes))
+	for _, route := range routes {
+		if route.Version != "" && route.Version != version {
+			continue
+		}
+		route.Scoped = route.Version != ""
+		scoped = append(scoped, route)
+	}
+	return scoped
+}
+
+// generateClient generates one API client file with the selected generator
+func generateClient(routes []types.APIRoute, typeDefs []types.TypeDefinition, config *config.APIClientConfig, libDir, outputFile string) error {
 	switch config.Generator {
 	case "basic":
 		return generateBasicJSClient(routes, typeDefs, config, libDir, outputFile)
@@ -50,11 +155,16 @@ func GenerateAPIClient(routes []types.APIRoute, typeDefs []types.TypeDefinition,
 // generateBasicJSClient generates the new basic JavaScript API client
 func generateBasicJSClient(routes []types.APIRoute, typeDefs []types.TypeDefinition, config *config.APIClientConfig, libDir, outputFile string) error {
 	apiObject := generateAPIObjectString(routes, "basic")
+	csrfCookieName, csrfHeaderName := detectCSRFNames(routes)
 
 	data := ClientTemplateData{
-		UsedTypes:   []string{}, // No types needed for JavaScript
-		TypesImport: "",         // No types import for JavaScript
-		APIObject:   apiObject,
+		UsedTypes:      []string{}, // No types needed for JavaScript
+		TypesImport:    "",         // No types import for JavaScript
+		APIObject:      apiObject,
+		CSRFCookieName: csrfCookieName,
+		CSRFHeaderName: csrfHeaderName,
+		ErrorCodes:     errorCodeList(typeDefs),
+		Deprecations:   deprecationTable(routes),
 	}
 
 	return generateFromTemplate(basicClientTemplate, data, filepath.Join(libDir, outputFile))
@@ -65,13 +175,21 @@ func generateBasicTSClient(routes []types.APIRoute, typeDefs []types.TypeDefinit
 	usedTypes := collectUsedTypes(routes, typeDefs)
 	apiObject := generateAPIObjectString(routes, "basic-ts")
 	requiresAuth, authType := detectAuthRequirements(routes)
+	apiKeyName, apiKeyIn := detectAPIKeyLocation(routes)
+	csrfCookieName, csrfHeaderName := detectCSRFNames(routes)
 
 	data := ClientTemplateData{
-		UsedTypes:    usedTypes,
-		TypesImport:  config.TypesImport,
-		APIObject:    apiObject,
-		RequiresAuth: requiresAuth,
-		AuthType:     authType,
+		UsedTypes:      usedTypes,
+		TypesImport:    config.TypesImport,
+		APIObject:      apiObject,
+		RequiresAuth:   requiresAuth,
+		AuthType:       authType,
+		APIKeyName:     apiKeyName,
+		APIKeyIn:       apiKeyIn,
+		CSRFCookieName: csrfCookieName,
+		CSRFHeaderName: csrfHeaderName,
+		ErrorCodes:     errorCodeList(typeDefs),
+		Deprecations:   deprecationTable(routes),
 	}
 
 	return generateFromTemplate(basicTSClientTemplate, data, filepath.Join(libDir, outputFile))
@@ -82,23 +200,135 @@ func generateAxiosClient(routes []types.APIRoute, typeDefs []types.TypeDefinitio
 	usedTypes := collectUsedTypes(routes, typeDefs)
 	apiObject := generateAPIObjectString(routes, "axios")
 	requiresAuth, authType := detectAuthRequirements(routes)
+	apiKeyName, apiKeyIn := detectAPIKeyLocation(routes)
+	csrfCookieName, csrfHeaderName := detectCSRFNames(routes)
 
 	data := ClientTemplateData{
-		UsedTypes:    usedTypes,
-		TypesImport:  config.TypesImport,
-		APIObject:    apiObject,
-		RequiresAuth: requiresAuth,
-		AuthType:     authType,
+		UsedTypes:      usedTypes,
+		TypesImport:    config.TypesImport,
+		APIObject:      apiObject,
+		RequiresAuth:   requiresAuth,
+		AuthType:       authType,
+		APIKeyName:     apiKeyName,
+		APIKeyIn:       apiKeyIn,
+		CSRFCookieName: csrfCookieName,
+		CSRFHeaderName: csrfHeaderName,
+		ErrorCodes:     errorCodeList(typeDefs),
+		Deprecations:   deprecationTable(routes),
 	}
 
 	return generateFromTemplate(axiosClientTemplate, data, filepath.Join(libDir, outputFile))
 }
 
+// prepareBatching takes the batch endpoint out of the routes and marks the queries that can be sent
+// through it. Streams, routes with several path parameters, filterable and deprecated routes and
+// routes taking an API key in the query string are always requested on their own
+func prepareBatching(routes []types.APIRoute) (string, int, []types.APIRoute) {
+	var endpoint *types.APIRoute
+	remaining := make([]types.APIRoute, 0, len(routes))
+	for i := range routes {
+		if routes[i].Batch != nil && endpoint == nil {
+			endpoint = &routes[i]
+			continue
+		}
+		remaining = append(remaining, routes[i])
+	}
+	if endpoint == nil {
+		return "", 0, routes
+	}
+
+	for i := range remaining {
+		route := &remaining[i]
+		route.Batched = route.Method == "GET" &&
+			route.Handler != "" &&
+			len(route.Events) == 0 &&
+			route.WebSocket == nil &&
+			route.Filters == nil &&
+			route.Deprecation == nil &&
+			!(route.AuthType == "ApiKey" && route.APIKeyIn == "query") &&
+			len(pathParamNames(route.Path)) <= 1
+	}
+	maxCalls := endpoint.Batch.MaxCalls
+	if maxCalls <= 0 {
+		maxCalls = 50
+	}
+	return buildRequestPath(endpoint.Path, false), maxCalls, remaining
+}
+
+// hasInfiniteQueries reports whether any route gets infinite query helpers
+func hasInfiniteQueries(routes []types.APIRoute) bool {
+	for _, route := range routes {
+		if route.Method == "GET" && route.Pagination != nil && len(route.QueryParameters) > 0 &&
+			(route.Pagination.Mode == "offset" || route.Pagination.Mode == "cursor") {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecationNotice describes when a deprecated route goes away and what replaces it, empty for
+// routes that are not deprecated
+func deprecationNotice(route types.APIRoute) string {
+	if route.Deprecation == nil {
+		return ""
+	}
+	notice := "This operation is deprecated"
+	if route.Deprecation.Sunset != "" {
+		sunset := route.Deprecation.Sunset
+		if t, err := time.Parse(time.RFC3339, sunset); err == nil {
+			sunset = t.Format("2006-01-02")
+		}
+		notice += " and will be removed on " + sunset
+	}
+	if route.Deprecation.Successor != "" {
+		notice += ", use " + route.Deprecation.Successor + " instead"
+	}
+	return notice
+}
+
+// deprecationTable returns the entries of the table of deprecated routes the clients' request
+// functions check their requests against, matching request paths without the /api prefix
+func deprecationTable(routes []types.APIRoute) string {
+	var entries []string
+	for _, route := range routes {
+		if route.Deprecation == nil {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(route.Path, "/api"), "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, "{") || strings.HasPrefix(segment, ":") {
+				segments[i] = `[^\/?]+`
+			} else {
+				segments[i] = regexp.QuoteMeta(segment)
+			}
+		}
+		pattern := "/^" + strings.Join(segments, `\/`) + `(?:\?|$)/`
+		message, _ := json.Marshal(fmt.Sprintf("%s %s: %s", route.Method, route.Path, deprecationNotice(route)))
+		entries = append(entries, fmt.Sprintf("  { method: '%s', pattern: %s, message: %s },", route.Method, pattern, message))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\n")
+}
+
+// errorCodeList returns the application error codes of the AppErrorCode type as a list of quoted
+// codes for the clients' type guards
+func errorCodeList(typeDefs []types.TypeDefinition) string {
+	for _, typeDef := range typeDefs {
+		if typeDef.Name == "AppErrorCode" && typeDef.IsEnum {
+			return strings.Join(typeDef.EnumValues, ", ")
+		}
+	}
+	return ""
+}
+
 // generateTRPCLikeClient generates a tRPC-like API client with React Query integration
 func generateTRPCLikeClient(routes []types.APIRoute, typeDefs []types.TypeDefinition, config *config.APIClientConfig, libDir, outputFile string) error {
+	batchPath, batchMaxCalls, routes := prepareBatching(routes)
 	usedTypes := collectUsedTypes(routes, typeDefs)
 	apiObject := generateTRPCAPIObjectString(routes, config)
 	requiresAuth, authType := detectAuthRequirements(routes)
+	apiKeyName, apiKeyIn := detectAPIKeyLocation(routes)
+	csrfCookieName, csrfHeaderName := detectCSRFNames(routes)
 
 	var queryKeys string
 	if config.ReactQuery.QueryKeys {
@@ -114,6 +344,15 @@ func generateTRPCLikeClient(routes []types.APIRoute, typeDefs []types.TypeDefini
 		QueryKeys:         queryKeys,
 		RequiresAuth:      requiresAuth,
 		AuthType:          authType,
+		APIKeyName:        apiKeyName,
+		APIKeyIn:          apiKeyIn,
+		CSRFCookieName:    csrfCookieName,
+		CSRFHeaderName:    csrfHeaderName,
+		BatchPath:         batchPath,
+		BatchMaxCalls:     batchMaxCalls,
+		HasInfinite:       hasInfiniteQueries(routes),
+		ErrorCodes:        errorCodeList(typeDefs),
+		Deprecations:      deprecationTable(routes),
 	}
 
 	return generateFromTemplate(trpcLikeClientTemplate, data, filepath.Join(libDir, outputFile))
@@ -316,7 +316,11 @@ func generateTRPCNestedObject(content *strings.Builder, nested types.NestedAPI,
 
 			templateData := createMethodTemplateData(v, "trpc-like", config.ReactQuery.Enabled)
 
-			if v.Route.Method == "GET" {
+			if templateData.SocketSendType != "" {
+				methodCode, err = executeMethodTemplate(trpcWebSocketMethodTemplate, templateData)
+			} else if templateData.EventMapType != "" {
+				methodCode, err = executeMethodTemplate(trpcSSEMethodTemplate, templateData)
+			} else if v.Route.Method == "GET" {
 				methodCode, err = executeMethodTemplate(trpcGetMethodTemplate, templateData)
 			} else {
 				methodCode, err = executeMethodTemplate(trpcMutationMethodTemplate, templateData)
@@ -2,6 +2,8 @@ package generator
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/barisgit/goflux/cli/internal/typegen/types"
@@ -17,9 +19,7 @@ func buildNestedAPIStructure(routes []types.APIRoute) types.NestedAPI {
 			continue // Skip SSR routes
 		}
 
-		path := strings.Replace(route.Path, "/api/", "", 1)
-		path = strings.TrimPrefix(path, "/")
-		pathParts := strings.Split(path, "/")
+		pathParts := strings.Split(nestingPath(route), "/")
 
 		// Filter out parameter parts and build resource hierarchy
 		var resourceParts []string
@@ -75,6 +75,17 @@ func buildNestedAPIStructure(routes []types.APIRoute) types.NestedAPI {
 	return nested
 }
 
+// nestingPath returns the path a route is nested by in the API object: without the /api prefix and,
+// in version-scoped clients, without the version segment
+func nestingPath(route types.APIRoute) string {
+	path := strings.Replace(route.Path, "/api/", "", 1)
+	path = strings.TrimPrefix(path, "/")
+	if route.Scoped && route.Version != "" {
+		path = strings.TrimPrefix(strings.Replace("/"+path, "/"+route.Version+"/", "/", 1), "/")
+	}
+	return path
+}
+
 // getMethodNameForHTTPMethod returns the method name based on HTTP method
 func getMethodNameForHTTPMethod(httpMethod string, hasIDParam bool, isNested bool) string {
 	switch httpMethod {
@@ -212,8 +223,7 @@ func generateQueryKeys(routes []types.APIRoute) string {
 	// Extract unique resources
 	for _, route := range routes {
 		if route.Method == "GET" {
-			path := strings.Replace(route.Path, "/api/", "", 1)
-			pathParts := strings.Split(path, "/")
+			pathParts := strings.Split(nestingPath(route), "/")
 			if len(pathParts) > 0 && pathParts[0] != "" {
 				resources[pathParts[0]] = true
 			}
@@ -330,8 +340,60 @@ func createMethodTemplateData(method types.APIMethod, generatorType string, reac
 	// Build request path for mutations (React Query uses different variable patterns)
 	requestPathForMutation := buildRequestPathForMutation(route.Path, method.HasIDParam, method.HasBodyData)
 
+	// GET routes that can respond with CSV, CBOR, ... take an optional format argument
+	var formats []string
+	var formatType string
+	if route.Method == "GET" && len(route.Formats) > 0 {
+		formats = route.Formats
+		formatType = buildFormatType(route.Formats)
+	}
+
+	// Server-sent event routes are subscribed to instead of fetched
+	var eventMapType string
+	if route.Method == "GET" && len(route.Events) > 0 {
+		eventMapType = buildEventMapType(route.Events)
+	}
+
+	// WebSocket routes are connected to instead of fetched
+	var socketSendType, socketReceiveType string
+	if route.Method == "GET" && route.WebSocket != nil {
+		socketSendType = route.WebSocket.Inbound
+		socketReceiveType = route.WebSocket.Outbound
+	}
+
+	// Queries sent through the batch endpoint name their operation and pass parameters by name
+	var batchOperationID, batchParams string
+	if route.Batched {
+		batchOperationID = route.Handler
+		batchParams = buildBatchParams(route.Path, method.HasIDParam, method.HasQueryParams)
+	}
+
+	// Paginated GET routes get infinite query helpers driven by the page parameter
+	var pageParamName, pageParamType, initialPageParam, nextPageParam, infiniteOptionsParamSig string
+	if route.Method == "GET" && route.Pagination != nil && method.HasQueryParams {
+		switch route.Pagination.Mode {
+		case "offset":
+			pageParamName, pageParamType, initialPageParam = "offset", "number", "0"
+			nextPageParam = "lastPage.hasMore ? (lastPage.offset ?? 0) + lastPage.items.length : undefined"
+		case "cursor":
+			pageParamName, pageParamType, initialPageParam = "cursor", "string | undefined", "undefined"
+			nextPageParam = "lastPage.hasMore ? lastPage.nextCursor : undefined"
+		}
+		if pageParamName != "" && method.HasIDParam {
+			infiniteOptionsParamSig = "id: number, "
+		}
+		if pageParamName != "" {
+			infiniteOptionsParamSig += fmt.Sprintf("params?: Omit<%s, '%s'>", queryParamsType, pageParamName)
+		}
+	}
+	infiniteParamSig := ""
+	if infiniteOptionsParamSig != "" {
+		infiniteParamSig = infiniteOptionsParamSig + ", "
+	}
+
 	return MethodTemplateData{
 		Description:                    route.Description,
+		Deprecated:                     deprecationNotice(route),
 		Method:                         route.Method,
 		MethodLower:                    strings.ToLower(route.Method),
 		ParameterSignature:             strings.Join(params, ", "),
@@ -351,7 +413,83 @@ func createMethodTemplateData(method types.APIMethod, generatorType string, reac
 		ReactQueryEnabled:              reactQueryEnabled,
 		RequiresAuth:                   route.RequiresAuth,
 		AuthType:                       route.AuthType,
+		Formats:                        formats,
+		FormatType:                     formatType,
+		EventMapType:                   eventMapType,
+		SocketSendType:                 socketSendType,
+		SocketReceiveType:              socketReceiveType,
+		BatchOperationID:               batchOperationID,
+		BatchParams:                    batchParams,
+		PageParamName:                  pageParamName,
+		PageParamType:                  pageParamType,
+		InitialPageParam:               initialPageParam,
+		NextPageParam:                  nextPageParam,
+		InfiniteParameterSignature:     infiniteParamSig,
+		InfiniteOptionsSignature:       infiniteOptionsParamSig,
+	}
+}
+
+// pathParamNames returns the names of the {name} and :name parameters of a route path
+func pathParamNames(path string) []string {
+	var names []string
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if match[1] != "" {
+			names = append(names, match[1])
+		} else {
+			names = append(names, match[2])
+		}
+	}
+	return names
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}|:([^/]+)`)
+
+// buildBatchParams builds the TypeScript object holding the parameters of a batched call, e.g.
+// "{ userId: id, ...params }"
+func buildBatchParams(path string, hasIDParam, hasQueryParams bool) string {
+	var fields []string
+	if names := pathParamNames(path); hasIDParam && len(names) > 0 {
+		key := names[0]
+		if !isValidJSIdentifier(key) {
+			key = fmt.Sprintf("'%s'", key)
+		}
+		fields = append(fields, key+": id")
+	}
+	if hasQueryParams {
+		fields = append(fields, "...params")
+	}
+	if len(fields) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// buildEventMapType builds a TypeScript object type mapping event names to payload types
+func buildEventMapType(events map[string]string) string {
+	names := make([]string, 0, len(events))
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		key := name
+		if !isValidJSIdentifier(name) {
+			key = fmt.Sprintf("'%s'", name)
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s", key, events[name]))
+	}
+	return "{ " + strings.Join(fields, "; ") + " }"
+}
+
+// buildFormatType builds a TypeScript union of the media types a route can respond with
+func buildFormatType(formats []string) string {
+	values := []string{"'application/json'"}
+	for _, format := range formats {
+		values = append(values, fmt.Sprintf("'%s'", format))
 	}
+	return strings.Join(values, " | ")
 }
 
 // buildQueryParamsType builds a TypeScript type for query parameters
@@ -423,6 +561,7 @@ func createMethodTemplateDataJS(method types.APIMethod) MethodTemplateData {
 
 	return MethodTemplateData{
 		Description:          cleanDescription,
+		Deprecated:           deprecationNotice(route),
 		Method:               route.Method,
 		MethodLower:          strings.ToLower(route.Method),
 		ParameterSignatureJS: strings.Join(jsParams, ", "),
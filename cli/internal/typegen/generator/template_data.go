@@ -9,12 +9,22 @@ type ClientTemplateData struct {
 	QueryKeysEnabled  bool
 	QueryKeys         string
 	RequiresAuth      bool   // Whether any routes require authentication
-	AuthType          string // Primary auth type: "Bearer", "Basic", "ApiKey"
+	AuthType          string // Primary auth type: "Bearer", "Basic", "ApiKey", "Cookie"
+	APIKeyName        string // Name of the API key header, query parameter or cookie
+	APIKeyIn          string // Where the API key is sent: "header", "query" or "cookie"
+	CSRFCookieName    string // Cookie the server issues CSRF tokens in
+	CSRFHeaderName    string // Header state-changing requests echo the CSRF token in
+	BatchPath         string // Request path of the batch endpoint, empty when queries are not batched
+	BatchMaxCalls     int    // Largest number of calls the batch endpoint accepts
+	HasInfinite       bool   // Whether any route gets infinite query helpers
+	ErrorCodes        string // Application error codes as a quoted, comma-separated list; empty without codes
+	Deprecations      string // Entries of the deprecated operations table the request functions warn from
 }
 
 // MethodTemplateData contains data for individual method templates
 type MethodTemplateData struct {
 	Description                    string
+	Deprecated                     string // Deprecation notice of deprecated routes, rendered as @deprecated
 	Method                         string
 	MethodLower                    string
 	ParameterSignature             string
@@ -32,6 +42,19 @@ type MethodTemplateData struct {
 	QueryKey                       string
 	MutationVariableType           string
 	ReactQueryEnabled              bool
-	RequiresAuth                   bool   // Whether this specific route requires authentication
-	AuthType                       string // Auth type for this route
+	RequiresAuth                   bool     // Whether this specific route requires authentication
+	AuthType                       string   // Auth type for this route
+	Formats                        []string // Alternative response media types a GET route can be asked for
+	FormatType                     string   // TypeScript union of the selectable media types, including JSON
+	EventMapType                   string   // TypeScript map of event names to payload types for server-sent event routes
+	SocketSendType                 string   // Type of the messages a WebSocket route accepts from the client
+	SocketReceiveType              string   // Type of the messages a WebSocket route sends to the client
+	BatchOperationID               string   // Operation ID used when the query is sent through the batch endpoint
+	BatchParams                    string   // TypeScript expression of the path and query parameters of a batched call
+	PageParamName                  string   // Query parameter that selects the page of a paginated route ("offset" or "cursor")
+	PageParamType                  string   // TypeScript type of the page parameter
+	InitialPageParam               string   // Page parameter of the first page
+	NextPageParam                  string   // TypeScript expression computing the next page parameter from lastPage
+	InfiniteParameterSignature     string   // Parameters of useInfiniteQuery, without the page parameter
+	InfiniteOptionsSignature       string   // Parameters of infiniteQueryOptions
 }
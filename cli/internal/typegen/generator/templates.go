@@ -38,6 +38,12 @@ var trpcGetMethodTemplate string
 //go:embed templates/trpc-mutation-method.ts.tmpl
 var trpcMutationMethodTemplate string
 
+//go:embed templates/trpc-sse-method.ts.tmpl
+var trpcSSEMethodTemplate string
+
+//go:embed templates/trpc-websocket-method.ts.tmpl
+var trpcWebSocketMethodTemplate string
+
 // generateFromTemplate creates a file from an embedded template
 func generateFromTemplate(templateStr string, data ClientTemplateData, outputPath string) error {
 	// Create custom function map for templates
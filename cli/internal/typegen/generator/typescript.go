@@ -115,8 +115,23 @@ func collectUsedTypes(routes []types.APIRoute, typeDefs []types.TypeDefinition)
 			}
 			usedTypes[responseType] = true
 		}
+		for _, eventType := range route.Events {
+			usedTypes[strings.TrimSuffix(eventType, "[]")] = true
+		}
+		if route.WebSocket != nil {
+			usedTypes[strings.TrimSuffix(route.WebSocket.Inbound, "[]")] = true
+			usedTypes[strings.TrimSuffix(route.WebSocket.Outbound, "[]")] = true
+		}
+		if route.Filters != nil {
+			usedTypes[route.Filters.Name] = true
+			usedTypes[route.Filters.Name+"Sort"] = true
+		}
 	}
 
+	// The clients' type guards narrow errors to the application error union
+	usedTypes["AppError"] = true
+	usedTypes["AppErrorCode"] = true
+
 	// Filter to only include types that exist in our generated types
 	var typeNames []string
 	for typeName := range usedTypes {
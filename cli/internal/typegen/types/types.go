@@ -20,8 +20,70 @@ type APIRoute struct {
 	Description     string                `json:"description,omitempty"`
 	QueryParameters []QueryParameter      `json:"query_parameters,omitempty"`
 	RequiresAuth    bool                  `json:"requires_auth"`
-	AuthType        string                `json:"auth_type,omitempty"` // "Bearer", "Basic", "ApiKey", etc.
+	AuthType        string                `json:"auth_type,omitempty"` // "Bearer", "Basic", "ApiKey", "Cookie", etc.
 	SecuritySchemes []map[string][]string `json:"security_schemes,omitempty"`
+	APIKeyName      string                `json:"api_key_name,omitempty"` // Header, query parameter or cookie name for ApiKey auth
+	APIKeyIn        string                `json:"api_key_in,omitempty"`   // "header", "query" or "cookie"
+	CSRFCookie      string                `json:"csrf_cookie,omitempty"`  // Cookie the session's CSRF token is issued in, for Cookie auth
+	CSRFHeader      string                `json:"csrf_header,omitempty"`  // Header unsafe requests echo the CSRF token in
+	Authorization   *RouteAuthorization   `json:"authorization,omitempty"`
+	Formats         []string              `json:"formats,omitempty"` // Alternative response media types, e.g. "text/csv"
+	Events          map[string]string     `json:"events,omitempty"`  // Server-sent event names mapped to their payload types
+	WebSocket       *WebSocketMessages    `json:"websocket,omitempty"`
+	Batch           *BatchEndpoint        `json:"batch,omitempty"`   // Set on the endpoint that executes batched calls
+	Batched         bool                  `json:"batched,omitempty"` // Whether clients send the route through the batch endpoint
+	Flags           []string              `json:"flags,omitempty"`   // Feature flags that gate the route
+	Pagination      *Pagination           `json:"pagination,omitempty"`
+	Filters         *Filters              `json:"filters,omitempty"`
+	Version         string                `json:"version,omitempty"`     // API version the route is served under
+	Deprecation     *Deprecation          `json:"deprecation,omitempty"` // Set on deprecated routes
+	Scoped          bool                  `json:"scoped,omitempty"`      // Whether the route is nested without its version, in version-scoped clients
+}
+
+// Deprecation describes when a deprecated route goes away and what replaces it
+type Deprecation struct {
+	Since     string `json:"deprecatedSince,omitempty"` // RFC 3339 dates
+	Sunset    string `json:"sunset,omitempty"`
+	Successor string `json:"successor,omitempty"`
+}
+
+// Filters describes the fields a list route can be filtered and sorted by
+type Filters struct {
+	Name   string        `json:"name"` // Name of the Go whitelist struct, used for the client's filter types
+	Fields []FilterField `json:"fields"`
+}
+
+// FilterField is a filterable or sortable field
+type FilterField struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // "string", "number", "integer", "boolean" or "date"
+	Ops      []string `json:"ops,omitempty"`
+	Sortable bool     `json:"sortable,omitempty"`
+}
+
+// Pagination describes how a route pages through its results
+type Pagination struct {
+	Mode         string `json:"mode"` // "offset" or "cursor"
+	DefaultLimit int    `json:"defaultLimit,omitempty"`
+	MaxLimit     int    `json:"maxLimit,omitempty"`
+}
+
+// BatchEndpoint describes the endpoint that executes several operations in one request
+type BatchEndpoint struct {
+	MaxCalls int `json:"maxCalls,omitempty"` // Largest number of calls accepted in one batch
+}
+
+// WebSocketMessages holds the message types of a WebSocket route
+type WebSocketMessages struct {
+	Inbound  string `json:"inbound"`  // Messages the client sends
+	Outbound string `json:"outbound"` // Messages the client receives
+}
+
+// RouteAuthorization lists what a caller needs to use a route, so frontends can hide unauthorized actions
+type RouteAuthorization struct {
+	Scopes      []string `json:"scopes,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
 }
 
 // TypeDefinition represents a Go struct converted to TypeScript
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -226,6 +227,9 @@ func (cm *ConfigManager) validateConfig(config *ProjectConfig) ValidationErrors
 		})
 	}
 
+	// Validate CORS configuration
+	errors = append(errors, cm.validateCORSConfig(&config.CORS)...)
+
 	// Validate static directory exists if embed_static is enabled
 	if config.Build.EmbedStatic && config.Build.StaticDir != "" {
 		// Only check during build, not during config load
@@ -235,6 +239,87 @@ func (cm *ConfigManager) validateConfig(config *ProjectConfig) ValidationErrors
 	return errors
 }
 
+// validateCORSConfig validates the cors section of the configuration
+func (cm *ConfigManager) validateCORSConfig(cors *CORSConfig) ValidationErrors {
+	var errors ValidationErrors
+
+	if !cors.Enabled {
+		return errors
+	}
+
+	if len(cors.AllowedOrigins) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "cors.allowed_origins",
+			Value:   cors.AllowedOrigins,
+			Message: "at least one allowed origin is required when CORS is enabled",
+		})
+	}
+
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			if cors.AllowCredentials {
+				errors = append(errors, ValidationError{
+					Field:   "cors.allowed_origins",
+					Value:   origin,
+					Message: "wildcard origin '*' cannot be combined with allow_credentials, list the origins explicitly",
+				})
+			}
+			continue
+		}
+
+		if !isValidCORSOrigin(origin) {
+			errors = append(errors, ValidationError{
+				Field:   "cors.allowed_origins",
+				Value:   origin,
+				Message: "origin must be '*' or a scheme and host such as 'https://app.example.com' or 'https://*.example.com'",
+			})
+		}
+	}
+
+	validMethods := []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	for _, method := range cors.AllowedMethods {
+		if !contains(validMethods, strings.ToUpper(method)) {
+			errors = append(errors, ValidationError{
+				Field:   "cors.allowed_methods",
+				Value:   method,
+				Message: fmt.Sprintf("unsupported method '%s', valid options are: %s", method, strings.Join(validMethods, ", ")),
+			})
+		}
+	}
+
+	for _, header := range cors.AllowedHeaders {
+		if strings.TrimSpace(header) == "" || strings.ContainsAny(header, " ,:") {
+			errors = append(errors, ValidationError{
+				Field:   "cors.allowed_headers",
+				Value:   header,
+				Message: "header names cannot be empty or contain spaces, commas or colons",
+			})
+		}
+	}
+
+	if cors.MaxAge < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "cors.max_age",
+			Value:   cors.MaxAge,
+			Message: "preflight max age cannot be negative",
+		})
+	}
+
+	return errors
+}
+
+// isValidCORSOrigin checks that an origin is a bare scheme://host[:port], optionally with a leading subdomain wildcard
+func isValidCORSOrigin(origin string) bool {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	return (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == ""
+}
+
 // applyDefaults sets default values for missing configuration fields
 func (cm *ConfigManager) applyDefaults(config *ProjectConfig) {
 	// Set default port
@@ -286,6 +371,19 @@ func (cm *ConfigManager) applyDefaults(config *ProjectConfig) {
 	if config.Build.LDFlags == "" {
 		config.Build.LDFlags = "-s -w"
 	}
+
+	// Set default CORS configuration (only when enabled)
+	if config.CORS.Enabled {
+		if len(config.CORS.AllowedMethods) == 0 {
+			config.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+		}
+		if len(config.CORS.AllowedHeaders) == 0 {
+			config.CORS.AllowedHeaders = []string{"Content-Type", "Authorization"}
+		}
+		if config.CORS.MaxAge == 0 {
+			config.CORS.MaxAge = 600
+		}
+	}
 }
 
 // checkDeprecatedFields warns about deprecated configuration fields
@@ -375,6 +473,8 @@ func GetConfigInfo(path string) (*ConfigInfo, error) {
 		BuildOutputDir:     config.Build.OutputDir,
 		StaticDir:          config.Build.StaticDir,
 		EmbedStatic:        config.Build.EmbedStatic,
+		CORSEnabled:        config.CORS.Enabled,
+		CORSOrigins:        config.CORS.AllowedOrigins,
 	}, nil
 }
 
@@ -390,6 +490,8 @@ type ConfigInfo struct {
 	BuildOutputDir     string
 	StaticDir          string
 	EmbedStatic        bool
+	CORSEnabled        bool
+	CORSOrigins        []string
 }
 
 // String returns a formatted string representation of config info
@@ -407,6 +509,9 @@ func (info *ConfigInfo) String() string {
 	}
 	lines = append(lines, fmt.Sprintf("   Build Output: %s", info.BuildOutputDir))
 	lines = append(lines, fmt.Sprintf("   Static Assets: %s (embed: %t)", info.StaticDir, info.EmbedStatic))
+	if info.CORSEnabled {
+		lines = append(lines, fmt.Sprintf("   CORS: enabled (%s)", strings.Join(info.CORSOrigins, ", ")))
+	}
 
 	return strings.Join(lines, "\n")
 }
@@ -471,6 +576,7 @@ type ProjectConfig struct {
 	Backend          BackendConfig           `yaml:"backend"`
 	Build            BuildConfig             `yaml:"build"`
 	APIClient        APIClientConfig         `yaml:"api_client"`
+	CORS             CORSConfig              `yaml:"cors,omitempty"`
 	ExternalTemplate *ExternalTemplateConfig `yaml:"external_template,omitempty"`
 }
 
@@ -531,6 +637,17 @@ type BackendConfig struct {
 	Template string `yaml:"template,omitempty"` // Backend template name
 }
 
+// CORSConfig configures cross-origin resource sharing for the API and static assets
+type CORSConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	AllowedOrigins   []string `yaml:"allowed_origins"`             // Exact origins, "*" or subdomain wildcards like "https://*.example.com"
+	AllowedMethods   []string `yaml:"allowed_methods,omitempty"`   // Methods allowed in preflight responses
+	AllowedHeaders   []string `yaml:"allowed_headers,omitempty"`   // Request headers allowed in preflight responses
+	ExposedHeaders   []string `yaml:"exposed_headers,omitempty"`   // Response headers readable by the browser
+	AllowCredentials bool     `yaml:"allow_credentials,omitempty"` // Allow cookies and Authorization headers
+	MaxAge           int      `yaml:"max_age,omitempty"`           // Preflight cache duration in seconds
+}
+
 type BuildConfig struct {
 	OutputDir   string `yaml:"output_dir"`
 	BinaryName  string `yaml:"binary_name"`
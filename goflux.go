@@ -4,18 +4,57 @@ package goflux
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	gopath "path"
 	"reflect"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
-
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barisgit/goflux/internal/apikey"
+	"github.com/barisgit/goflux/internal/auth"
+	"github.com/barisgit/goflux/internal/batch"
+	"github.com/barisgit/goflux/internal/compress"
 	"github.com/barisgit/goflux/internal/core"
+	"github.com/barisgit/goflux/internal/cors"
+	"github.com/barisgit/goflux/internal/envconfig"
+	"github.com/barisgit/goflux/internal/errcodes"
+	"github.com/barisgit/goflux/internal/etag"
 	"github.com/barisgit/goflux/internal/features"
+	"github.com/barisgit/goflux/internal/filter"
+	"github.com/barisgit/goflux/internal/flags"
+	"github.com/barisgit/goflux/internal/formats"
+	"github.com/barisgit/goflux/internal/health"
+	"github.com/barisgit/goflux/internal/hooks"
+	"github.com/barisgit/goflux/internal/idempotency"
+	"github.com/barisgit/goflux/internal/jobs"
+	"github.com/barisgit/goflux/internal/jwt"
+	"github.com/barisgit/goflux/internal/lifecycle"
+	"github.com/barisgit/goflux/internal/limits"
+	"github.com/barisgit/goflux/internal/oidc"
 	"github.com/barisgit/goflux/internal/openapi"
+	"github.com/barisgit/goflux/internal/pagination"
 	"github.com/barisgit/goflux/internal/parsing"
+	"github.com/barisgit/goflux/internal/policy"
+	"github.com/barisgit/goflux/internal/schedule"
+	"github.com/barisgit/goflux/internal/session"
+	"github.com/barisgit/goflux/internal/sse"
 	"github.com/barisgit/goflux/internal/static"
 	"github.com/barisgit/goflux/internal/upload"
+	"github.com/barisgit/goflux/internal/validation"
+	"github.com/barisgit/goflux/internal/versioning"
+	"github.com/barisgit/goflux/internal/ws"
 	openapiutils "github.com/barisgit/goflux/openapi"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -62,7 +101,7 @@ Create dependencies without generics - types are inferred automatically:
 		return &UserService{}, nil
 	})
 
-	// Dependencies with additional input fields (e.g., pagination)
+	// Dependencies with additional input fields (see Pagination for the built-in paginators)
 	type PaginationParams struct {
 		Page     int `query:"page" minimum:"1" default:"1"`
 		PageSize int `query:"page_size" minimum:"1" maximum:"100" default:"20"`
@@ -157,6 +196,405 @@ Middleware uses standard Huma signatures with optional tRPC-style context extens
 		o.Description = "Admin-only user creation endpoint"
 	})
 
+# JWT Authentication
+
+	verifier, err := goflux.NewJWTVerifier(goflux.JWTOptions{
+		JWKSURL:  "https://auth.example.com/.well-known/jwks.json",
+		Issuer:   "https://auth.example.com/",
+		Audience: []string{"my-api"},
+	})
+
+	// Requires a valid bearer token and registers the "bearer" security scheme
+	jwtProcedure := goflux.JWTProcedure(goflux.PublicProcedure(dbDep), verifier)
+
+	// Typed claims can be injected like any other dependency
+	claimsDep := goflux.JWTClaimsDependency[MyClaims](verifier)
+	jwtProcedure.Inject(claimsDep).Get(api, "/me", func(ctx context.Context, input *struct{}, db *sql.DB, claims *MyClaims) (*MeOutput, error) {
+		...
+	})
+
+# Authorization
+
+	// Declarative requirements are checked against the authenticated principal
+	// and documented in the operation's security and x-goflux-authorization extension
+	jwtProcedure.RequireScopes("posts:write").RequireRoles("editor").Post(api, "/posts", createPost)
+
+	// Custom policies receive the parsed input and any procedure dependencies
+	jwtProcedure.Inject(currentUserDep, dbDep).Authorize(func(ctx context.Context, input *UpdatePostInput, user *User, db *sql.DB) error {
+		if !isOwner(ctx, db, user.ID, input.ID) {
+			return huma.Error403Forbidden("You can only edit your own posts")
+		}
+		return nil
+	}).Put(api, "/posts/{id}", updatePost)
+
+# API Keys
+
+	// Keys are looked up by SHA-256 hash; store HashAPIKey(raw), never the raw key
+	raw, hash, _ := goflux.GenerateAPIKey("sk_live_")
+	store := goflux.NewMemoryAPIKeyStore(&goflux.APIKey{ID: "key-1", Hash: hash, Subject: "billing-service", Scopes: []string{"invoices:read"}, Tier: "standard"})
+
+	authenticator, err := goflux.NewAPIKeyAuthenticator(goflux.APIKeyOptions{
+		Store: store,
+		Name:  "X-API-Key", // header by default, In can be "query" or "cookie"
+		Tiers: map[string]goflux.APIKeyRateLimit{"standard": {Requests: 100, Window: time.Minute}},
+	})
+
+	apiKeyProcedure := goflux.APIKeyProcedure(goflux.PublicProcedure(goflux.PrincipalDependency()), authenticator)
+
+# Cookie Sessions
+
+	store, _ := goflux.NewPgxSessionStore(pool, "")
+	manager, err := goflux.NewSessionManager(goflux.SessionOptions{
+		Secret: []byte(os.Getenv("SESSION_SECRET")),
+		Store:  store, // nil keeps the (signed, optionally encrypted) data in the cookie
+		Secure: true,
+	})
+
+	// Login/logout handlers receive the *Session as a dependency
+	sessionDep := goflux.SessionDependency(manager)
+
+	// Requires a logged-in session, enforces CSRF and registers the "session" security scheme
+	sessionProcedure := goflux.SessionProcedure(goflux.PublicProcedure(dbDep), manager)
+
+# OpenID Connect
+
+	// Authorization code + PKCE login; the user is logged into a cookie session on callback
+	rp, err := goflux.NewOIDCRelyingParty(goflux.OIDCOptions{
+		Issuer:      "https://accounts.example.com",
+		ClientID:    "my-app",
+		RedirectURL: "http://localhost:3000/api/auth/callback",
+	})
+	goflux.RegisterOIDCRoutes(api, rp, manager, "/api/auth")
+
+	// Requires a signed-in user and registers the "oidc" openIdConnect security scheme
+	oidcProcedure := goflux.OIDCProcedure(goflux.PublicProcedure(dbDep), rp, manager)
+
+	// In development and tests, a mock provider can stand in for the real one
+	mock, _ := goflux.NewMockOIDCProvider(goflux.MockOIDCOptions{
+		Issuer: "http://localhost:3000/api/_oidc",
+		Users:  []goflux.MockOIDCUser{{Subject: "alice", Email: "alice@example.com", Roles: []string{"admin"}}},
+	})
+	mux.Handle("/api/_oidc/", mock)
+
+# ETags and Conditional Requests
+
+	type Post struct {
+		ID        int       `json:"id"`
+		Version   int       `json:"version" etag:"version"`      // becomes the ETag
+		UpdatedAt time.Time `json:"updatedAt" etag:"modified"`   // becomes Last-Modified
+	}
+
+	// GET responses get ETags and answer If-None-Match / If-Modified-Since with 304
+	cached := goflux.PublicProcedure(dbDep).WithETags(goflux.ETagOptions{})
+
+	// Writes use If-Match for optimistic concurrency
+	cached.Put(api, "/posts/{id}", func(ctx context.Context, input *UpdatePostInput, db *sql.DB) (*PostOutput, error) {
+		post, err := loadPost(ctx, db, input.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := goflux.CheckPreconditions(ctx, post.Version, post.UpdatedAt); err != nil {
+			return nil, err // 412 Precondition Failed
+		}
+		...
+	})
+
+# Idempotency
+
+	// Retries with the same Idempotency-Key replay the first response instead of creating a second order
+	orderProcedure := jwtProcedure.WithIdempotency(goflux.IdempotencyOptions{
+		Store: goflux.NewMemoryIdempotencyStore(),
+		TTL:   24 * time.Hour,
+	})
+	orderProcedure.Post(api, "/orders", createOrder)
+
+# Content Negotiation
+
+	// Responses are JSON by default; Accept: application/cbor, application/msgpack or text/csv selects
+	// another encoder, and the matching Content-Type is decoded for request bodies
+	config := huma.DefaultConfig("My API", "1.0.0")
+	if err := goflux.ConfigureFormats(&config, goflux.MediaTypeCBOR, goflux.MediaTypeMessagePack, goflux.MediaTypeCSV); err != nil {
+		log.Fatal(err)
+	}
+	api := humachi.New(router, config)
+
+	// CSV is offered for list bodies and streamed row by row, e.g. GET /api/users with Accept: text/csv
+
+# Compression
+
+	// gzip or brotli, negotiated from Accept-Encoding, for bodies of at least 1KB with a compressible type
+	compressor := goflux.NewCompressor(goflux.CompressionOptions{MinSize: 1024})
+
+	// Everything served by the router, API and static files alike
+	router.Use(gofluxchi.CompressionMiddleware(compressor))
+
+	// Or only the operations of one procedure
+	goflux.PublicProcedure(dbDep).WithCompression(goflux.CompressionOptions{}).Get(api, "/posts", listPosts)
+
+# Server-Sent Events
+
+	// Each event name maps to a payload type; the stream is documented in OpenAPI and the
+	// trpc-like client gets a typed EventSource wrapper for it
+	chatProcedure := goflux.PublicProcedure(dbDep).WithSSE(goflux.SSEOptions{Heartbeat: 15 * time.Second})
+	chatProcedure.SSE(api, "/rooms/{room}/events", map[string]any{
+		"message": ChatMessage{},
+		"typing":  TypingEvent{},
+	}, func(ctx context.Context, input *RoomInput, send *goflux.EventSender, db *sql.DB) error {
+		// Resume after the last event the browser saw before reconnecting
+		for msg := range messagesSince(ctx, db, input.Room, send.LastEventID()) {
+			if err := send.Send(goflux.SSEMessage{ID: msg.ID, Data: msg}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+# WebSockets
+
+	// ChatCommand messages come from the client and ChatEvent messages go to it; both are validated
+	// against their schemas, and the trpc-like client gets a typed, reconnecting socket for the route
+	socketProcedure := goflux.JWTProcedure(base, verifier).WithWebSocket(goflux.WebSocketOptions{PingInterval: 30 * time.Second})
+	socketProcedure.WebSocket(api, "/rooms/{room}/socket", func(ctx context.Context, input *RoomInput, conn *goflux.WebSocketConn[ChatCommand, ChatEvent], hub *Hub) error {
+		for {
+			cmd, err := conn.Receive(ctx)
+			if errors.Is(err, goflux.ErrWebSocketClosed) {
+				return nil
+			}
+			var invalid *goflux.WebSocketMessageError
+			if errors.As(err, &invalid) {
+				continue // The connection stays open
+			}
+			if err != nil {
+				return err
+			}
+			if err := conn.Send(ctx, hub.Apply(input.Room, cmd)); err != nil {
+				return err
+			}
+		}
+	})
+
+# Batching
+
+	// POST /api/batch takes a list of {operationId, params, body} calls and answers with one
+	// {status, body} result per call; the trpc-like client sends queries made in the same tick through it
+	goflux.PublicProcedure().WithBatch(goflux.BatchOptions{MaxCalls: 20}).Batch(api, "/api/batch")
+
+# Background Jobs
+
+	// Job types pair a kind with a payload type; workers get the procedure's dependencies like handlers
+	var SendWelcomeEmail = goflux.DefineJob[WelcomeEmail]("send-welcome-email")
+
+	store, _ := goflux.NewPgxJobStore(pool, "") // or goflux.NewMemoryJobStore()
+	queue := goflux.NewJobQueue(store, goflux.JobQueueOptions{Concurrency: 8, MaxAttempts: 5})
+	goflux.PublicProcedure(mailerDep).Worker(queue, SendWelcomeEmail, func(ctx context.Context, email *WelcomeEmail, mailer *Mailer) error {
+		return mailer.Send(ctx, email.To, "Welcome!")
+	})
+	go queue.Run(ctx)
+
+	// Enqueue from a handler, optionally delayed
+	SendWelcomeEmail.Enqueue(ctx, queue, WelcomeEmail{To: user.Email}, goflux.JobOptions{Delay: time.Minute})
+
+	// List, retry and cancel jobs over HTTP
+	adminProcedure.RegisterJobAdmin(api, queue, "/api/admin/jobs")
+
+# Scheduled Tasks
+
+	// Five-field cron expressions, macros such as @daily, or intervals such as "@every 5m"
+	goflux.PublicProcedure(dbDep).Schedule("0 3 * * *", func(ctx context.Context, db *DB) error {
+		return db.DeleteExpiredSessions(ctx)
+	}, goflux.TaskOptions{Name: "purge-sessions"})
+
+	// Run each occurrence on one instance only
+	locker, _ := goflux.NewPgxLocker(pool, "")
+	goflux.ConfigureScheduler(goflux.SchedulerOptions{Locker: locker})
+	go goflux.RunScheduler(ctx)
+
+	// Failed requests and tasks are reported to the same hooks
+	goflux.OnError(func(ctx context.Context, event goflux.ErrorEvent) {
+		slog.ErrorContext(ctx, "failure", "source", event.Source, "name", event.Name, "error", event.Err)
+	})
+
+	// List operations and scheduled tasks over HTTP
+	adminProcedure.RegisterRouteListing(api, "/api/admin/routes")
+
+# Server Lifecycle
+
+	// Singletons are loaded once and closed on shutdown, in reverse order of loading
+	var PoolDep = goflux.NewSingleton("pool", func(ctx context.Context, _ interface{}) (*pgxpool.Pool, error) {
+		return pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	})
+	goflux.OnShutdown("tracer", tracerProvider.Shutdown)
+
+	// Prints the banner, serves until SIGINT/SIGTERM, then fails readiness, drains in-flight
+	// requests and runs the shutdown hooks (Fiber: Server: gofluxfiber.Server(app))
+	err := goflux.Serve(ctx, api, goflux.ServeOptions{
+		Addr:            ":8080",
+		ShutdownTimeout: 20 * time.Second,
+		Greet:           goflux.GreetOptions{ServiceName: "my-api", Version: "1.0.0"},
+	})
+
+# Health Checks
+
+	// Checks run concurrently with a timeout, results are cached briefly
+	var PoolDep = goflux.NewSingleton("postgres", loadPool).WithHealthCheck(func(ctx context.Context, pool *pgxpool.Pool) error {
+		return pool.Ping(ctx)
+	}, goflux.HealthCheckOptions{Critical: true})
+	goflux.RegisterHealthCheck("smtp", mailer.Ping, goflux.HealthCheckOptions{Timeout: time.Second})
+
+	// GET /api/livez and /api/readyz; readiness fails until the warm-up is done and during shutdown
+	goflux.AddHealthProbes(api, "/api")
+	adminProcedure.RegisterHealthReport(api, "/api/admin/health")
+	goflux.Serve(ctx, api, goflux.ServeOptions{WarmUp: []goflux.Dependency{PoolDep}})
+
+# Configuration
+
+	// Filled from flags, environment variables, .env.{GO_ENV}.local, .env.local, .env.{GO_ENV}, .env
+	// and defaults; all missing variables are reported at once
+	type Config struct {
+		Port        int           `env:"PORT" default:"3000" flag:"port"`
+		DatabaseURL string        `env:"DATABASE_URL" required:"true" secret:"true" doc:"Postgres connection string"`
+		Timeout     time.Duration `default:"5s"`
+		SMTP        struct {
+			Host string `required:"true"` // SMTP_HOST
+		}
+	}
+	cfg := goflux.MustLoadConfig[Config](goflux.ConfigOptions{Args: os.Args[1:]})
+	log.Println(goflux.FormatConfig(cfg)) // DATABASE_URL=******
+
+	// Handlers receive *Config like any other dependency
+	var ConfigDep = goflux.ConfigDependency(cfg)
+
+# Feature Flags
+
+	// Defaults in code; flags.yaml (flags: [{key, enabled, rollout, users, tenants}]) overrides them
+	// and is reloaded when it changes
+	goflux.DefineFlag(goflux.Flag{Key: "new-checkout", Enabled: true, Rollout: &twentyFive, Tenants: []string{"acme"}})
+	if err := goflux.WatchFlags(ctx, "flags.yaml", 0); err != nil {
+		log.Fatal(err)
+	}
+
+	// 404 for callers without the flag; left out of the spec while the flag is disabled
+	checkoutProcedure := authProcedure.RequireFlag("new-checkout")
+
+	// Serve documents built per request, so flag reloads show and hide operations. Leave huma's
+	// OpenAPIPath and DocsPath empty, its document is fixed after the first request
+	goflux.ServeOpenAPI(api, "/api/openapi", "/api/docs")
+
+	// Handlers evaluate flags for the caller (principal subject and tenant claim)
+	func(ctx context.Context, input *CartInput, flags *goflux.FlagEvaluator) (*CartOutput, error) {
+		if flags.Enabled("new-checkout") { ... }
+	}
+
+	// GET /api/flags evaluates every flag; the keys become the FeatureFlag type of the TS client
+	authProcedure.RegisterFlagsEndpoint(api, "/api/flags")
+
+# Pagination
+
+	// ?limit=&offset= with a total, or ?limit=&cursor= with opaque signed cursors
+	type ListUsersOutput struct {
+		Body goflux.Page[User] // {items, limit, offset, total, nextCursor, hasMore}
+	}
+	goflux.PublicProcedure(dbDep, goflux.OffsetPagination()).Get(api, "/api/users",
+		func(ctx context.Context, input *struct{}, db *DB, page *goflux.OffsetPaginator) (*ListUsersOutput, error) {
+			users, total, err := db.ListUsers(ctx, page.Limit, page.Offset)
+			return &ListUsersOutput{Body: goflux.NewOffsetPage(page, users, total)}, err
+		})
+
+	// Cursors carry any JSON position; the response gets Link headers and the generated React
+	// Query client a useInfiniteQuery helper
+	goflux.PublicProcedure(dbDep, goflux.CursorPagination(goflux.PaginationOptions{Secret: secret})).Get(api, "/api/events",
+		func(ctx context.Context, input *struct{}, db *DB, cursor *goflux.CursorPaginator) (*ListEventsOutput, error) {
+			var after EventPosition
+			if _, err := cursor.Scan(&after); err != nil {
+				return nil, err
+			}
+			events, next := db.EventsAfter(ctx, after, cursor.Limit) // next is nil on the last page
+			page, err := goflux.NewCursorPage(cursor, events, next)
+			return &ListEventsOutput{Body: page}, err
+		})
+
+# Filtering and Sorting
+
+	// Every exported field is whitelisted; ops default by type (strings: eq, ne, in, contains,
+	// prefix; numbers: eq, ne, gt, gte, lt, lte, in; dates: eq, gt, gte, lt, lte; bools: eq)
+	type UserFilters struct {
+		Name      string    `filter:"name" ops:"eq,contains" sort:"true"`
+		Age       int       `filter:"age"`
+		CreatedAt time.Time `filter:"created_at" column:"u.created_at" sort:"true"`
+	}
+	var UserFiltering = goflux.Filtering[UserFilters](goflux.FilterOptions{DefaultSort: "-created_at"})
+
+	// GET /api/users?filter[name][contains]=ann&filter[age][gte]=18&sort=-created_at,name
+	goflux.PublicProcedure(dbDep, UserFiltering).Get(api, "/api/users",
+		func(ctx context.Context, input *struct{}, db *DB, q *goflux.ListQuery[UserFilters]) (*ListUsersOutput, error) {
+			where, args := q.Where(1) // "name ILIKE $1 AND age >= $2", ["%ann%", 18]
+			rows, err := db.Query(ctx, "SELECT * FROM users u WHERE "+where+" ORDER BY "+q.OrderBy(), args...)
+			...
+		})
+
+	// The TS client gets typed filters: { filter: { name: { contains: 'ann' } }, sort: ['-created_at'] }
+
+# Error Codes
+
+	// Codes are unique; {placeholders} are filled from the JSON fields of the details
+	type UserNotFoundDetails struct {
+		ID int `json:"id"`
+	}
+	var ErrUserNotFound = goflux.DefineError[UserNotFoundDetails]("user_not_found", http.StatusNotFound, "User {id} was not found")
+
+	// Declared codes are documented as responses; errors are sent as problems with code and details:
+	// {"title": "Not Found", "status": 404, "detail": "User 7 was not found", "code": "user_not_found", "details": {"id": 7}}
+	goflux.PublicProcedure(dbDep).WithErrors(ErrUserNotFound).Get(api, "/api/users/{id}",
+		func(ctx context.Context, input *GetUserInput, db *DB) (*GetUserOutput, error) {
+			user, ok := db.FindUser(ctx, input.ID)
+			if !ok {
+				return nil, ErrUserNotFound.New(UserNotFoundDetails{ID: input.ID})
+			}
+			return &GetUserOutput{Body: user}, nil
+		})
+
+	// The TS client gets an AppError union and guards: if (isAppError(err)) switch (err.code) { ... }
+
+# Validation
+
+Inputs, including the input fields of dependencies, implement InputValidator for rules struct tags
+can't express. Validators run after authorization and before the handler; their problems are sent
+together as one 422 response:
+
+	func (in *CreatePostInput) ValidateInput(ctx context.Context, v *goflux.Validation) error {
+		db, err := goflux.Inject[*DB](v)
+		if err != nil {
+			return err
+		}
+		if db.SlugExists(ctx, in.Body.Slug) {
+			v.AddError("body.slug", "slug is already taken", in.Body.Slug)
+		}
+		return nil
+	}
+
+# Request Limits
+
+Procedures bound the size of request bodies, uploads included, and the time spent on a request. Too
+large bodies get 413, bodies not received in time 408, and the handler's context is cancelled at the
+deadline, answering 503 if it fails. The limits are listed in the x-goflux-limits extension:
+
+	api := goflux.PublicProcedure(dbDep).WithLimits(1<<20, 10*time.Second)
+	api.Post(humaAPI, "/api/posts", createPost)
+	api.Post(humaAPI, "/api/avatars", uploadAvatar, goflux.OperationLimits(10<<20, time.Minute))
+
+# API Versioning
+
+Procedures declare the version their operations are served under, so /api/v1 and /api/v2 run
+side by side. Each version gets its own OpenAPI document and generated client; deprecated operations
+answer with Deprecation, Sunset and Link headers and clients warn when they are called:
+
+	v1 := goflux.PublicProcedure(dbDep).WithVersion("v1").
+		WithDeprecation(goflux.Deprecation{Since: deprecatedAt, Sunset: sunsetAt, Successor: "/api/v2/users"})
+	v2 := goflux.PublicProcedure(dbDep).WithVersion("v2")
+
+	v1.Get(api, "/api/users", listUsersV1) // GET /api/v1/users, documented at /v1/openapi.json
+	v2.Get(api, "/api/users", listUsersV2) // GET /api/v2/users, documented at /v2/openapi.json
+
 # Migration from Huma
 
 GoFlux is designed to be a drop-in replacement for Huma:
@@ -287,12 +725,37 @@ func (d *Dependency) getCore() *core.DependencyCore {
 	return d.core
 }
 
+// operationAnnotations maps built-in dependencies to a function documenting them on the operations
+// that inject them
+var operationAnnotations sync.Map
+
+// annotateOperations registers the documentation of a dependency. Register it on the final
+// dependency, since WithInputFields and RequiresMiddleware return copies
+func annotateOperations(dep Dependency, annotate func(operation *huma.Operation)) {
+	operationAnnotations.Store(dep.core, annotate)
+}
+
 // Procedure represents a fluent builder for dependency injection
 type Procedure struct {
 	registry    *core.DependencyRegistry
 	middlewares []Middleware
 	security    []map[string][]string
 	utils       core.MiddlewareUtils
+	cors        *cors.Policy
+	schemes     map[string]*huma.SecurityScheme
+	required    policy.Requirements
+	policies    []interface{}
+	idempotency *idempotency.Manager
+	etags       *etag.Options
+	compression *compress.Compressor
+	sse         sse.Options
+	websocket   ws.Options
+	batch       batch.Options
+	flags       []string
+	errorCodes  []*errcodes.Definition
+	limits      limits.Limits
+	version     string
+	deprecation *versioning.Deprecation
 }
 
 // NewProcedure creates a new procedure builder
@@ -330,12 +793,9 @@ func (p *Procedure) Use(middleware ...Middleware) *Procedure {
 		newMiddlewares[i] = mw.(Middleware)
 	}
 
-	return &Procedure{
-		registry:    p.registry,
-		middlewares: newMiddlewares,
-		security:    p.security,
-		utils:       p.utils,
-	}
+	procedure := p.clone()
+	procedure.middlewares = newMiddlewares
+	return procedure
 }
 
 // Inject adds additional dependencies with automatic middleware collection and deduplication
@@ -378,22 +838,203 @@ func (p *Procedure) Inject(deps ...Dependency) *Procedure {
 		newMiddlewares[i] = mw.(Middleware)
 	}
 
-	return &Procedure{
-		registry:    newRegistry,
-		middlewares: newMiddlewares,
-		security:    p.security,
-		utils:       p.utils,
-	}
+	procedure := p.clone()
+	procedure.registry = newRegistry
+	procedure.middlewares = newMiddlewares
+	return procedure
 }
 
 // WithSecurity adds security requirements to the procedure
 func (p *Procedure) WithSecurity(security ...map[string][]string) *Procedure {
-	return &Procedure{
-		registry:    p.registry,
-		middlewares: p.middlewares,
-		security:    append(p.security, security...),
-		utils:       p.utils,
+	procedure := p.clone()
+	procedure.security = append(append([]map[string][]string{}, p.security...), security...)
+	return procedure
+}
+
+// WithSecurityScheme declares a security scheme that is added to the OpenAPI components
+// when an operation is registered with this procedure (existing schemes with the same name are kept)
+func (p *Procedure) WithSecurityScheme(name string, scheme *huma.SecurityScheme) *Procedure {
+	procedure := p.clone()
+	procedure.schemes = make(map[string]*huma.SecurityScheme, len(p.schemes)+1)
+	for k, v := range p.schemes {
+		procedure.schemes[k] = v
+	}
+	procedure.schemes[name] = scheme
+	return procedure
+}
+
+// RequireScopes requires the authenticated principal to have all of the given scopes
+// The scopes are also added to the operation's security requirements
+func (p *Procedure) RequireScopes(scopes ...string) *Procedure {
+	procedure := p.clone()
+	procedure.required = p.required.Merge(policy.Requirements{Scopes: scopes})
+	return procedure
+}
+
+// RequireRoles requires the authenticated principal to have all of the given roles
+func (p *Procedure) RequireRoles(roles ...string) *Procedure {
+	procedure := p.clone()
+	procedure.required = p.required.Merge(policy.Requirements{Roles: roles})
+	return procedure
+}
+
+// RequirePermissions requires the authenticated principal to have all of the given permissions
+func (p *Procedure) RequirePermissions(permissions ...string) *Procedure {
+	procedure := p.clone()
+	procedure.required = p.required.Merge(policy.Requirements{Permissions: permissions})
+	return procedure
+}
+
+// Authorize adds custom policy functions that run after input parsing and before the handler
+// A policy has the signature func(context.Context, *InputType, ...deps) error, where deps are
+// resolved from the procedure like handler dependencies. Returning an error denies the request
+// (huma status errors keep their status, anything else becomes 403 Forbidden)
+// Example: procedure.Authorize(func(ctx context.Context, input *UpdatePostInput, user *User, db *sql.DB) error { ... })
+func (p *Procedure) Authorize(policies ...interface{}) *Procedure {
+	procedure := p.clone()
+	procedure.policies = append(append([]interface{}{}, p.policies...), policies...)
+	return procedure
+}
+
+// WithCORS overrides the router-level CORS policy for every operation registered with this procedure
+// Preflight and actual requests to these operations are answered using the given options instead.
+// The API must have its router-level policy bound with UseCORS, registration panics otherwise
+// Example: goflux.PublicProcedure().WithCORS(goflux.CORSOptions{AllowedOrigins: []string{"*"}})
+func (p *Procedure) WithCORS(options CORSOptions) *Procedure {
+	policy, err := cors.New(options)
+	if err != nil {
+		panic(err.Error())
+	}
+	procedure := p.clone()
+	procedure.cors = policy
+	return procedure
+}
+
+// WithIdempotency makes unsafe operations registered with this procedure honor the Idempotency-Key header
+// The first response for a key and client (principal, session or remote address) is stored and
+// replayed for identical retries. Behind a reverse proxy, set ClientHeader so anonymous clients
+// don't share the proxy's address.
+// Concurrent duplicates get 409 Conflict, and reusing a key with a different payload gets 422
+// Example: goflux.JWTProcedure(base, verifier).WithIdempotency(goflux.IdempotencyOptions{TTL: 24 * time.Hour})
+func (p *Procedure) WithIdempotency(options IdempotencyOptions) *Procedure {
+	procedure := p.clone()
+	procedure.idempotency = idempotency.New(options)
+	return procedure
+}
+
+// WithETags enables automatic ETags for operations registered with this procedure
+// Successful GET responses get an ETag (from the output's `etag:"version"` field or a hash of the body)
+// and conditional requests are answered with 304 Not Modified. Write handlers can call
+// CheckPreconditions to reject stale If-Match requests with 412 Precondition Failed
+// Example: goflux.PublicProcedure(dbDep).WithETags(goflux.ETagOptions{})
+func (p *Procedure) WithETags(options ETagOptions) *Procedure {
+	procedure := p.clone()
+	procedure.etags = &options
+	return procedure
+}
+
+// WithCompression compresses the responses of operations registered with this procedure
+// using gzip or brotli, negotiated from the request's Accept-Encoding header.
+// To compress every API and static response instead, use the compression middleware of a router adapter
+// Example: goflux.PublicProcedure(dbDep).WithCompression(goflux.CompressionOptions{MinSize: 2048})
+func (p *Procedure) WithCompression(options CompressionOptions) *Procedure {
+	procedure := p.clone()
+	procedure.compression = compress.New(options)
+	return procedure
+}
+
+// WithSSE configures the heartbeat and reconnection delay of event streams registered with this procedure
+// Example: goflux.PublicProcedure(dbDep).WithSSE(goflux.SSEOptions{Heartbeat: 30 * time.Second, Retry: 5000})
+func (p *Procedure) WithSSE(options SSEOptions) *Procedure {
+	procedure := p.clone()
+	procedure.sse = options
+	return procedure
+}
+
+// WithWebSocket configures the allowed origins, subprotocols, pings and read limit of WebSocket
+// endpoints registered with this procedure
+// Example: goflux.PublicProcedure(dbDep).WithWebSocket(goflux.WebSocketOptions{OriginPatterns: []string{"app.example.com"}})
+func (p *Procedure) WithWebSocket(options WebSocketOptions) *Procedure {
+	procedure := p.clone()
+	procedure.websocket = options
+	return procedure
+}
+
+// WithBatch configures the call limit and concurrency of batch endpoints registered with this procedure
+// Example: goflux.PublicProcedure().WithBatch(goflux.BatchOptions{MaxCalls: 20})
+func (p *Procedure) WithBatch(options BatchOptions) *Procedure {
+	procedure := p.clone()
+	procedure.batch = options
+	return procedure
+}
+
+// RequireFlag gates the operations registered with this procedure behind feature flags. Requests
+// for which a flag is off get 404 Not Found, as if the operation did not exist, and operations whose
+// flag is off for everyone are left out of the OpenAPI documents served by ServeOpenAPI and generated
+// by the openapi command, following flag reloads
+// Example: goflux.JWTProcedure(base, verifier).RequireFlag("new-checkout")
+func (p *Procedure) RequireFlag(keys ...string) *Procedure {
+	procedure := p.clone()
+	procedure.flags = append(append([]string{}, p.flags...), keys...)
+	return procedure
+}
+
+// WithErrors declares the application error codes the operations registered with this procedure can
+// respond with. They are documented as responses in the OpenAPI spec and become the typed error union
+// of the generated client
+// Example: goflux.PublicProcedure(dbDep).WithErrors(ErrUserNotFound, ErrEmailTaken)
+func (p *Procedure) WithErrors(codes ...ErrorCodeDefinition) *Procedure {
+	procedure := p.clone()
+	procedure.errorCodes = slices.Clone(p.errorCodes)
+	for _, code := range codes {
+		procedure.errorCodes = append(procedure.errorCodes, code.errorDefinition())
+	}
+	return procedure
+}
+
+// WithLimits bounds the requests of the operations registered with this procedure: bodies, uploads
+// included, larger than maxBody bytes are rejected with 413, bodies not received within timeout with
+// 408, and the handler's context is cancelled once timeout passed, answering 503 if it fails. SSE and
+// WebSocket handlers are not cancelled, their timeout only bounds reading the request. Zero
+// disables a limit; operations override them with OperationLimits
+// Example: goflux.PublicProcedure(dbDep).WithLimits(1<<20, 10*time.Second)
+func (p *Procedure) WithLimits(maxBody int64, timeout time.Duration) *Procedure {
+	procedure := p.clone()
+	procedure.limits = limits.Limits{MaxBodyBytes: maxBody, Timeout: timeout}
+	return procedure
+}
+
+// WithVersion serves the operations registered with this procedure under an API version: the
+// version follows the /api prefix of their paths (/api/users becomes /api/v1/users) or prefixes
+// other paths. Each version gets its own OpenAPI document at /<version>/openapi.json and its own
+// generated client
+// Example:
+//
+//	v1 := goflux.PublicProcedure(dbDep).WithVersion("v1")
+//	v2 := goflux.PublicProcedure(dbDep).WithVersion("v2")
+func (p *Procedure) WithVersion(version string) *Procedure {
+	if err := versioning.Validate(version); err != nil {
+		panic(err.Error())
 	}
+	procedure := p.clone()
+	procedure.version = version
+	return procedure
+}
+
+// WithDeprecation marks the operations registered with this procedure as deprecated. Their responses
+// carry the Deprecation, Sunset and successor-version Link headers, and generated clients warn when
+// they are called. Operations override it with OperationDeprecation
+// Example: v1.WithDeprecation(goflux.Deprecation{Since: deprecatedAt, Sunset: sunsetAt, Successor: "/api/v2/users"})
+func (p *Procedure) WithDeprecation(deprecation Deprecation) *Procedure {
+	procedure := p.clone()
+	procedure.deprecation = &deprecation
+	return procedure
+}
+
+// clone returns a shallow copy of the procedure so builder methods never mutate their receiver
+func (p *Procedure) clone() *Procedure {
+	procedure := *p
+	return &procedure
 }
 
 // Internal access methods for the register.go file
@@ -418,6 +1059,11 @@ func (p *Procedure) Register(
 	// Find the actual user code location (skip framework code)
 	location := core.FindUserCodeLocation()
 
+	// Serve the operation under the procedure's version
+	if p.version != "" {
+		operation.Path = versioning.Path(p.version, operation.Path)
+	}
+
 	handlerValue := reflect.ValueOf(handler)
 	handlerType := handlerValue.Type()
 
@@ -458,6 +1104,9 @@ func (p *Procedure) Register(
 		panic(fmt.Sprintf("Handler validation failed: %v", err))
 	}
 
+	// Validate custom policies and account for the dependencies they use
+	policies := p.validatePolicies(operation.OperationID, inputParamType, validationResult)
+
 	// Report errors if any
 	if len(validationResult.MissingTypes) > 0 {
 		FormatMissingDependenciesError(operation.OperationID, location.File, location.Line, MissingDependencies{
@@ -467,14 +1116,59 @@ func (p *Procedure) Register(
 		panic(fmt.Sprintf("missing dependencies for operation '%s' - see error details above", operation.OperationID))
 	}
 
+	// Resolve the body and time limits of the operation
+	requestLimits := p.limits.ForOperation(&operation)
+
+	// Resolve the deprecation of the operation; huma's Deprecated flag alone announces it without dates
+	deprecation := p.deprecation
+	if override, ok := operation.Metadata[deprecationMetadataKey].(*Deprecation); ok {
+		deprecation = override
+	} else if deprecation == nil && operation.Deprecated {
+		deprecation = &Deprecation{}
+	}
+
+	// Find the input validators; they may inject any dependency of the procedure
+	validators := p.inputValidators(inputType, validationResult)
+
 	// Report warnings for unused dependencies
-	if len(validationResult.UnusedDeps) > 0 {
+	if len(validationResult.UnusedDeps) > 0 && len(validators) == 0 {
 		FormatUnusedDependenciesWarning(operation.OperationID, location.File, location.Line, convertCoreDepsListToPublic(validationResult.UnusedDeps))
 	}
 
 	// Apply middlewares and security to operation first
 	applyMiddlewaresAndSecurity(&operation, p, api)
 
+	// Document declared scopes, roles and permissions
+	p.required.Annotate(&operation)
+
+	// Document the feature flags gating the operation
+	if len(p.flags) > 0 {
+		annotateFlags(&operation, api, p.flags)
+	}
+
+	// Document conditional request headers and the 412 response of writes
+	if p.etags != nil {
+		annotateConditionalRequest(&operation, inputType)
+	}
+
+	// Document the idempotency key header and its error responses
+	if p.idempotency != nil {
+		annotateIdempotency(&operation, p.idempotency.Options())
+	}
+
+	// Document the 422 response of input validators
+	if len(validators) > 0 && !slices.Contains(operation.Errors, http.StatusUnprocessableEntity) {
+		operation.Errors = append(operation.Errors, http.StatusUnprocessableEntity)
+	}
+
+	// Document the limits and the 413, 408 and 503 responses they cause
+	requestLimits.Annotate(&operation)
+
+	// Register per-procedure CORS overrides with the API's router-level policy
+	if p.cors != nil {
+		overrideCORS(api, operation, p.cors)
+	}
+
 	// Process the operation using the schema processor
 	schemaProcessor := openapi.NewSchemaProcessor()
 	deps := make([]*core.DependencyCore, 0, len(validationResult.DepsByType))
@@ -486,11 +1180,49 @@ func (p *Procedure) Register(
 		panic(fmt.Sprintf("Failed to process operation schema: %v", err))
 	}
 
+	// Let dependencies document what they read, e.g. the pagination limits or filterable fields
+	for _, dep := range deps {
+		if annotate, ok := operationAnnotations.Load(dep); ok {
+			annotate.(func(*huma.Operation))(&operation)
+		}
+	}
+
+	// Document the validators and 304 response of conditional reads
+	if p.etags != nil {
+		annotateConditionalResponses(&operation)
+	}
+
+	// Document the application error codes of the operation
+	errcodes.Annotate(&operation, api.OpenAPI(), p.errorCodes)
+
+	// Document the version and deprecation of the operation, and serve the version's document
+	versioning.Annotate(&operation, api.OpenAPI(), p.version, deprecation)
+	if p.version != "" {
+		serveVersionDocument(api, p.version)
+	}
+
+	// List the CBOR, MessagePack, CSV, ... bodies the API can negotiate next to JSON
+	formats.Annotate(&operation, api)
+	negotiable := len(formats.Supported(api)) > 0
+
 	// Create a dependency injection wrapper that will be registered as the actual handler
 	diWrapper := func(ctx huma.Context) {
+		// Compress everything written below, including error responses
+		if p.compression != nil {
+			compressed := p.compression.NewContext(ctx)
+			defer compressed.Close()
+			ctx = compressed
+		}
+
+		// Announce deprecation on every response, errors included
+		if deprecation != nil {
+			deprecation.WriteHeaders(ctx)
+		}
+
 		// Check if response has already been written (for safety)
 		defer func() {
 			if r := recover(); r != nil {
+				reportRequestError(ctx, operation, http.StatusInternalServerError, fmt.Errorf("%v", r), true)
 				// Don't write error if response was already started
 				if ctx.Status() == 0 {
 					huma.WriteErr(api, ctx, http.StatusInternalServerError, "Internal server error", fmt.Errorf("%v", r))
@@ -498,66 +1230,213 @@ func (p *Procedure) Register(
 			}
 		}()
 
+		// Check declared requirements before touching the input
+		if !p.required.IsEmpty() {
+			principal, _ := PrincipalFromContext(ctx.Context())
+			if err := p.required.Check(principal); err != nil {
+				writePolicyError(api, ctx, err)
+				return
+			}
+		}
+
+		// Operations behind a flag that is off do not exist for the caller
+		for _, key := range p.flags {
+			if !flags.Default.Enabled(key, FlagTargetFromContext(ctx.Context())) {
+				huma.WriteErr(api, ctx, http.StatusNotFound, "Not Found")
+				return
+			}
+		}
+
+		// Bound the body and the time spent reading it and running the handler
+		if !requestLimits.IsZero() {
+			limited, cancel, err := requestLimits.Wrap(ctx)
+			defer cancel()
+			if err != nil {
+				writeParseError(api, ctx, "Failed to parse input", err)
+				return
+			}
+			ctx = limited
+		}
+
 		// Create an instance of the input type
 		inputPtr := reflect.New(inputType)
 
 		// Parse the input from the request using the request parser
 		requestParser := parsing.NewRequestParser()
 		if err := requestParser.ParseInput(api, ctx, inputPtr, inputType); err != nil {
-			huma.WriteErr(api, ctx, http.StatusBadRequest, "Failed to parse input", err)
+			writeParseError(api, ctx, "Failed to parse input", err)
 			return
 		}
 
-		// Prepare handler arguments
-		handlerArgs := []reflect.Value{
-			reflect.ValueOf(ctx.Context()),
-			inputPtr,
+		// Make conditional write headers available to CheckPreconditions
+		preconditions := etag.Preconditions{
+			IfMatch:           ctx.Header("If-Match"),
+			IfNoneMatch:       ctx.Header("If-None-Match"),
+			IfUnmodifiedSince: ctx.Header("If-Unmodified-Since"),
+		}
+		if !preconditions.IsEmpty() && ctx.Method() != http.MethodGet && ctx.Method() != http.MethodHead {
+			ctx = huma.WithContext(ctx, etag.WithPreconditions(ctx.Context(), preconditions, p.etags != nil && p.etags.Weak))
 		}
 
-		// Resolve and inject dependencies
-		for i := 2; i < handlerType.NumIn(); i++ {
-			paramType := handlerType.In(i)
+		// Resolve dependencies once per request, shared by policies and the handler
+		resolved := make(map[reflect.Type]reflect.Value)
+		depInputs := make(map[*core.DependencyCore]reflect.Value)
+		resolve := func(paramType reflect.Type, index int) (reflect.Value, bool) {
+			if value, ok := resolved[paramType]; ok {
+				return value, true
+			}
+
+			dep, exists := validationResult.DepsByType[paramType]
+			if !exists && len(validators) > 0 {
+				dep, exists = p.getRegistry().Get(paramType)
+			}
+			if !exists {
+				err := fmt.Errorf("no dependency found for parameter %d of type %v", index, paramType)
+				if index < 0 {
+					err = fmt.Errorf("no dependency found for input validator of type %v", paramType)
+				}
+				// Don't write error if response was already started
+				if ctx.Status() == 0 {
+					huma.WriteErr(api, ctx, http.StatusInternalServerError, "Missing dependency", err)
+				}
+				return reflect.Value{}, false
+			}
 
-			if dep, exists := validationResult.DepsByType[paramType]; exists {
-				// Parse dependency-specific input if the dependency has InputFields
-				var depInput interface{}
-				if dep.InputFields != nil {
+			// Parse dependency-specific input if the dependency has InputFields
+			var depInput interface{}
+			if dep.InputFields != nil {
+				// Reuse the input parsed for the dependency's validator
+				depInputPtr, parsed := depInputs[dep]
+				if !parsed {
 					// Create an instance of the dependency's input type
-					depInputPtr := reflect.New(dep.InputFields)
+					depInputPtr = reflect.New(dep.InputFields)
 
 					// Parse dependency input fields from the request using the request parser
 					if err := requestParser.ParseInput(api, ctx, depInputPtr, dep.InputFields); err != nil {
-						huma.WriteErr(api, ctx, http.StatusBadRequest, "Failed to parse dependency input", err)
-						return
+						writeParseError(api, ctx, "Failed to parse dependency input", err)
+						return reflect.Value{}, false
 					}
-
-					depInput = depInputPtr.Interface()
-				} else {
-					// No specific input fields, pass the main input
-					depInput = inputPtr.Interface()
 				}
 
-				resolved, err := dep.Load(ctx.Context(), depInput)
-				if err != nil {
-					// Don't write error if response was already started
-					if ctx.Status() == 0 {
-						huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to resolve dependency", err)
-					}
-					return
-				}
-				handlerArgs = append(handlerArgs, reflect.ValueOf(resolved))
+				depInput = depInputPtr.Interface()
 			} else {
-				err := fmt.Errorf("no dependency found for parameter %d of type %v", i-2, paramType)
+				// No specific input fields, pass the main input
+				depInput = inputPtr.Interface()
+			}
+
+			value, err := dep.Load(ctx.Context(), depInput)
+			if err != nil {
 				// Don't write error if response was already started
 				if ctx.Status() == 0 {
-					huma.WriteErr(api, ctx, http.StatusInternalServerError, "Missing dependency", err)
+					var se huma.StatusError
+					if limits.TimedOut(ctx.Context()) {
+						huma.WriteErr(api, ctx, http.StatusServiceUnavailable, "Request timed out", err)
+					} else if errors.As(err, &se) {
+						writeStatusError(api, ctx, se)
+					} else {
+						huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to resolve dependency", err)
+					}
 				}
-				return
+				return reflect.Value{}, false
 			}
+
+			resolved[paramType] = reflect.ValueOf(value)
+			return resolved[paramType], true
 		}
 
-		// Call the original handler
-		results := handlerValue.Call(handlerArgs)
+		// Run custom policies
+		for _, pol := range policies {
+			policyArgs := []reflect.Value{reflect.ValueOf(ctx.Context()), inputPtr}
+			for i := 2; i < pol.Type().NumIn(); i++ {
+				value, ok := resolve(pol.Type().In(i), i-2)
+				if !ok {
+					return
+				}
+				policyArgs = append(policyArgs, value)
+			}
+
+			if errValue := pol.Call(policyArgs)[0]; !errValue.IsNil() {
+				writePolicyError(api, ctx, errValue.Interface().(error))
+				return
+			}
+		}
+
+		// Run the input validators, reporting the problems of all inputs at once
+		if len(validators) > 0 {
+			v := validation.New(func(t reflect.Type) (reflect.Value, error) {
+				value, ok := resolve(t, -1)
+				if !ok {
+					return reflect.Value{}, validation.ErrAborted
+				}
+				return value, nil
+			})
+
+			var err error
+			for _, dep := range validators {
+				input := inputPtr
+				if dep != nil {
+					input = reflect.New(dep.InputFields)
+					if parseErr := requestParser.ParseInput(api, ctx, input, dep.InputFields); parseErr != nil {
+						writeParseError(api, ctx, "Failed to parse dependency input", parseErr)
+						return
+					}
+					depInputs[dep] = input
+				}
+				if err = validation.Run(ctx.Context(), v, input.Interface()); err != nil {
+					break
+				}
+			}
+
+			// Don't write error if response was already started, e.g. by a failed dependency
+			if ctx.Status() != 0 {
+				return
+			}
+			if err != nil {
+				var se huma.StatusError
+				if errors.As(err, &se) {
+					writeStatusError(api, ctx, se)
+				} else {
+					huma.WriteErr(api, ctx, http.StatusInternalServerError, "Validation error", err)
+				}
+				return
+			}
+			if v.HasErrors() {
+				writeStatusError(api, ctx, huma.Error422UnprocessableEntity("validation failed", v.Errors()...))
+				return
+			}
+		}
+
+		// Prepare handler arguments
+		handlerArgs := []reflect.Value{
+			reflect.ValueOf(ctx.Context()),
+			inputPtr,
+		}
+
+		// Resolve and inject dependencies
+		for i := 2; i < handlerType.NumIn(); i++ {
+			value, ok := resolve(handlerType.In(i), i-2)
+			if !ok {
+				return
+			}
+			handlerArgs = append(handlerArgs, value)
+		}
+
+		// Replay or reserve the Idempotency-Key before running the handler
+		if p.idempotency != nil {
+			next, finish, err := p.idempotency.Begin(ctx, idempotencyScope(ctx, p.idempotency.Options()), idempotencyFingerprint(ctx, inputPtr))
+			if err != nil {
+				writeIdempotencyError(api, ctx, err)
+				return
+			}
+			if next == nil {
+				return // Stored response was replayed
+			}
+			defer finish()
+			ctx = next
+		}
+
+		// Call the original handler
+		results := handlerValue.Call(handlerArgs)
 
 		// Handle the response
 		if len(results) != 2 {
@@ -575,12 +1454,17 @@ func (p *Procedure) Register(
 			if ctx.Status() == 0 {
 				// Handle different error types appropriately
 				var se huma.StatusError
-				if errors.As(err, &se) {
-					huma.WriteErr(api, ctx, se.GetStatus(), se.Error())
+				if limits.TimedOut(ctx.Context()) {
+					huma.WriteErr(api, ctx, http.StatusServiceUnavailable, "Request timed out", err)
+				} else if errors.As(err, &se) {
+					writeStatusError(api, ctx, se)
 				} else {
 					huma.WriteErr(api, ctx, http.StatusInternalServerError, "Handler error", err)
 				}
 			}
+			if status := errorStatus(err); status >= http.StatusInternalServerError {
+				reportRequestError(ctx, operation, status, err, false)
+			}
 			return
 		}
 
@@ -589,8 +1473,13 @@ func (p *Procedure) Register(
 		if output != nil {
 			// Don't write response if error was already written
 			if ctx.Status() == 0 {
+				// Link to the neighbouring pages of paginated responses
+				writePageLinks(ctx, output)
+
 				// Use the response writer
 				responseWriter := parsing.NewResponseWriter()
+				responseWriter.ETags = p.etags
+				responseWriter.VaryAccept = negotiable
 				if err := responseWriter.WriteOutput(api, ctx, output, outputType, operation); err != nil {
 					// Log the error but don't write response since headers might be sent
 					// In production, would use proper logging
@@ -613,6 +1502,285 @@ func (p *Procedure) Register(
 	}
 }
 
+// validatePolicies checks the signatures of custom policies and marks their dependencies as used
+func (p *Procedure) validatePolicies(operationID string, inputParamType reflect.Type, result *core.ValidationResult) []reflect.Value {
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+
+	policies := make([]reflect.Value, 0, len(p.policies))
+	for _, pol := range p.policies {
+		policyValue := reflect.ValueOf(pol)
+		policyType := policyValue.Type()
+
+		if policyType.Kind() != reflect.Func || policyType.NumIn() < 2 || policyType.NumOut() != 1 ||
+			policyType.In(0) != contextType || policyType.Out(0) != errorType {
+			panic(fmt.Sprintf("policy for operation '%s' must have signature func(context.Context, *InputType, ...deps) error, got %T", operationID, pol))
+		}
+
+		// The input parameter must accept the handler's input
+		if !inputParamType.AssignableTo(policyType.In(1)) {
+			panic(fmt.Sprintf("policy for operation '%s' expects input %v, but the handler's input is %v", operationID, policyType.In(1), inputParamType))
+		}
+
+		for i := 2; i < policyType.NumIn(); i++ {
+			paramType := policyType.In(i)
+			if _, ok := result.DepsByType[paramType]; ok {
+				continue
+			}
+
+			dep, ok := p.getRegistry().Get(paramType)
+			if !ok {
+				if !slices.Contains(result.MissingTypes, paramType) {
+					result.MissingTypes = append(result.MissingTypes, paramType)
+				}
+				continue
+			}
+
+			result.DepsByType[paramType] = dep
+			result.UnusedDeps = slices.DeleteFunc(result.UnusedDeps, func(d *core.DependencyCore) bool { return d == dep })
+		}
+
+		policies = append(policies, policyValue)
+	}
+
+	return policies
+}
+
+// inputValidators lists the inputs of an operation that implement InputValidator: nil stands for the
+// handler's input, followed by the dependencies whose input fields have a validator
+func (p *Procedure) inputValidators(inputType reflect.Type, result *core.ValidationResult) []*core.DependencyCore {
+	var validators []*core.DependencyCore
+	if validation.Implements(inputType) {
+		validators = append(validators, nil)
+	}
+
+	var deps []*core.DependencyCore
+	for _, dep := range result.DepsByType {
+		if dep.InputFields != nil && validation.Implements(dep.InputFields) {
+			deps = append(deps, dep)
+		}
+	}
+	// Run dependency validators in a stable order
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Name < deps[j].Name
+	})
+
+	return append(validators, deps...)
+}
+
+// annotateConditionalRequest documents the conditional headers a write operation accepts
+func annotateConditionalRequest(operation *huma.Operation, inputType reflect.Type) {
+	var headers []string
+	switch operation.Method {
+	case http.MethodGet, http.MethodHead:
+		headers = []string{"If-None-Match", "If-Modified-Since"}
+	case http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodPost:
+		headers = []string{"If-Match", "If-None-Match", "If-Unmodified-Since"}
+		if !slices.Contains(operation.Errors, http.StatusPreconditionFailed) {
+			operation.Errors = append(operation.Errors, http.StatusPreconditionFailed)
+		}
+	}
+
+	descriptions := map[string]string{
+		"If-Match":            "Only apply the change if the resource's current ETag matches",
+		"If-None-Match":       "ETags the client already has; * on writes only succeeds if the resource doesn't exist",
+		"If-Modified-Since":   "Only return the resource if it changed after this date",
+		"If-Unmodified-Since": "Only apply the change if the resource hasn't changed since this date",
+	}
+
+	for _, header := range headers {
+		// Input structs may declare the header themselves
+		declared := false
+		for i := 0; i < inputType.NumField(); i++ {
+			if strings.EqualFold(inputType.Field(i).Tag.Get("header"), header) {
+				declared = true
+				break
+			}
+		}
+		if declared {
+			continue
+		}
+
+		operation.Parameters = append(operation.Parameters, &huma.Param{
+			Name:        header,
+			In:          "header",
+			Description: descriptions[header],
+			Schema:      &huma.Schema{Type: "string"},
+		})
+	}
+}
+
+// annotateConditionalResponses documents the ETag and Last-Modified headers and the 304 response of reads
+func annotateConditionalResponses(operation *huma.Operation) {
+	if operation.Method != http.MethodGet && operation.Method != http.MethodHead {
+		return
+	}
+
+	for code, response := range operation.Responses {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = map[string]*huma.Param{}
+		}
+		if response.Headers["ETag"] == nil {
+			response.Headers["ETag"] = &huma.Param{Description: "Validator for conditional requests", Schema: &huma.Schema{Type: "string"}}
+		}
+	}
+
+	if operation.Responses[strconv.Itoa(http.StatusNotModified)] == nil {
+		operation.Responses[strconv.Itoa(http.StatusNotModified)] = &huma.Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+}
+
+// annotateIdempotency adds the idempotency key header and 409 response to an operation
+func annotateIdempotency(operation *huma.Operation, options IdempotencyOptions) {
+	operation.Parameters = append(operation.Parameters, &huma.Param{
+		Name:        options.Header,
+		In:          "header",
+		Required:    options.Required,
+		Description: "Unique key that makes retries of this request safe; the first response is replayed for identical requests",
+		Schema:      &huma.Schema{Type: "string", MaxLength: &[]int{255}[0]},
+	})
+	// 400 for missing or invalid keys, 409 for concurrent duplicates and 422 for reused keys,
+	// see writeIdempotencyError
+	for _, status := range []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity} {
+		if !slices.Contains(operation.Errors, status) {
+			operation.Errors = append(operation.Errors, status)
+		}
+	}
+}
+
+// idempotencyScope separates the idempotency keys of different clients: the principal when
+// authenticated, else the established session, else the address of the client. Anonymous clients
+// behind the same reverse proxy or NAT share the remote address, and with it a key space, unless
+// ClientHeader names a header the proxy sets to the client's address
+func idempotencyScope(ctx huma.Context, options IdempotencyOptions) string {
+	if principal, ok := PrincipalFromContext(ctx.Context()); ok {
+		return principal.Scheme + ":" + principal.Subject
+	}
+	if sess, ok := session.FromContext(ctx.Context()); ok && !sess.IsNew() {
+		return "session:" + sess.ID
+	}
+	if options.ClientHeader != "" {
+		// Proxies append the address they received the request from
+		if values := strings.Split(ctx.Header(options.ClientHeader), ","); strings.TrimSpace(values[len(values)-1]) != "" {
+			return "client:" + strings.TrimSpace(values[len(values)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(ctx.RemoteAddr())
+	if err != nil {
+		host = ctx.RemoteAddr()
+	}
+	return "remote:" + host
+}
+
+// idempotencyFingerprint identifies a request by method, path and parsed input
+func idempotencyFingerprint(ctx huma.Context, input reflect.Value) string {
+	payload, err := json.Marshal(input.Interface())
+	if err != nil {
+		payload = nil // Inputs that can't be encoded are compared by method and path only
+	}
+	return idempotency.Fingerprint([]byte(ctx.Method()), []byte(ctx.URL().Path), payload)
+}
+
+// writeIdempotencyError maps idempotency failures to responses
+func writeIdempotencyError(api huma.API, ctx huma.Context, err error) {
+	switch {
+	case errors.Is(err, idempotency.ErrMissingKey), errors.Is(err, idempotency.ErrInvalidKey):
+		huma.WriteErr(api, ctx, http.StatusBadRequest, "Invalid idempotency key", err)
+	case errors.Is(err, idempotency.ErrInProgress):
+		ctx.SetHeader("Retry-After", "1")
+		huma.WriteErr(api, ctx, http.StatusConflict, "Request is already being processed", err)
+	case errors.Is(err, idempotency.ErrKeyMismatch):
+		huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Idempotency key reused with a different payload", err)
+	default:
+		huma.WriteErr(api, ctx, http.StatusInternalServerError, "Idempotency check failed", err)
+	}
+}
+
+// writePolicyError writes an authorization failure, keeping the status of huma status errors
+func writePolicyError(api huma.API, ctx huma.Context, err error) {
+	if ctx.Status() != 0 {
+		return
+	}
+
+	// Preserve the details of huma error models
+	var model *huma.ErrorModel
+	if errors.As(err, &model) {
+		details := make([]error, 0, len(model.Errors))
+		for _, detail := range model.Errors {
+			if detail != nil {
+				details = append(details, detail)
+			}
+		}
+		huma.WriteErr(api, ctx, model.Status, model.Detail, details...)
+		return
+	}
+
+	var se huma.StatusError
+	if errors.As(err, &se) {
+		huma.WriteErr(api, ctx, se.GetStatus(), se.Error())
+		return
+	}
+	huma.WriteErr(api, ctx, http.StatusForbidden, "Forbidden", err)
+}
+
+// writeParseError writes a request parsing error: 413 and 408 for exceeded limits, 400 otherwise
+func writeParseError(api huma.API, ctx huma.Context, message string, err error) {
+	if status, ok := limits.Status(err); ok {
+		if status == http.StatusRequestEntityTooLarge {
+			message = "Request body too large"
+		} else {
+			message = "Request body not received in time"
+		}
+		huma.WriteErr(api, ctx, status, message, err)
+		return
+	}
+	huma.WriteErr(api, ctx, http.StatusBadRequest, message, err)
+}
+
+// writeStatusError writes an error that carries its status, keeping the details of huma errors
+// (e.g. the locations of invalid parameters)
+func writeStatusError(api huma.API, ctx huma.Context, se huma.StatusError) {
+	if coded, ok := se.(*CodedError); ok {
+		writeCodedError(api, ctx, coded)
+		return
+	}
+	model, ok := se.(*huma.ErrorModel)
+	if !ok {
+		huma.WriteErr(api, ctx, se.GetStatus(), se.Error())
+		return
+	}
+	details := make([]error, len(model.Errors))
+	for i, detail := range model.Errors {
+		details[i] = detail
+	}
+	huma.WriteErr(api, ctx, model.Status, model.Detail, details...)
+}
+
+// errorStatus is the HTTP status a handler error is answered with
+func errorStatus(err error) int {
+	var se huma.StatusError
+	if errors.As(err, &se) {
+		return se.GetStatus()
+	}
+	return http.StatusInternalServerError
+}
+
+// reportRequestError passes a server error of an operation to the error hooks
+func reportRequestError(ctx huma.Context, operation huma.Operation, status int, err error, panicked bool) {
+	hooks.ReportError(ctx.Context(), ErrorEvent{
+		Source: ErrorSourceRequest,
+		Name:   operation.OperationID,
+		Method: operation.Method,
+		Path:   operation.Path,
+		Status: status,
+		Panic:  panicked,
+		Err:    err,
+	})
+}
+
 // applyMiddlewaresAndSecurity applies middlewares and security to the operation
 func applyMiddlewaresAndSecurity(operation *huma.Operation, procedure *Procedure, api huma.API) {
 	// Create API injection middleware that runs FIRST
@@ -634,6 +1802,22 @@ func applyMiddlewaresAndSecurity(operation *huma.Operation, procedure *Procedure
 	if len(procedure.getSecurity()) > 0 && len(operation.Security) == 0 {
 		operation.Security = procedure.getSecurity()
 	}
+
+	// Register the security schemes the procedure relies on
+	if len(procedure.schemes) > 0 {
+		oapi := api.OpenAPI()
+		if oapi.Components == nil {
+			oapi.Components = &huma.Components{}
+		}
+		if oapi.Components.SecuritySchemes == nil {
+			oapi.Components.SecuritySchemes = map[string]*huma.SecurityScheme{}
+		}
+		for name, scheme := range procedure.schemes {
+			if _, exists := oapi.Components.SecuritySchemes[name]; !exists {
+				oapi.Components.SecuritySchemes[name] = scheme
+			}
+		}
+	}
 }
 
 // Register is the simple version without DI - just a clean wrapper around huma.Register
@@ -664,8 +1848,34 @@ func AuthenticatedProcedure(baseProcedure *Procedure, authMiddleware Middleware,
 	return baseProcedure.Use(authMiddleware).WithSecurity(security)
 }
 
+// JWTProcedure creates a procedure that requires a valid JWT bearer token
+// The verified token and principal are stored in the request context, and the
+// "bearer" security scheme is registered in the OpenAPI components automatically
+func JWTProcedure(baseProcedure *Procedure, verifier *JWTVerifier) *Procedure {
+	return AuthenticatedProcedure(baseProcedure, JWTMiddleware(verifier), map[string][]string{"bearer": {}}).
+		WithSecurityScheme("bearer", BearerSecurityScheme())
+}
+
+// APIKeyProcedure creates a procedure that requires a valid API key
+// The key's principal is stored in the context, per-key rate limits are enforced, and the
+// "apiKey" security scheme is registered in the OpenAPI components automatically
+func APIKeyProcedure(baseProcedure *Procedure, authenticator *APIKeyAuthenticator) *Procedure {
+	return AuthenticatedProcedure(baseProcedure, APIKeyMiddleware(authenticator), map[string][]string{"apiKey": {}}).
+		WithSecurityScheme("apiKey", APIKeySecurityScheme(authenticator))
+}
+
+// SessionProcedure creates a procedure that requires a logged-in cookie session
+// CSRF protection is applied, and the "session" cookie security scheme is registered automatically
+func SessionProcedure(baseProcedure *Procedure, manager *SessionManager) *Procedure {
+	return baseProcedure.
+		Use(SessionMiddleware(manager), CSRFMiddleware(manager), RequireSessionMiddleware).
+		WithSecurity(map[string][]string{"session": {}}).
+		WithSecurityScheme("session", SessionSecurityScheme(manager))
+}
+
 // AdminProcedure creates a procedure pre-configured with auth + admin role check
 // Takes an authenticated procedure and additional admin middleware
+// For declarative checks prefer authProcedure.RequireRoles("admin"), which is also documented in OpenAPI
 func AdminProcedure(authProcedure *Procedure, adminMiddleware Middleware) *Procedure {
 	return authProcedure.Use(adminMiddleware)
 }
@@ -681,7 +1891,12 @@ func (p *Procedure) convenience(api huma.API, method, path string, handler inter
 	outputType := handlerType.Out(0) // First return value (*OutputType)
 
 	// Auto-generate operation ID and summary like Huma does
-	opID := huma.GenerateOperationID(method, path, reflect.Zero(outputType).Interface())
+	// Versions are part of generated IDs, so the same path can be registered in several versions
+	idPath := path
+	if p.version != "" {
+		idPath = versioning.Path(p.version, path)
+	}
+	opID := huma.GenerateOperationID(method, idPath, reflect.Zero(outputType).Interface())
 	opSummary := huma.GenerateSummary(method, path, reflect.Zero(outputType).Interface())
 
 	operation := huma.Operation{
@@ -708,85 +1923,1826 @@ func (p *Procedure) convenience(api huma.API, method, path string, handler inter
 	p.Register(api, operation, handler)
 }
 
-// HTTP method convenience functions for procedures (Huma-style)
+// HTTP method convenience functions for procedures (Huma-style)
+
+// Get registers a GET endpoint with automatic operation ID/summary generation
+func (p *Procedure) Get(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodGet, path, handler, operationHandlers...)
+}
+
+// Post registers a POST endpoint with automatic operation ID/summary generation
+func (p *Procedure) Post(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodPost, path, handler, operationHandlers...)
+}
+
+// Put registers a PUT endpoint with automatic operation ID/summary generation
+func (p *Procedure) Put(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodPut, path, handler, operationHandlers...)
+}
+
+// Patch registers a PATCH endpoint with automatic operation ID/summary generation
+func (p *Procedure) Patch(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodPatch, path, handler, operationHandlers...)
+}
+
+// Delete registers a DELETE endpoint with automatic operation ID/summary generation
+func (p *Procedure) Delete(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodDelete, path, handler, operationHandlers...)
+}
+
+// Head registers a HEAD endpoint with automatic operation ID/summary generation
+func (p *Procedure) Head(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodHead, path, handler, operationHandlers...)
+}
+
+// Options registers an OPTIONS endpoint with automatic operation ID/summary generation
+func (p *Procedure) Options(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	p.convenience(api, http.MethodOptions, path, handler, operationHandlers...)
+}
+
+// SSE registers a GET endpoint that streams server-sent events. events maps event names to example
+// payloads, and the handler receives a sender that only accepts those payload types:
+//
+//	proc.SSE(api, "/chat/{room}/events", map[string]any{
+//		"message": ChatMessage{},
+//		"typing":  TypingEvent{},
+//	}, func(ctx context.Context, input *RoomInput, send *goflux.EventSender, db *sql.DB) error {
+//		for msg := range subscribe(ctx, input.Room, send.LastEventID()) {
+//			if err := send.Send(goflux.SSEMessage{ID: msg.ID, Data: msg}); err != nil {
+//				return err // Client went away
+//			}
+//		}
+//		return nil
+//	})
+//
+// Middleware, security, requirements, policies and dependencies work as for Register, and an error
+// returned before the first event becomes a regular error response. The stream ends when the handler
+// returns; its context is cancelled when the client disconnects
+func (p *Procedure) SSE(api huma.API, path string, events map[string]any, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+
+	// Validate handler signature
+	if handlerType.Kind() != reflect.Func {
+		panic(fmt.Sprintf("handler must be a function, got %T", handler))
+	}
+	if handlerType.NumIn() < 3 || handlerType.In(2) != reflect.TypeFor[*EventSender]() {
+		panic("SSE handler must have at least 3 parameters: (context.Context, *InputType, *goflux.EventSender)")
+	}
+	if handlerType.NumOut() != 1 || handlerType.Out(0) != reflect.TypeFor[error]() {
+		panic("SSE handler must return exactly one value: error")
+	}
+
+	eventSet, err := sse.NewEvents(events)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid SSE events for %s: %v", path, err))
+	}
+	options := p.sse
+
+	// Wrap the handler as a regular one returning a streaming response, so it goes through
+	// the same dependency injection pipeline. The sender takes the place of the third parameter
+	in := []reflect.Type{handlerType.In(0), handlerType.In(1)}
+	for i := 3; i < handlerType.NumIn(); i++ {
+		in = append(in, handlerType.In(i))
+	}
+	out := []reflect.Type{reflect.TypeFor[*huma.StreamResponse](), reflect.TypeFor[error]()}
+	streamHandler := reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+		stream := &huma.StreamResponse{Body: func(ctx huma.Context) {
+			send := sse.NewSender(ctx, eventSet, options)
+			defer send.Close()
+
+			handlerArgs := append([]reflect.Value{args[0], args[1], reflect.ValueOf(send)}, args[2:]...)
+			errValue := handlerValue.Call(handlerArgs)[0]
+			if errValue.IsNil() {
+				if !send.Started() {
+					ctx.SetStatus(http.StatusNoContent)
+				}
+				return
+			}
+
+			// Once events have been sent the error can only end the stream
+			if send.Started() {
+				return
+			}
+			err := errValue.Interface().(error)
+			var se huma.StatusError
+			if errors.As(err, &se) {
+				huma.WriteErr(api, ctx, se.GetStatus(), se.Error())
+			} else {
+				huma.WriteErr(api, ctx, http.StatusInternalServerError, "Handler error", err)
+			}
+		}}
+		return []reflect.Value{reflect.ValueOf(stream), reflect.Zero(reflect.TypeFor[error]())}
+	})
+
+	// Document the event stream before user operation handlers run
+	documentEvents := func(o *huma.Operation) {
+		markStreaming(o)
+		if o.Responses == nil {
+			o.Responses = map[string]*huma.Response{}
+		}
+		o.Responses["200"] = &huma.Response{
+			Description: "Server-sent event stream",
+			Content: map[string]*huma.MediaType{
+				sse.ContentType: {Schema: eventSet.Schema(api.OpenAPI().Components.Schemas)},
+			},
+		}
+	}
+
+	p.convenience(api, http.MethodGet, path, streamHandler.Interface(), append([]func(o *huma.Operation){documentEvents}, operationHandlers...)...)
+}
+
+// WebSocket registers a GET endpoint that upgrades to a WebSocket connection. The handler's third
+// parameter declares the message types: In for messages from the client and Out for messages to it,
+// both JSON validated against the schemas of their Go types:
+//
+//	proc.WebSocket(api, "/rooms/{room}/socket", func(ctx context.Context, input *RoomInput, conn *goflux.WebSocketConn[ChatCommand, ChatEvent], hub *Hub) error {
+//		for {
+//			cmd, err := conn.Receive(ctx)
+//			if errors.Is(err, goflux.ErrWebSocketClosed) {
+//				return nil
+//			}
+//			...
+//		}
+//	})
+//
+// The upgrade request goes through middleware, security, requirements and policies like any other
+// operation, so unauthorized clients are rejected before the handshake. Dependencies are resolved
+// once per connection and live as long as it does; the handler's context is cancelled when the
+// client disconnects. The message schemas are listed in the operation's x-goflux-websocket extension
+func (p *Procedure) WebSocket(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+
+	// Validate handler signature
+	if handlerType.Kind() != reflect.Func {
+		panic(fmt.Sprintf("handler must be a function, got %T", handler))
+	}
+	if handlerType.NumIn() < 3 || !ws.IsConn(handlerType.In(2)) {
+		panic("WebSocket handler must have at least 3 parameters: (context.Context, *InputType, *goflux.WebSocketConn[In, Out])")
+	}
+	if handlerType.NumOut() != 1 || handlerType.Out(0) != reflect.TypeFor[error]() {
+		panic("WebSocket handler must return exactly one value: error")
+	}
+
+	endpoint, err := ws.NewEndpoint(api.OpenAPI().Components.Schemas, handlerType.In(2), p.websocket)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid WebSocket handler for %s: %v", path, err))
+	}
+
+	// Wrap the handler as a regular one returning a streaming response, so the upgrade request goes
+	// through the same dependency injection pipeline. The connection takes the place of the third parameter
+	in := []reflect.Type{handlerType.In(0), handlerType.In(1)}
+	for i := 3; i < handlerType.NumIn(); i++ {
+		in = append(in, handlerType.In(i))
+	}
+	out := []reflect.Type{reflect.TypeFor[*huma.StreamResponse](), reflect.TypeFor[error]()}
+	upgradeHandler := reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+		stream := &huma.StreamResponse{Body: func(ctx huma.Context) {
+			session, err := endpoint.Accept(ctx)
+			if errors.Is(err, ws.ErrUnsupported) {
+				huma.WriteErr(api, ctx, http.StatusNotImplemented, "WebSockets are not supported by this router", err)
+				return
+			}
+			if err != nil {
+				return // The handshake failure has been answered
+			}
+
+			// The response now belongs to the connection, so panics close it instead
+			var handlerErr error
+			defer func() {
+				if r := recover(); r != nil {
+					handlerErr = fmt.Errorf("%v", r)
+				}
+				session.Finish(handlerErr)
+			}()
+
+			handlerArgs := append([]reflect.Value{reflect.ValueOf(session.Context()), args[1], session.Bind()}, args[2:]...)
+			if errValue := handlerValue.Call(handlerArgs)[0]; !errValue.IsNil() {
+				handlerErr = errValue.Interface().(error)
+			}
+		}}
+		return []reflect.Value{reflect.ValueOf(stream), reflect.Zero(reflect.TypeFor[error]())}
+	})
+
+	p.convenience(api, http.MethodGet, path, upgradeHandler.Interface(), append([]func(o *huma.Operation){markStreaming, endpoint.Annotate}, operationHandlers...)...)
+}
+
+// markStreaming exempts a long-lived operation from the handler timeout of its limits
+func markStreaming(o *huma.Operation) {
+	if o.Metadata == nil {
+		o.Metadata = map[string]any{}
+	}
+	o.Metadata[limits.StreamingMetadataKey] = true
+}
+
+// Batch registers a POST endpoint that executes several operations in one request. Each call names an
+// operation by ID and is routed through the API as its own request, inheriting the batch's headers, so
+// router middleware, the operation's procedure, dependency injection and validation all apply to it.
+// The procedure's own middleware runs once for the whole batch
+// Example: goflux.PublicProcedure().Batch(api, "/batch")
+func (p *Procedure) Batch(api huma.API, path string, operationHandlers ...func(o *huma.Operation)) {
+	dispatcher := batch.New(api, p.batch)
+	p.convenience(api, http.MethodPost, path, dispatcher.Handle, append([]func(o *huma.Operation){dispatcher.Annotate}, operationHandlers...)...)
+}
+
+// Top-level convenience functions (Huma-compatible API)
+
+// Get registers a GET endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Get(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Get(api, path, handler, operationHandlers...)
+}
+
+// Post registers a POST endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Post(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Post(api, path, handler, operationHandlers...)
+}
+
+// Put registers a PUT endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Put(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Put(api, path, handler, operationHandlers...)
+}
+
+// Patch registers a PATCH endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Patch(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Patch(api, path, handler, operationHandlers...)
+}
+
+// Delete registers a DELETE endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Delete(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Delete(api, path, handler, operationHandlers...)
+}
+
+// Head registers a HEAD endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Head(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Head(api, path, handler, operationHandlers...)
+}
+
+// Options registers an OPTIONS endpoint using a public procedure (no dependencies)
+// This matches Huma's API exactly for backward compatibility
+func Options(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Options(api, path, handler, operationHandlers...)
+}
+
+// SSE registers a server-sent event stream using a public procedure (no dependencies)
+func SSE(api huma.API, path string, events map[string]any, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().SSE(api, path, events, handler, operationHandlers...)
+}
+
+// WebSocket registers a WebSocket endpoint using a public procedure (no dependencies)
+func WebSocket(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().WebSocket(api, path, handler, operationHandlers...)
+}
+
+// Batch registers a batch endpoint using a public procedure (no dependencies)
+func Batch(api huma.API, path string, operationHandlers ...func(o *huma.Operation)) {
+	PublicProcedure().Batch(api, path, operationHandlers...)
+}
+
+// ============================================================================
+// BACKGROUND JOBS
+// ============================================================================
+
+// DefineJob creates a job type with a typed payload; kind identifies its jobs in the store
+// Example: var SendWelcomeEmail = goflux.DefineJob[WelcomeEmail]("send-welcome-email")
+func DefineJob[T any](kind string) JobType[T] {
+	return jobs.Define[T](kind)
+}
+
+// JobFromContext returns the job a worker is processing, e.g. to read its ID or attempt number
+func JobFromContext(ctx context.Context) (*Job, bool) {
+	return jobs.FromContext(ctx)
+}
+
+// Worker registers the handler of a job type with a queue. Like an operation handler it receives the
+// decoded payload followed by the procedure's dependencies, which are loaded for every attempt:
+//
+//	func(ctx context.Context, email *WelcomeEmail, mailer *Mailer) error
+//
+// Returning an error schedules a retry with backoff until the job runs out of attempts; wrap it with
+// PermanentJobError to fail right away. The procedure's middleware does not run for jobs, so
+// dependencies that read request input or need middleware cannot be used
+func (p *Procedure) Worker(queue *JobQueue, jobType JobDefinition, handler interface{}) {
+	location := core.FindUserCodeLocation()
+	kind := jobType.Kind()
+	payloadType := jobType.PayloadType()
+
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+
+	// Validate handler signature
+	if handlerType.Kind() != reflect.Func {
+		panic(fmt.Sprintf("worker must be a function, got %T", handler))
+	}
+	if handlerType.NumIn() < 2 || handlerType.In(0) != reflect.TypeFor[context.Context]() || handlerType.In(1) != reflect.PointerTo(payloadType) {
+		panic(fmt.Sprintf("worker for %q must have at least 2 parameters: (context.Context, *%s)", kind, payloadType))
+	}
+	if handlerType.NumOut() != 1 || handlerType.Out(0) != reflect.TypeFor[error]() {
+		panic(fmt.Sprintf("worker for %q must return exactly one value: error", kind))
+	}
+
+	validationResult, err := p.getRegistry().ValidateHandlerDependencies(handlerType)
+	if err != nil {
+		panic(fmt.Sprintf("Worker validation failed: %v", err))
+	}
+	operation := "job:" + kind
+	if len(validationResult.MissingTypes) > 0 {
+		FormatMissingDependenciesError(operation, location.File, location.Line, MissingDependencies{
+			MissingTypes:  validationResult.MissingTypes,
+			AvailableDeps: convertCoreDepsToPublic(validationResult.DepsByType),
+		})
+		panic(fmt.Sprintf("missing dependencies for worker '%s' - see error details above", kind))
+	}
+	if len(validationResult.UnusedDeps) > 0 {
+		FormatUnusedDependenciesWarning(operation, location.File, location.Line, convertCoreDepsListToPublic(validationResult.UnusedDeps))
+	}
+	for _, dep := range validationResult.DepsByType {
+		if dep.InputFields != nil {
+			panic(fmt.Sprintf("dependency '%s' reads request input and cannot be used by the worker for %q", dep.Name, kind))
+		}
+	}
+
+	err = queue.Handle(kind, func(ctx context.Context, payload json.RawMessage) error {
+		payloadPtr := reflect.New(payloadType)
+		if err := json.Unmarshal(payload, payloadPtr.Interface()); err != nil {
+			return jobs.Permanent(fmt.Errorf("job: invalid %q payload: %w", kind, err))
+		}
+
+		args := []reflect.Value{reflect.ValueOf(ctx), payloadPtr}
+		for i := 2; i < handlerType.NumIn(); i++ {
+			paramType := handlerType.In(i)
+			dep := validationResult.DepsByType[paramType]
+			value, err := dep.Load(ctx, payloadPtr.Interface())
+			if err != nil {
+				return fmt.Errorf("job: failed to resolve dependency '%s': %w", dep.Name, err)
+			}
+			if value == nil {
+				args = append(args, reflect.Zero(paramType))
+			} else {
+				args = append(args, reflect.ValueOf(value))
+			}
+		}
+
+		if errValue := handlerValue.Call(args)[0]; !errValue.IsNil() {
+			return errValue.Interface().(error)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+type jobListInput struct {
+	Status string `query:"status" enum:"pending,running,succeeded,failed,cancelled" doc:"Only jobs in this state"`
+	Kind   string `query:"kind" doc:"Only jobs of this kind"`
+	Limit  int    `query:"limit" default:"50" minimum:"1" maximum:"500" doc:"Number of jobs to return"`
+	Offset int    `query:"offset" minimum:"0" doc:"Number of jobs to skip"`
+}
+
+type jobListOutput struct {
+	Body []*Job
+}
+
+type jobInput struct {
+	ID string `path:"id" doc:"Job ID"`
+}
+
+type jobOutput struct {
+	Body *Job
+}
+
+// jobError maps store errors to HTTP errors
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return huma.Error404NotFound("Job not found")
+	case errors.Is(err, jobs.ErrInvalidState):
+		return huma.Error409Conflict("The job cannot be changed in its current state")
+	default:
+		return huma.Error500InternalServerError("Job store unavailable", err)
+	}
+}
+
+// RegisterJobAdmin registers operations to inspect and manage the jobs of a queue under prefix
+// (e.g. "/api/admin/jobs"). Register them on a procedure that only lets administrators through:
+//
+//	GET  {prefix}              lists jobs, filtered by status and kind
+//	GET  {prefix}/{id}         returns a job
+//	POST {prefix}/{id}/retry   runs a failed or cancelled job again
+//	POST {prefix}/{id}/cancel  cancels a pending job
+func (p *Procedure) RegisterJobAdmin(api huma.API, queue *JobQueue, prefix string) {
+	p.Register(api, huma.Operation{
+		OperationID: "list-jobs",
+		Method:      http.MethodGet,
+		Path:        prefix,
+		Summary:     "List background jobs",
+		Tags:        []string{"jobs"},
+	}, func(ctx context.Context, input *jobListInput) (*jobListOutput, error) {
+		list, err := queue.List(ctx, JobListOptions{
+			Status: JobStatus(input.Status),
+			Kind:   input.Kind,
+			Limit:  input.Limit,
+			Offset: input.Offset,
+		})
+		if err != nil {
+			return nil, jobError(err)
+		}
+		if list == nil {
+			list = []*Job{}
+		}
+		return &jobListOutput{Body: list}, nil
+	})
+
+	p.Register(api, huma.Operation{
+		OperationID: "get-job",
+		Method:      http.MethodGet,
+		Path:        prefix + "/{id}",
+		Summary:     "Get a background job",
+		Tags:        []string{"jobs"},
+	}, func(ctx context.Context, input *jobInput) (*jobOutput, error) {
+		job, err := queue.Get(ctx, input.ID)
+		if err != nil {
+			return nil, jobError(err)
+		}
+		return &jobOutput{Body: job}, nil
+	})
+
+	p.Register(api, huma.Operation{
+		OperationID: "retry-job",
+		Method:      http.MethodPost,
+		Path:        prefix + "/{id}/retry",
+		Summary:     "Retry a failed or cancelled background job",
+		Tags:        []string{"jobs"},
+	}, func(ctx context.Context, input *jobInput) (*jobOutput, error) {
+		job, err := queue.Retry(ctx, input.ID)
+		if err != nil {
+			return nil, jobError(err)
+		}
+		return &jobOutput{Body: job}, nil
+	})
+
+	p.Register(api, huma.Operation{
+		OperationID: "cancel-job",
+		Method:      http.MethodPost,
+		Path:        prefix + "/{id}/cancel",
+		Summary:     "Cancel a pending background job",
+		Tags:        []string{"jobs"},
+	}, func(ctx context.Context, input *jobInput) (*jobOutput, error) {
+		job, err := queue.Cancel(ctx, input.ID)
+		if err != nil {
+			return nil, jobError(err)
+		}
+		return &jobOutput{Body: job}, nil
+	})
+}
+
+// ============================================================================
+// SCHEDULED TASKS
+// ============================================================================
+
+// DefaultScheduler runs the tasks registered with Schedule. Start it with RunScheduler
+var DefaultScheduler = schedule.New(schedule.Options{OnError: reportTaskError})
+
+// ConfigureScheduler sets the options of DefaultScheduler, e.g. a leader locker for applications
+// running on several instances. Failures are reported to the error hooks in addition to OnError
+func ConfigureScheduler(options SchedulerOptions) {
+	onError := options.OnError
+	options.OnError = func(ctx context.Context, task *ScheduledTask, err error) {
+		reportTaskError(ctx, task, err)
+		if onError != nil {
+			onError(ctx, task, err)
+		}
+	}
+	DefaultScheduler.Configure(options)
+}
+
+// RunScheduler runs the scheduled tasks until ctx is cancelled, then waits for running tasks to finish
+func RunScheduler(ctx context.Context) error {
+	return DefaultScheduler.Run(ctx)
+}
+
+// ScheduledTasks describes the scheduled tasks and their latest runs
+func ScheduledTasks() []ScheduledTask {
+	return DefaultScheduler.Tasks()
+}
+
+// OnError adds a hook that is called when an operation fails with a 5xx status or panics, and when a
+// scheduled task fails. Hooks run synchronously, so slow work such as network calls should be handed off
+func OnError(hook ErrorHook) {
+	hooks.OnError(hook)
+}
+
+func reportTaskError(ctx context.Context, task *ScheduledTask, err error) {
+	hooks.ReportError(ctx, ErrorEvent{
+		Source: ErrorSourceTask,
+		Name:   task.Name,
+		Panic:  errors.Is(err, schedule.ErrPanicked),
+		Err:    err,
+	})
+}
+
+// Schedule runs handler on a schedule using a public procedure (no dependencies)
+// Example: goflux.Schedule("*/15 * * * *", func(ctx context.Context) error { ... })
+func Schedule(spec string, handler interface{}, options ...TaskOptions) {
+	PublicProcedure().Schedule(spec, handler, options...)
+}
+
+// Schedule registers a task with DefaultScheduler. spec is a five-field cron expression
+// ("30 3 * * mon-fri"), a macro ("@hourly") or an interval ("@every 5m"). The handler receives a
+// context followed by the procedure's dependencies, which are loaded for every run:
+//
+//	func(ctx context.Context, db *pgxpool.Pool) error
+//
+// A run is skipped while the previous one is still going. The procedure's middleware does not run
+// for tasks, so dependencies that read request input or need middleware cannot be used
+func (p *Procedure) Schedule(spec string, handler interface{}, options ...TaskOptions) {
+	location := core.FindUserCodeLocation()
+
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+
+	// Validate handler signature
+	if handlerType.Kind() != reflect.Func {
+		panic(fmt.Sprintf("scheduled task must be a function, got %T", handler))
+	}
+	if handlerType.NumIn() < 1 || handlerType.In(0) != reflect.TypeFor[context.Context]() {
+		panic("scheduled task must take context.Context as its first parameter")
+	}
+	if handlerType.NumOut() != 1 || handlerType.Out(0) != reflect.TypeFor[error]() {
+		panic("scheduled task must return exactly one value: error")
+	}
+
+	var taskOptions TaskOptions
+	if len(options) > 0 {
+		taskOptions = options[0]
+	}
+	if taskOptions.Name == "" {
+		taskOptions.Name = runtime.FuncForPC(handlerValue.Pointer()).Name()
+	}
+	name := taskOptions.Name
+
+	// Dependencies are validated like those of an operation handler, which start after the input
+	params := []reflect.Type{handlerType.In(0), reflect.TypeFor[*struct{}]()}
+	for i := 1; i < handlerType.NumIn(); i++ {
+		params = append(params, handlerType.In(i))
+	}
+	validationResult, err := p.getRegistry().ValidateHandlerDependencies(reflect.FuncOf(params, []reflect.Type{handlerType.Out(0)}, false))
+	if err != nil {
+		panic(fmt.Sprintf("Scheduled task validation failed: %v", err))
+	}
+	operation := "task:" + name
+	if len(validationResult.MissingTypes) > 0 {
+		FormatMissingDependenciesError(operation, location.File, location.Line, MissingDependencies{
+			MissingTypes:  validationResult.MissingTypes,
+			AvailableDeps: convertCoreDepsToPublic(validationResult.DepsByType),
+		})
+		panic(fmt.Sprintf("missing dependencies for scheduled task '%s' - see error details above", name))
+	}
+	if len(validationResult.UnusedDeps) > 0 {
+		FormatUnusedDependenciesWarning(operation, location.File, location.Line, convertCoreDepsListToPublic(validationResult.UnusedDeps))
+	}
+	for _, dep := range validationResult.DepsByType {
+		if dep.InputFields != nil {
+			panic(fmt.Sprintf("dependency '%s' reads request input and cannot be used by scheduled task %q", dep.Name, name))
+		}
+	}
+
+	err = DefaultScheduler.Add(spec, func(ctx context.Context) error {
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		for i := 1; i < handlerType.NumIn(); i++ {
+			paramType := handlerType.In(i)
+			dep := validationResult.DepsByType[paramType]
+			value, err := dep.Load(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("schedule: failed to resolve dependency '%s': %w", dep.Name, err)
+			}
+			if value == nil {
+				args = append(args, reflect.Zero(paramType))
+			} else {
+				args = append(args, reflect.ValueOf(value))
+			}
+		}
+
+		if errValue := handlerValue.Call(args)[0]; !errValue.IsNil() {
+			return errValue.Interface().(error)
+		}
+		return nil
+	}, taskOptions)
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+// RouteInfo describes a registered operation
+type RouteInfo struct {
+	Method      string   `json:"method" doc:"HTTP method"`
+	Path        string   `json:"path" doc:"Path template"`
+	OperationID string   `json:"operationId" doc:"Operation ID"`
+	Summary     string   `json:"summary,omitempty" doc:"Operation summary"`
+	Tags        []string `json:"tags,omitempty" doc:"Operation tags"`
+	Deprecated  bool     `json:"deprecated,omitempty" doc:"Whether the operation is deprecated"`
+}
+
+type routeListingOutput struct {
+	Body struct {
+		Routes []RouteInfo     `json:"routes" doc:"Registered operations, sorted by path and method"`
+		Tasks  []ScheduledTask `json:"tasks" doc:"Scheduled tasks, sorted by name"`
+	}
+}
+
+// Routes lists the operations registered with api, sorted by path and method
+func Routes(api huma.API) []RouteInfo {
+	routes := []RouteInfo{}
+	for _, item := range api.OpenAPI().Paths {
+		for _, op := range []*huma.Operation{item.Get, item.Put, item.Post, item.Delete, item.Options, item.Head, item.Patch, item.Trace} {
+			if op == nil {
+				continue
+			}
+			routes = append(routes, RouteInfo{
+				Method:      op.Method,
+				Path:        op.Path,
+				OperationID: op.OperationID,
+				Summary:     op.Summary,
+				Tags:        op.Tags,
+				Deprecated:  op.Deprecated,
+			})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// RegisterRouteListing registers an operation at path (e.g. "/api/admin/routes") that lists the
+// API's operations and the scheduled tasks with their next and latest runs. Register it on a
+// procedure that only lets administrators through
+func (p *Procedure) RegisterRouteListing(api huma.API, path string) {
+	p.Register(api, huma.Operation{
+		OperationID: "list-routes",
+		Method:      http.MethodGet,
+		Path:        path,
+		Summary:     "List routes and scheduled tasks",
+		Tags:        []string{"admin"},
+	}, func(ctx context.Context, input *struct{}) (*routeListingOutput, error) {
+		out := &routeListingOutput{}
+		out.Body.Routes = Routes(api)
+		out.Body.Tasks = ScheduledTasks()
+		return out, nil
+	})
+}
+
+// ============================================================================
+// SERVER LIFECYCLE
+// ============================================================================
+
+// ServeOptions configures Serve
+type ServeOptions struct {
+	// Addr is the address to listen on (default ":$PORT", or ":3000" without PORT)
+	Addr string
+	// Handler serves the requests (default: the API's router). Set it to wrap the router in
+	// net/http middleware
+	Handler http.Handler
+	// Server replaces the default *http.Server, e.g. gofluxfiber.Server(app) for Fiber
+	Server Server
+	// ShutdownTimeout is how long in-flight requests, scheduled tasks and teardown hooks get once
+	// shutdown begins (default 15 seconds, or the FLUX_SHUTDOWN_TIMEOUT environment variable)
+	ShutdownTimeout time.Duration
+	// DrainDelay keeps accepting requests for a while after readiness turns false, so load
+	// balancers stop routing traffic before the listener closes (default: none)
+	DrainDelay time.Duration
+	// Greet is printed once the server listens. Host and Port default to the listening address
+	Greet GreetOptions
+	// WarmUp dependencies, typically singletons such as connection pools, are loaded in order once
+	// the server listens. The readiness probe fails until they are all loaded; if one fails, Serve
+	// shuts down and returns its error
+	WarmUp []Dependency
+}
+
+// Serve starts the API's server and blocks until ctx is cancelled or the process receives SIGINT or
+// SIGTERM. The instance reports ready once the WarmUp dependencies are loaded. Shutdown turns
+// readiness off, drains in-flight requests, waits for running scheduled tasks and calls the
+// OnShutdown hooks, which close singletons, in reverse order. Scheduled tasks run while the server does
+func Serve(ctx context.Context, api huma.API, options ServeOptions) error {
+	addr := options.Addr
+	if addr == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		addr = ":" + port
+	}
+
+	server := options.Server
+	if server == nil {
+		handler := options.Handler
+		if handler == nil {
+			handler = api.Adapter()
+		}
+		server = &http.Server{Handler: handler}
+	}
+
+	var services []func(ctx context.Context) error
+	if len(DefaultScheduler.Tasks()) > 0 {
+		services = append(services, DefaultScheduler.Run)
+	}
+
+	var startup func(ctx context.Context) error
+	if len(options.WarmUp) > 0 {
+		startup = func(ctx context.Context) error {
+			for _, dep := range options.WarmUp {
+				if _, err := dep.core.Load(ctx, nil); err != nil {
+					return fmt.Errorf("failed to warm up dependency '%s': %w", dep.core.Name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return lifecycle.Serve(ctx, server, addr, lifecycle.Options{
+		ShutdownTimeout: options.ShutdownTimeout,
+		DrainDelay:      options.DrainDelay,
+		Startup:         startup,
+		Services:        services,
+		OnListen: func(listenAddr net.Addr) {
+			greet := options.Greet
+			if tcp, ok := listenAddr.(*net.TCPAddr); ok {
+				if greet.Host == "" {
+					greet.Host = "localhost"
+					if !tcp.IP.IsUnspecified() {
+						greet.Host = tcp.IP.String()
+					}
+				}
+				if greet.Port == 0 {
+					greet.Port = tcp.Port
+				}
+			}
+			Greet(api, greet)
+		},
+	})
+}
+
+// NewSingleton creates a dependency whose value is loaded on first use and shared from then on.
+// Values with a Close or Shutdown method are closed when Serve shuts down, after the requests have
+// drained and in the reverse order the singletons were loaded. A failed load is retried on next use
+// Example: var PoolDep = goflux.NewSingleton("pool", func(ctx context.Context, _ interface{}) (*pgxpool.Pool, error) { ... })
+func NewSingleton(name string, loadFn interface{}) Dependency {
+	dep := core.NewDependencyCore(name, loadFn)
+	load := dep.LoadFn
+
+	var mu sync.Mutex
+	var value interface{}
+	loaded := false
+	dep.LoadFn = func(ctx context.Context, input interface{}) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if loaded {
+			return value, nil
+		}
+
+		// The value outlives the request that happens to load it
+		v, err := load(context.WithoutCancel(ctx), nil)
+		if err != nil {
+			return nil, err
+		}
+		value, loaded = v, true
+		if closer := lifecycle.Closer(v); closer != nil {
+			lifecycle.OnShutdown(name, closer)
+		}
+		return v, nil
+	}
+	return Dependency{core: dep}
+}
+
+// ============================================================================
+// HEALTH CHECKS
+// ============================================================================
+
+// RegisterHealthCheck adds a named check to the readiness probe and the health report
+// Example: goflux.RegisterHealthCheck("smtp", mailer.Ping, goflux.HealthCheckOptions{Timeout: time.Second})
+func RegisterHealthCheck(name string, check func(ctx context.Context) error, options HealthCheckOptions) {
+	if err := health.Default.Register(name, check, options); err != nil {
+		panic(err.Error())
+	}
+}
+
+// WithHealthCheck registers a health check named after the dependency. The check receives the
+// dependency's value, so it suits singletons such as connection pools:
+//
+//	PoolDep = goflux.NewSingleton("postgres", loadPool).WithHealthCheck(func(ctx context.Context, pool *pgxpool.Pool) error {
+//		return pool.Ping(ctx)
+//	}, goflux.HealthCheckOptions{Critical: true})
+func (d Dependency) WithHealthCheck(check interface{}, options HealthCheckOptions) Dependency {
+	depType := d.core.Type()
+	checkValue := reflect.ValueOf(check)
+	checkType := checkValue.Type()
+	if checkType.Kind() != reflect.Func || checkType.NumIn() != 2 || checkType.In(0) != reflect.TypeFor[context.Context]() ||
+		checkType.In(1) != depType || checkType.NumOut() != 1 || checkType.Out(0) != reflect.TypeFor[error]() {
+		panic(fmt.Sprintf("health check of dependency '%s' must have signature func(context.Context, %s) error", d.core.Name, depType))
+	}
+	if d.core.InputFields != nil {
+		panic(fmt.Sprintf("dependency '%s' reads request input and cannot be health checked", d.core.Name))
+	}
+
+	dep := d.core
+	RegisterHealthCheck(dep.Name, func(ctx context.Context) error {
+		value, err := dep.Load(ctx, nil)
+		if err != nil {
+			return err
+		}
+		arg := reflect.Zero(depType)
+		if value != nil {
+			arg = reflect.ValueOf(value)
+		}
+		if errValue := checkValue.Call([]reflect.Value{reflect.ValueOf(ctx), arg})[0]; !errValue.IsNil() {
+			return errValue.Interface().(error)
+		}
+		return nil
+	}, options)
+	return d
+}
+
+type probeOutput struct {
+	Status int
+	Body   struct {
+		Status string `json:"status" enum:"pass,warn,fail" doc:"fail takes the instance out of rotation, warn reports a failing non-critical check"`
+	}
+}
+
+type healthReportOutput struct {
+	Status int
+	Body   HealthReport
+}
+
+// readinessReport runs every check and fails while the server is starting up or shutting down
+func readinessReport(ctx context.Context) HealthReport {
+	ready := lifecycle.Ready()
+	report := health.Default.Run(ctx, nil)
+	if !ready {
+		report.Status = health.StatusFail
+		report.Checks = append([]HealthCheckResult{{
+			Name:      "lifecycle",
+			Status:    health.StatusFail,
+			Critical:  true,
+			Error:     "the server is starting up or shutting down",
+			CheckedAt: time.Now(),
+		}}, report.Checks...)
+	}
+	return report
+}
+
+func probeStatus(report HealthReport) int {
+	if report.Status == health.StatusFail {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// AddHealthProbes registers the liveness and readiness probes under prefix (e.g. "/api"):
+//
+//	GET {prefix}/livez   fails only when a Liveness check fails; a failure gets the process restarted
+//	GET {prefix}/readyz  fails while the server starts up or shuts down and when a Critical check fails
+//
+// Both answer 200 or 503 with just the overall status; RegisterHealthReport exposes the details
+func AddHealthProbes(api huma.API, prefix string) {
+	huma.Register(api, huma.Operation{
+		OperationID: "livez",
+		Method:      http.MethodGet,
+		Path:        prefix + "/livez",
+		Summary:     "Liveness probe",
+		Description: "Reports whether the process is alive. Orchestrators restart the instance when it fails",
+		Tags:        []string{"Health"},
+		Responses:   map[string]*huma.Response{"503": {Description: "Service Unavailable"}},
+	}, func(ctx context.Context, input *struct{}) (*probeOutput, error) {
+		report := health.Default.Run(ctx, func(options HealthCheckOptions) bool {
+			return options.Liveness
+		})
+		out := &probeOutput{Status: probeStatus(report)}
+		out.Body.Status = report.Status
+		return out, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "readyz",
+		Method:      http.MethodGet,
+		Path:        prefix + "/readyz",
+		Summary:     "Readiness probe",
+		Description: "Reports whether the instance should receive traffic. Load balancers hold traffic back while it fails",
+		Tags:        []string{"Health"},
+		Responses:   map[string]*huma.Response{"503": {Description: "Service Unavailable"}},
+	}, func(ctx context.Context, input *struct{}) (*probeOutput, error) {
+		report := readinessReport(ctx)
+		out := &probeOutput{Status: probeStatus(report)}
+		out.Body.Status = report.Status
+		return out, nil
+	})
+}
+
+// RegisterHealthReport registers an operation at path (e.g. "/api/admin/health") that returns the result,
+// duration and error of every check. Register it on a procedure that only lets operators through, as
+// errors may reveal infrastructure details
+func (p *Procedure) RegisterHealthReport(api huma.API, path string) {
+	p.Register(api, huma.Operation{
+		OperationID: "health-report",
+		Method:      http.MethodGet,
+		Path:        path,
+		Summary:     "Detailed health report",
+		Tags:        []string{"Health"},
+		Responses:   map[string]*huma.Response{"503": {Description: "Service Unavailable"}},
+	}, func(ctx context.Context, input *struct{}) (*healthReportOutput, error) {
+		report := readinessReport(ctx)
+		return &healthReportOutput{Status: probeStatus(report), Body: report}, nil
+	})
+}
+
+// ============================================================================
+// CONFIGURATION
+// ============================================================================
+
+// LoadConfig fills a T from command-line flags, the environment, the .env files of the current
+// environment and `default` tags, in that order of precedence. Every missing or invalid variable is
+// reported in one *ConfigError. See the package documentation for the supported tags
+// Example: cfg, err := goflux.LoadConfig[Config](goflux.ConfigOptions{Args: os.Args[1:]})
+func LoadConfig[T any](options ...ConfigOptions) (*T, error) {
+	var opts ConfigOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return envconfig.Load[T](opts)
+}
+
+// MustLoadConfig is LoadConfig for main functions: it prints the problems to stderr and exits
+// with status 1 when the configuration is incomplete
+func MustLoadConfig[T any](options ...ConfigOptions) *T {
+	cfg, err := LoadConfig[T](options...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// FormatConfig lists the variables of a loaded config as NAME=value lines, with the values of
+// `secret:"true"` fields redacted, for logging the effective configuration at startup
+func FormatConfig(cfg any, options ...ConfigOptions) string {
+	var opts ConfigOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return envconfig.Format(cfg, opts)
+}
+
+// ConfigDependency makes a loaded config injectable, so handlers and other dependencies receive it
+// as a *T parameter instead of reading globals
+// Example: var ConfigDep = goflux.ConfigDependency(goflux.MustLoadConfig[Config]())
+func ConfigDependency[T any](cfg *T) Dependency {
+	return NewDependency("config", func(ctx context.Context, _ interface{}) (*T, error) {
+		return cfg, nil
+	})
+}
+
+// ============================================================================
+// FEATURE FLAGS
+// ============================================================================
+
+// DefineFlag defines a feature flag in code. A flag file loaded with LoadFlags or WatchFlags
+// replaces flags with the same key
+// Example: goflux.DefineFlag(goflux.Flag{Key: "new-checkout", Enabled: true, Tenants: []string{"acme"}})
+func DefineFlag(flag Flag) {
+	if err := flags.Default.Define(flag); err != nil {
+		panic(err.Error())
+	}
+}
+
+// LoadFlags loads the flags of a YAML or JSON file, replacing those of the previous load
+func LoadFlags(path string) error {
+	return flags.Default.LoadFile(path)
+}
+
+// WatchFlags loads a flag file and reloads it whenever it changes, until ctx is cancelled.
+// Reload errors are reported to the OnError hooks and leave the previous flags in effect
+// Example: if err := goflux.WatchFlags(ctx, "flags.yaml", 0); err != nil { ... }
+func WatchFlags(ctx context.Context, path string, interval time.Duration) error {
+	if err := flags.Default.LoadFile(path); err != nil {
+		return err
+	}
+	go flags.Default.Watch(ctx, path, interval, func(err error) {
+		hooks.ReportError(ctx, ErrorEvent{Source: ErrorSourceFlags, Name: path, Err: err})
+	})
+	return nil
+}
+
+// FlagEnabled reports whether a flag is on for the caller of ctx
+func FlagEnabled(ctx context.Context, key string) bool {
+	return flags.Default.Enabled(key, FlagTargetFromContext(ctx))
+}
+
+// FlagTargetFromContext returns who flags are evaluated for: the target set with WithFlagTarget or,
+// by default, the authenticated principal's subject and its "tenant", "tenant_id" or "org_id" claim
+func FlagTargetFromContext(ctx context.Context) FlagTarget {
+	if target, ok := flags.TargetFromContext(ctx); ok {
+		return target
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return FlagTarget{}
+	}
+	target := FlagTarget{User: principal.Subject}
+	for _, claim := range []string{"tenant", "tenant_id", "org_id"} {
+		if tenant, ok := principal.Claims[claim].(string); ok && tenant != "" {
+			target.Tenant = tenant
+			break
+		}
+	}
+	return target
+}
+
+// FlagsDependency injects a *FlagEvaluator bound to the caller of the request
+// Example: goflux.PublicProcedure(goflux.FlagsDependency).Get(api, "/checkout", func(ctx context.Context, input *struct{}, flags *goflux.FlagEvaluator) (*Output, error) { ... })
+var FlagsDependency = NewDependency("flags", func(ctx context.Context, _ interface{}) (*FlagEvaluator, error) {
+	return flags.NewEvaluator(flags.Default, FlagTargetFromContext(ctx)), nil
+})
+
+// annotateFlags documents the flags of an operation and lists the flag keys in the spec for the
+// generated client. Served documents leave the operation out while its flags are off, see OpenAPIDocument
+func annotateFlags(operation *huma.Operation, api huma.API, keys []string) {
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[flags.OperationExtension] = keys
+	addFlagsExtension(api)
+}
+
+func addFlagsExtension(api huma.API) {
+	oapi := api.OpenAPI()
+	if oapi.Extensions == nil {
+		oapi.Extensions = map[string]any{}
+	}
+	oapi.Extensions[flags.Extension] = flags.SpecExtension{Store: flags.Default}
+}
+
+// FlagStates holds the evaluated feature flags of a caller
+type FlagStates struct {
+	Flags map[string]bool `json:"flags" doc:"Whether each feature flag is on for the caller"`
+}
+
+type flagStatesOutput struct {
+	Body FlagStates
+}
+
+// RegisterFlagsEndpoint registers an operation at path (e.g. "/api/flags") that evaluates every flag
+// for the caller, so frontends can gate features. The flag keys are listed in the OpenAPI spec and
+// become the FeatureFlag type of the generated client
+func (p *Procedure) RegisterFlagsEndpoint(api huma.API, path string) {
+	addFlagsExtension(api)
+	p.Inject(FlagsDependency).Register(api, huma.Operation{
+		OperationID: "list-flags",
+		Method:      http.MethodGet,
+		Path:        path,
+		Summary:     "Evaluate feature flags for the caller",
+		Tags:        []string{"Flags"},
+	}, func(ctx context.Context, input *struct{}, evaluator *FlagEvaluator) (*flagStatesOutput, error) {
+		return &flagStatesOutput{Body: FlagStates{Flags: evaluator.All()}}, nil
+	})
+}
+
+// ============================================================================
+// PAGINATION
+// ============================================================================
+
+// OffsetPagination creates a dependency that reads the limit and offset query parameters and injects
+// an *OffsetPaginator. Build the response with NewOffsetPage; its links are sent in the Link header
+// Example: goflux.PublicProcedure(goflux.OffsetPagination(goflux.PaginationOptions{MaxLimit: 50}))
+func OffsetPagination(options ...PaginationOptions) Dependency {
+	var opts PaginationOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	dep := NewDependency("pagination", func(ctx context.Context, input interface{}) (*OffsetPaginator, error) {
+		params, _ := input.(*pagination.OffsetParams)
+		return pagination.NewOffset(params, opts), nil
+	}).WithInputFields(pagination.OffsetParams{})
+	spec := pagination.SpecFor(pagination.ModeOffset, opts)
+	annotateOperations(dep, func(operation *huma.Operation) {
+		pagination.Annotate(operation, spec)
+	})
+	return dep
+}
+
+// CursorPagination creates a dependency that reads the limit and cursor query parameters and injects
+// a *CursorPaginator. Cursors are opaque and signed with PaginationOptions.Secret, so clients cannot
+// forge positions; tampered cursors get 400 Bad Request
+// Example: goflux.PublicProcedure(goflux.CursorPagination(goflux.PaginationOptions{Secret: []byte(cfg.CursorSecret)}))
+func CursorPagination(options ...PaginationOptions) Dependency {
+	var opts PaginationOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	dep := NewDependency("pagination", func(ctx context.Context, input interface{}) (*CursorPaginator, error) {
+		params, _ := input.(*pagination.CursorParams)
+		return pagination.NewCursor(params, opts)
+	}).WithInputFields(pagination.CursorParams{})
+	spec := pagination.SpecFor(pagination.ModeCursor, opts)
+	annotateOperations(dep, func(operation *huma.Operation) {
+		pagination.Annotate(operation, spec)
+	})
+	return dep
+}
+
+// NewOffsetPage builds the page of an offset request from its items and the total number of items
+// Example: return &ListUsersOutput{Body: goflux.NewOffsetPage(page, users, total)}, nil
+func NewOffsetPage[T any](paginator *OffsetPaginator, items []T, total int) Page[T] {
+	return pagination.NewOffsetPage(paginator, items, total)
+}
+
+// NewCursorPage builds the page of a cursor request from its items and the position after the last
+// item, which is encoded in the next cursor. Pass nil as next on the last page
+// Example: page, err := goflux.NewCursorPage(paginator, users, nextPosition)
+func NewCursorPage[T any](paginator *CursorPaginator, items []T, next any) (Page[T], error) {
+	return pagination.NewCursorPage(paginator, items, next)
+}
+
+// writePageLinks sets the Link header of outputs whose body is a page
+func writePageLinks(ctx huma.Context, output interface{}) {
+	value := reflect.ValueOf(output)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	body := value.FieldByName("Body")
+	if !body.IsValid() || !body.CanInterface() {
+		return
+	}
+	linker, ok := body.Interface().(pagination.Linker)
+	if !ok {
+		return
+	}
+	requestURL := ctx.URL()
+	if links := linker.Links(&requestURL); len(links) > 0 {
+		ctx.SetHeader("Link", strings.Join(links, ", "))
+	}
+}
+
+// ============================================================================
+// FILTERING
+// ============================================================================
+
+// ListQuery is the parsed filters and sort order of a list request, for the whitelist struct T.
+// Where and OrderBy translate it to parameterized SQL for pgx
+type ListQuery[T any] struct {
+	filter.Query
+}
+
+type rawQueryKey struct{}
+
+// rawQueryMiddleware stores the query parameters of the request, which filter dependencies parse
+// beyond their declared input fields
+func rawQueryMiddleware(ctx huma.Context, next func(huma.Context)) {
+	requestURL := ctx.URL()
+	next(huma.WithValue(ctx, rawQueryKey{}, requestURL.Query()))
+}
+
+// Filtering creates a dependency that parses filter[field][operator]=value and sort=-field,field
+// query parameters against the whitelist struct T and injects a *ListQuery[T]. Unknown fields,
+// disallowed operators and malformed values are all reported in one 422 response. The allowed
+// filters are documented in the spec and typed in the generated client
+// Example:
+//
+//	type UserFilters struct {
+//		Name      string    `filter:"name" ops:"eq,contains" sort:"true"`
+//		CreatedAt time.Time `filter:"created_at" column:"u.created_at" sort:"true"`
+//	}
+//	var UserFiltering = goflux.Filtering[UserFilters](goflux.FilterOptions{DefaultSort: "-created_at"})
+func Filtering[T any](options ...FilterOptions) Dependency {
+	var opts FilterOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	schema, err := filter.Compile(reflect.TypeFor[T](), opts)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	dep := NewDependency("filtering", func(ctx context.Context, input interface{}) (*ListQuery[T], error) {
+		values, _ := ctx.Value(rawQueryKey{}).(url.Values)
+		query, err := schema.Parse(values)
+		if err != nil {
+			return nil, err
+		}
+		return &ListQuery[T]{Query: *query}, nil
+	}).WithInputFields(filter.SortParams{}).RequiresMiddleware(rawQueryMiddleware)
+	annotateOperations(dep, schema.Annotate)
+	return dep
+}
+
+// ============================================================================
+// ERROR CODES
+// ============================================================================
+
+// ErrorCode is an application error code whose errors carry details of type D. Define codes once
+// with DefineError and return their errors from handlers
+type ErrorCode[D any] struct {
+	definition *errcodes.Definition
+}
+
+// ErrorCodeDefinition is implemented by every ErrorCode, whatever its details
+type ErrorCodeDefinition interface {
+	errorDefinition() *errcodes.Definition
+}
+
+// NoDetails is the details type of error codes without details
+type NoDetails = struct{}
+
+// DefineError declares an application error code with its status and message template. {name}
+// placeholders of the message are filled from the JSON fields of the details. Codes are unique; an
+// invalid or duplicate code panics
+// Example:
+//
+//	var ErrUserNotFound = goflux.DefineError[UserNotFoundDetails]("user_not_found", http.StatusNotFound, "User {id} was not found")
+//	var ErrEmailTaken = goflux.DefineError[goflux.NoDetails]("email_taken", http.StatusConflict, "The email address is already registered")
+func DefineError[D any](code string, status int, message string) *ErrorCode[D] {
+	definition := &errcodes.Definition{Code: code, Status: status, Message: message}
+	if detailsType := reflect.TypeFor[D](); detailsType.Kind() != reflect.Struct || detailsType.NumField() > 0 {
+		definition.Details = detailsType
+	}
+	if err := errcodes.Default.Define(definition); err != nil {
+		panic(err.Error())
+	}
+	return &ErrorCode[D]{definition: definition}
+}
+
+// New creates an error of the code, to be returned from a handler or dependency
+// Example: return nil, ErrUserNotFound.New(UserNotFoundDetails{ID: input.ID})
+func (c *ErrorCode[D]) New(details D) *CodedError {
+	return c.definition.New(details)
+}
+
+// Code returns the error code
+func (c *ErrorCode[D]) Code() string {
+	return c.definition.Code
+}
+
+// Is reports whether err, or an error it wraps, is an error of the code
+func (c *ErrorCode[D]) Is(err error) bool {
+	var coded *CodedError
+	return errors.As(err, &coded) && coded.Code == c.definition.Code
+}
+
+func (c *ErrorCode[D]) errorDefinition() *errcodes.Definition {
+	return c.definition
+}
+
+// writeCodedError writes an error with an application code as a problem document with its code and
+// details
+func writeCodedError(api huma.API, ctx huma.Context, coded *CodedError) {
+	ct, err := api.Negotiate(ctx.Header("Accept"))
+	if err != nil {
+		ct = "application/json"
+	}
+	ct = coded.ContentType(ct)
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetStatus(coded.Status)
+
+	body, err := api.Transform(ctx, strconv.Itoa(coded.Status), coded)
+	if err != nil {
+		body = coded
+	}
+	if err := api.Marshal(ctx.BodyWriter(), ct, body); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write error: %v\n", err)
+	}
+}
+
+// ============================================================================
+// VALIDATION
+// ============================================================================
+
+// Inject returns the dependency of type T to an input validator. Dependencies are loaded once per
+// request, so the handler receives the same instance; T must be a dependency of the procedure
+func Inject[T any](v *Validation) (T, error) {
+	var zero T
+	value, err := v.Resolve(reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	return value.Interface().(T), nil
+}
+
+// ============================================================================
+// REQUEST LIMITS
+// ============================================================================
+
+// OperationLimits overrides the procedure's limits for one operation, see Procedure.WithLimits.
+// Positive values replace a limit, negative values remove it and zero keeps the procedure's
+// Example:
+//
+//	procedure.Post(api, "/api/avatars", uploadAvatar, goflux.OperationLimits(10<<20, time.Minute))
+func OperationLimits(maxBody int64, timeout time.Duration) func(*huma.Operation) {
+	return func(o *huma.Operation) {
+		o.MaxBodyBytes = maxBody
+		if timeout != 0 {
+			if o.Metadata == nil {
+				o.Metadata = map[string]any{}
+			}
+			o.Metadata[limits.TimeoutMetadataKey] = timeout
+		}
+	}
+}
+
+// ============================================================================
+// API VERSIONING
+// ============================================================================
+
+// deprecationMetadataKey is the operation metadata key of a per-operation deprecation
+const deprecationMetadataKey = "goflux.deprecation"
+
+// OperationDeprecation marks one operation as deprecated, overriding the procedure's deprecation
+// Example: v1.Get(api, "/api/users", listUsers, goflux.OperationDeprecation(goflux.Deprecation{Sunset: sunsetAt}))
+func OperationDeprecation(deprecation Deprecation) func(*huma.Operation) {
+	return func(o *huma.Operation) {
+		if o.Metadata == nil {
+			o.Metadata = map[string]any{}
+		}
+		o.Metadata[deprecationMetadataKey] = &deprecation
+	}
+}
+
+// VersionDocument returns the OpenAPI document of an API version: its operations and the
+// unversioned ones, without operations behind disabled flags
+func VersionDocument(api huma.API, version string) *huma.OpenAPI {
+	return versioning.Document(OpenAPIDocument(api), version)
+}
+
+// APIVersions returns the versions operations were registered with
+func APIVersions(api huma.API) []string {
+	return versioning.Versions(api.OpenAPI())
+}
+
+// servedVersionDocuments remembers the versions whose documents are served, per API
+var servedVersionDocuments sync.Map
+
+// serveVersionDocument serves the OpenAPI document of a version at /<version>/openapi.json and
+// /<version>/openapi.yaml, next to huma's full document. Documents are built when requested, so
+// they include operations registered later
+func serveVersionDocument(api huma.API, version string) {
+	type key struct {
+		api     huma.API
+		version string
+	}
+	if _, served := servedVersionDocuments.LoadOrStore(key{api, version}, true); served {
+		return
+	}
+	serveDocument(api, "/"+version+"/openapi", func() *huma.OpenAPI {
+		return VersionDocument(api, version)
+	})
+}
+
+// OpenAPIDocument returns the OpenAPI document of an API as it is served: operations behind a
+// feature flag that is off for everyone are left out, so flags loaded or reloaded after registration
+// show and hide them
+func OpenAPIDocument(api huma.API) *huma.OpenAPI {
+	return flags.Document(api.OpenAPI(), flags.Default)
+}
+
+// ServeOpenAPI serves the API's OpenAPI document at <path>.json and <path>.yaml and, when docsPath
+// is set, a documentation page reading it. Unlike huma's OpenAPIPath and DocsPath, which keep the
+// document of the first request, documents are built when requested so feature flag reloads apply.
+// Leave huma's paths empty when using it
+// Example: goflux.ServeOpenAPI(api, "/api/openapi", "/api/docs")
+func ServeOpenAPI(api huma.API, path, docsPath string) {
+	serveDocument(api, path, func() *huma.OpenAPI {
+		return OpenAPIDocument(api)
+	})
+	if docsPath == "" {
+		return
+	}
+
+	api.Adapter().Handle(&huma.Operation{Method: http.MethodGet, Path: docsPath}, func(ctx huma.Context) {
+		oapi := api.OpenAPI()
+		specPath := path
+		for _, server := range oapi.Servers {
+			if u, err := url.Parse(server.URL); err == nil && u.Path != "" {
+				specPath = gopath.Join(u.Path, path)
+				break
+			}
+		}
+		title := "API Reference"
+		if oapi.Info != nil && oapi.Info.Title != "" {
+			title = oapi.Info.Title + " Reference"
+		}
+		ctx.SetHeader("Content-Type", "text/html")
+		_ = docsTemplate.Execute(ctx.BodyWriter(), map[string]string{"Title": title, "SpecURL": specPath + ".yaml"})
+	})
+}
+
+// docsTemplate renders the documentation page with Stoplight Elements, like huma's DocsPath
+var docsTemplate = template.Must(template.New("docs").Parse(`<!doctype html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8" />
+    <meta name="referrer" content="same-origin" />
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no" />
+    <title>{{.Title}}</title>
+    <link href="https://unpkg.com/@stoplight/elements@9.0.0/styles.min.css" rel="stylesheet" />
+    <script src="https://unpkg.com/@stoplight/elements@9.0.0/web-components.min.js" integrity="sha256-Tqvw1qE2abI+G6dPQBc5zbeHqfVwGoamETU3/TSpUw4="
+            crossorigin="anonymous"></script>
+  </head>
+  <body style="height: 100vh;">
+    <elements-api apiDescriptionUrl="{{.SpecURL}}" router="hash" layout="sidebar" tryItCredentialsPolicy="same-origin" />
+  </body>
+</html>`))
+
+// serveDocument serves the document built by build at <path>.json and <path>.yaml
+func serveDocument(api huma.API, path string, build func() *huma.OpenAPI) {
+	adapter := api.Adapter()
+	adapter.Handle(&huma.Operation{Method: http.MethodGet, Path: path + ".json"}, func(ctx huma.Context) {
+		spec, err := build().MarshalJSON()
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to generate OpenAPI document", err)
+			return
+		}
+		ctx.SetHeader("Content-Type", "application/vnd.oai.openapi+json")
+		ctx.BodyWriter().Write(spec)
+	})
+	adapter.Handle(&huma.Operation{Method: http.MethodGet, Path: path + ".yaml"}, func(ctx huma.Context) {
+		spec, err := build().YAML()
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Failed to generate OpenAPI document", err)
+			return
+		}
+		ctx.SetHeader("Content-Type", "application/vnd.oai.openapi+yaml")
+		ctx.BodyWriter().Write(spec)
+	})
+}
+
+// ============================================================================
+// JWT AUTHENTICATION
+// ============================================================================
+
+// BearerSecurityScheme returns the OpenAPI security scheme for JWT bearer tokens
+func BearerSecurityScheme() *huma.SecurityScheme {
+	return &huma.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+}
+
+// JWTMiddleware verifies the Authorization bearer token and stores the token and principal in the context
+// Requests without a valid token are rejected with 401 Unauthorized
+func JWTMiddleware(verifier *JWTVerifier) Middleware {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		token, err := verifier.VerifyRequest(ctx.Context(), ctx.Header("Authorization"))
+		if err != nil {
+			if errors.Is(err, jwt.ErrMissingToken) {
+				ctx.SetHeader("WWW-Authenticate", `Bearer`)
+			} else {
+				ctx.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			WriteErr(ctx, http.StatusUnauthorized, "Authentication required", err)
+			return
+		}
+
+		requestCtx := jwt.WithToken(ctx.Context(), token)
+		requestCtx = auth.WithPrincipal(requestCtx, token.Principal())
+		next(huma.WithContext(ctx, requestCtx))
+	}
+}
+
+// JWTClaimsDependency creates a dependency that injects the verified token's claims decoded into *T
+// The dependency requires the JWT middleware, so injecting it is enough to protect an endpoint:
+//
+//	type Claims struct {
+//		Subject string   `json:"sub"`
+//		Roles   []string `json:"roles"`
+//	}
+//
+//	claimsDep := goflux.JWTClaimsDependency[Claims](verifier)
+//	goflux.PublicProcedure(claimsDep).Get(api, "/me", func(ctx context.Context, input *struct{}, claims *Claims) (*MeOutput, error) { ... })
+func JWTClaimsDependency[T any](verifier *JWTVerifier) Dependency {
+	return NewDependency("jwtClaims", func(ctx context.Context, input interface{}) (*T, error) {
+		token, ok := jwt.TokenFromContext(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized("Authentication required")
+		}
+
+		claims := new(T)
+		if err := token.Decode(claims); err != nil {
+			return nil, fmt.Errorf("failed to decode token claims: %w", err)
+		}
+		return claims, nil
+	}).RequiresMiddleware(JWTMiddleware(verifier))
+}
+
+// ============================================================================
+// API KEYS
+// ============================================================================
+
+// APIKeySecurityScheme returns the OpenAPI security scheme for the authenticator's key location
+func APIKeySecurityScheme(authenticator *APIKeyAuthenticator) *huma.SecurityScheme {
+	options := authenticator.Options()
+	return &huma.SecurityScheme{
+		Type: "apiKey",
+		In:   options.In,
+		Name: options.Name,
+	}
+}
+
+// APIKeyMiddleware authenticates requests by API key and applies the key's rate limit tier
+// Missing, unknown, expired and revoked keys get 401 Unauthorized, exhausted rate limits get
+// 429 Too Many Requests. Store failures get 500 and are passed to the error hooks, not the client
+func APIKeyMiddleware(authenticator *APIKeyAuthenticator) Middleware {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		raw := authenticator.Extract(ctx.Header, ctx.Query)
+		key, err := authenticator.Authenticate(ctx.Context(), raw)
+		if err != nil {
+			if rejection := apiKeyRejection(err); rejection != nil {
+				WriteErr(ctx, http.StatusUnauthorized, "Invalid or missing API key", rejection)
+				return
+			}
+			if operation := ctx.Operation(); operation != nil {
+				reportRequestError(ctx, *operation, http.StatusInternalServerError, err, false)
+			}
+			WriteErr(ctx, http.StatusInternalServerError, "Failed to authenticate API key")
+			return
+		}
+
+		limit := authenticator.Allow(key)
+		if limit.Limited {
+			ctx.SetHeader("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+			ctx.SetHeader("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+			ctx.SetHeader("X-RateLimit-Reset", strconv.FormatInt(limit.Reset.Unix(), 10))
+		}
+		if !limit.Allowed {
+			retryAfter := max(int(time.Until(limit.Reset).Seconds()+0.5), 1)
+			ctx.SetHeader("Retry-After", strconv.Itoa(retryAfter))
+			WriteErr(ctx, http.StatusTooManyRequests, "Rate limit exceeded for API key tier "+strconv.Quote(key.Tier))
+			return
+		}
+
+		requestCtx := apikey.WithKey(ctx.Context(), key)
+		requestCtx = auth.WithPrincipal(requestCtx, key.Principal())
+		next(huma.WithContext(ctx, requestCtx))
+	}
+}
+
+// apiKeyRejection returns the sentinel of an error that rejects the caller's key, or nil for
+// other errors, e.g. an unreachable key store
+func apiKeyRejection(err error) error {
+	for _, sentinel := range []error{apikey.ErrMissingKey, apikey.ErrInvalidKey, apikey.ErrExpiredKey, apikey.ErrRevokedKey} {
+		if errors.Is(err, sentinel) {
+			return sentinel
+		}
+	}
+	return nil
+}
+
+// APIKeyDependency creates a dependency that injects the authenticated *APIKey record
+// The dependency requires the API key middleware, so injecting it is enough to protect an endpoint
+func APIKeyDependency(authenticator *APIKeyAuthenticator) Dependency {
+	return NewDependency("apiKey", func(ctx context.Context, input interface{}) (*APIKey, error) {
+		key, ok := apikey.KeyFromContext(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized("API key required")
+		}
+		return key, nil
+	}).RequiresMiddleware(APIKeyMiddleware(authenticator))
+}
+
+// PrincipalDependency creates a dependency that injects the authenticated *Principal,
+// whichever authentication middleware (JWT, session, API key) produced it
+func PrincipalDependency() Dependency {
+	return NewDependency("principal", func(ctx context.Context, input interface{}) (*Principal, error) {
+		principal, ok := PrincipalFromContext(ctx)
+		if !ok {
+			return nil, huma.Error401Unauthorized("Authentication required")
+		}
+		return principal, nil
+	})
+}
+
+// ============================================================================
+// COOKIE SESSIONS
+// ============================================================================
+
+// SessionSecurityScheme returns the OpenAPI security scheme for the session cookie
+func SessionSecurityScheme(manager *SessionManager) *huma.SecurityScheme {
+	return &huma.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        manager.Options().CookieName,
+		Description: "Cookie session. Unsafe requests must send the CSRF cookie value in the " + manager.Options().CSRFHeaderName + " header",
+		Extensions:  map[string]any{session.CSRFExtension: manager.CSRFNames()},
+	}
+}
+
+// SessionMiddleware loads the caller's session and stores it in the context
+// Changes made during the request are saved before the response is written
+func SessionMiddleware(manager *SessionManager) Middleware {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		// Already loaded by another middleware in the chain
+		if _, ok := session.FromContext(ctx.Context()); ok {
+			next(ctx)
+			return
+		}
 
-// Get registers a GET endpoint with automatic operation ID/summary generation
-func (p *Procedure) Get(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodGet, path, handler, operationHandlers...)
+		sess := manager.Load(ctx.Context(), ctx.Header("Cookie"))
+		ctx, finish := manager.Attach(ctx, sess)
+		next(ctx)
+		finish()
+	}
 }
 
-// Post registers a POST endpoint with automatic operation ID/summary generation
-func (p *Procedure) Post(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodPost, path, handler, operationHandlers...)
-}
+// CSRFMiddleware implements double-submit cookie CSRF protection
+// A token cookie bound to the caller's session is issued to every client, and unsafe requests
+// must echo it in the CSRF header (X-CSRF-Token by default). Rejected requests receive a fresh
+// token, so clients without one can retry
+func CSRFMiddleware(manager *SessionManager) Middleware {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		sess, ok := session.FromContext(ctx.Context())
+		if !ok {
+			sess = manager.Load(ctx.Context(), ctx.Header("Cookie"))
+		}
 
-// Put registers a PUT endpoint with automatic operation ID/summary generation
-func (p *Procedure) Put(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodPut, path, handler, operationHandlers...)
+		result := manager.CheckCSRF(ctx.Method(), ctx.Header("Cookie"), ctx.Header(manager.Options().CSRFHeaderName), sess)
+		if result.SetCookie != "" {
+			ctx.AppendHeader("Set-Cookie", result.SetCookie)
+		}
+		if !result.Allowed {
+			WriteErr(ctx, http.StatusForbidden, "Invalid or missing CSRF token")
+			return
+		}
+		next(ctx)
+	}
 }
 
-// Patch registers a PATCH endpoint with automatic operation ID/summary generation
-func (p *Procedure) Patch(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodPatch, path, handler, operationHandlers...)
+// RequireSessionMiddleware rejects requests whose session has no logged-in subject
+// Must run after SessionMiddleware
+func RequireSessionMiddleware(ctx huma.Context, next func(huma.Context)) {
+	sess, ok := session.FromContext(ctx.Context())
+	if !ok || !sess.IsAuthenticated() {
+		WriteErr(ctx, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	next(ctx)
 }
 
-// Delete registers a DELETE endpoint with automatic operation ID/summary generation
-func (p *Procedure) Delete(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodDelete, path, handler, operationHandlers...)
+// SessionDependency creates a dependency that injects the caller's *Session
+// Use it for login and logout handlers:
+//
+//	sessionDep := goflux.SessionDependency(manager)
+//	goflux.PublicProcedure(sessionDep).Post(api, "/login", func(ctx context.Context, input *LoginInput, sess *goflux.Session) (*LoginOutput, error) {
+//		user, err := checkPassword(input.Body.Email, input.Body.Password)
+//		if err != nil {
+//			return nil, huma.Error401Unauthorized("Invalid credentials")
+//		}
+//		sess.Login(user.ID)
+//		return &LoginOutput{}, nil
+//	})
+func SessionDependency(manager *SessionManager) Dependency {
+	return NewDependency("session", func(ctx context.Context, input interface{}) (*Session, error) {
+		sess, ok := session.FromContext(ctx)
+		if !ok {
+			return nil, fmt.Errorf("session middleware did not run")
+		}
+		return sess, nil
+	}).RequiresMiddleware(SessionMiddleware(manager), CSRFMiddleware(manager))
 }
 
-// Head registers a HEAD endpoint with automatic operation ID/summary generation
-func (p *Procedure) Head(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodHead, path, handler, operationHandlers...)
-}
+// ============================================================================
+// OPENID CONNECT
+// ============================================================================
 
-// Options registers an OPTIONS endpoint with automatic operation ID/summary generation
-func (p *Procedure) Options(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	p.convenience(api, http.MethodOptions, path, handler, operationHandlers...)
+// OIDCSecurityScheme returns the OpenAPI openIdConnect security scheme for the relying party's provider
+func OIDCSecurityScheme(rp *OIDCRelyingParty) *huma.SecurityScheme {
+	return &huma.SecurityScheme{
+		Type:             "openIdConnect",
+		OpenIDConnectURL: rp.DiscoveryURL(),
+	}
 }
 
-// Top-level convenience functions (Huma-compatible API)
+// OIDCMiddleware authenticates requests with the session created by the OIDC login flow,
+// or with an ID token issued to this client sent as an Authorization bearer token
+// Must run after SessionMiddleware
+func OIDCMiddleware(rp *OIDCRelyingParty) Middleware {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		if ctx.Header("Authorization") == "" {
+			RequireSessionMiddleware(ctx, next)
+			return
+		}
 
-// Get registers a GET endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Get(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Get(api, path, handler, operationHandlers...)
+		verifier, err := rp.Verifier(ctx.Context())
+		if err != nil {
+			WriteErr(ctx, http.StatusServiceUnavailable, "Identity provider unavailable", err)
+			return
+		}
+		JWTMiddleware(verifier)(ctx, next)
+	}
 }
 
-// Post registers a POST endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Post(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Post(api, path, handler, operationHandlers...)
+// OIDCProcedure creates a procedure that requires a user signed in through OpenID Connect
+// Sessions are loaded with CSRF protection, and the "oidc" openIdConnect security scheme
+// is registered in the OpenAPI components automatically
+func OIDCProcedure(baseProcedure *Procedure, rp *OIDCRelyingParty, manager *SessionManager) *Procedure {
+	sessionProcedure := baseProcedure.Use(SessionMiddleware(manager), CSRFMiddleware(manager))
+	scheme := OIDCSecurityScheme(rp)
+	scheme.Extensions = map[string]any{session.CSRFExtension: manager.CSRFNames()}
+	return AuthenticatedProcedure(sessionProcedure, OIDCMiddleware(rp), map[string][]string{"oidc": {}}).
+		WithSecurityScheme("oidc", scheme)
 }
 
-// Put registers a PUT endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Put(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Put(api, path, handler, operationHandlers...)
+type oidcLoginInput struct {
+	ReturnTo string `query:"return_to" doc:"Local path to redirect to after login"`
 }
 
-// Patch registers a PATCH endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Patch(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Patch(api, path, handler, operationHandlers...)
+type oidcCallbackInput struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
 }
 
-// Delete registers a DELETE endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Delete(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Delete(api, path, handler, operationHandlers...)
+type oidcRedirectOutput struct {
+	Status   int
+	Location string `header:"Location"`
 }
 
-// Head registers a HEAD endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Head(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Head(api, path, handler, operationHandlers...)
-}
+// RegisterOIDCRoutes registers the login flow endpoints under prefix (e.g. "/api/auth"):
+//
+//	GET  {prefix}/login?return_to=/dashboard  redirects to the provider
+//	GET  {prefix}/callback                    completes the login and redirects to return_to
+//	POST {prefix}/logout                      destroys the session
+//
+// The redirect endpoints are hidden from the OpenAPI spec since browsers navigate to them directly.
+// The relying party's RedirectURL must point at {prefix}/callback
+func RegisterOIDCRoutes(api huma.API, rp *OIDCRelyingParty, manager *SessionManager, prefix string) {
+	procedure := PublicProcedure(SessionDependency(manager))
+
+	procedure.Register(api, huma.Operation{
+		OperationID: "oidc-login",
+		Method:      http.MethodGet,
+		Path:        prefix + "/login",
+		Summary:     "Start OpenID Connect login",
+		Tags:        []string{"auth"},
+		Hidden:      true,
+	}, func(ctx context.Context, input *oidcLoginInput, sess *Session) (*oidcRedirectOutput, error) {
+		location, err := rp.BeginLogin(ctx, sess, input.ReturnTo)
+		if err != nil {
+			return nil, huma.Error502BadGateway("Identity provider unavailable", err)
+		}
+		return &oidcRedirectOutput{Status: http.StatusFound, Location: location}, nil
+	})
 
-// Options registers an OPTIONS endpoint using a public procedure (no dependencies)
-// This matches Huma's API exactly for backward compatibility
-func Options(api huma.API, path string, handler interface{}, operationHandlers ...func(o *huma.Operation)) {
-	PublicProcedure().Options(api, path, handler, operationHandlers...)
+	procedure.Register(api, huma.Operation{
+		OperationID: "oidc-callback",
+		Method:      http.MethodGet,
+		Path:        prefix + "/callback",
+		Summary:     "Complete OpenID Connect login",
+		Tags:        []string{"auth"},
+		Hidden:      true,
+	}, func(ctx context.Context, input *oidcCallbackInput, sess *Session) (*oidcRedirectOutput, error) {
+		result, err := rp.CompleteLogin(ctx, sess, url.Values{
+			"code":              {input.Code},
+			"state":             {input.State},
+			"error":             {input.Error},
+			"error_description": {input.ErrorDescription},
+		})
+		if err != nil {
+			var providerErr *OIDCProviderError
+			switch {
+			case errors.Is(err, oidc.ErrInvalidState), errors.Is(err, oidc.ErrMissingCode):
+				return nil, huma.Error400BadRequest("Invalid login callback", err)
+			case errors.As(err, &providerErr):
+				return nil, huma.Error401Unauthorized("Login was rejected by the identity provider", err)
+			default:
+				return nil, huma.Error401Unauthorized("Login failed", err)
+			}
+		}
+		return &oidcRedirectOutput{Status: http.StatusFound, Location: result.ReturnTo}, nil
+	})
+
+	procedure.Register(api, huma.Operation{
+		OperationID:   "oidc-logout",
+		Method:        http.MethodPost,
+		Path:          prefix + "/logout",
+		Summary:       "Log out",
+		Tags:          []string{"auth"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *struct{}, sess *Session) (*struct{}, error) {
+		sess.Logout()
+		return &struct{}{}, nil
+	})
 }
 
 // GoFluxAPI is a special context key for storing the API instance
@@ -957,6 +3913,11 @@ func (ctx *FluxContext) WriteStatusError(statusError *StatusError, errors ...err
 	ctx.WriteErr(statusError.Status, statusError.Message, errors...)
 }
 
+// WriteCodedError writes an error with an application code, e.g. from a middleware
+func (ctx *FluxContext) WriteCodedError(err *CodedError) {
+	writeCodedError(ctx.api, ctx.Context, err)
+}
+
 // 4xx
 
 // NewBadRequestError writes a 400 Bad Request response
@@ -1059,6 +4020,15 @@ func AddOpenAPICommand(rootCmd *cobra.Command, apiProvider func() huma.API) {
 					return err
 				}
 				fmt.Printf("✅ OpenAPI spec saved to %s\n", outputPath)
+
+				// Save the document of each API version next to the full spec
+				paths, err := openapiutils.GenerateVersionSpecsToFiles(api, outputPath)
+				if err != nil {
+					return err
+				}
+				for _, path := range paths {
+					fmt.Printf("✅ OpenAPI spec saved to %s\n", path)
+				}
 			} else {
 				fmt.Print(string(spec))
 			}
@@ -1081,10 +4051,11 @@ func AddOpenAPICommand(rootCmd *cobra.Command, apiProvider func() huma.API) {
 
 // OpenAPI generation utilities - re-export from openapi package
 var (
-	GenerateSpecToFile = openapiutils.GenerateSpecToFile
-	GenerateSpec       = openapiutils.GenerateSpec
-	GenerateSpecYAML   = openapiutils.GenerateSpecYAML
-	GetRouteCount      = openapiutils.GetRouteCount
+	GenerateSpecToFile          = openapiutils.GenerateSpecToFile
+	GenerateVersionSpecsToFiles = openapiutils.GenerateVersionSpecsToFiles
+	GenerateSpec                = openapiutils.GenerateSpec
+	GenerateSpecYAML            = openapiutils.GenerateSpecYAML
+	GetRouteCount               = openapiutils.GetRouteCount
 )
 
 // Re-export feature functions from internal/features
@@ -1101,6 +4072,102 @@ type (
 	HealthResponse = features.HealthResponse
 )
 
+// Re-export health check types from internal/health
+type (
+	HealthCheckOptions = health.Options
+	HealthCheckResult  = health.CheckResult
+	HealthReport       = health.HealthReport
+)
+
+// Re-export configuration types from internal/envconfig
+type (
+	ConfigOptions = envconfig.Options
+	ConfigError   = envconfig.Error
+	ConfigProblem = envconfig.Problem
+)
+
+// Re-export feature flag types from internal/flags
+type (
+	Flag          = flags.Flag
+	FlagTarget    = flags.Target
+	FlagEvaluator = flags.Evaluator
+)
+
+// Re-export feature flag functionality from internal/flags
+var (
+	WithFlagTarget = flags.WithTarget
+)
+
+// Re-export pagination types from internal/pagination
+type (
+	PaginationOptions = pagination.Options
+	OffsetPaginator   = pagination.Offset
+	CursorPaginator   = pagination.Cursor
+	Page[T any]       = pagination.Page[T]
+	PaginationLinker  = pagination.Linker
+)
+
+// Re-export pagination errors from internal/pagination
+var (
+	ErrInvalidCursor = pagination.ErrInvalidCursor
+)
+
+// Re-export filtering types from internal/filter
+type (
+	FilterOptions   = filter.Options
+	FilterCondition = filter.Condition
+	FilterOp        = filter.Op
+	SortKey         = filter.SortKey
+)
+
+// Filter operators
+const (
+	FilterEq       = filter.OpEq
+	FilterNe       = filter.OpNe
+	FilterGt       = filter.OpGt
+	FilterGte      = filter.OpGte
+	FilterLt       = filter.OpLt
+	FilterLte      = filter.OpLte
+	FilterIn       = filter.OpIn
+	FilterContains = filter.OpContains
+	FilterPrefix   = filter.OpPrefix
+	FilterNull     = filter.OpNull
+)
+
+// Re-export error code types from internal/errcodes
+type (
+	CodedError = errcodes.Error
+)
+
+// Re-export validation types from internal/validation
+type (
+	InputValidator = validation.Validator
+	Validation     = validation.Validation
+)
+
+// Re-export request limit errors from internal/limits
+var (
+	ErrBodyTooLarge = limits.ErrBodyTooLarge
+	ErrReadTimeout  = limits.ErrReadTimeout
+)
+
+// Re-export versioning types from internal/versioning
+type (
+	Deprecation = versioning.Deprecation
+)
+
+// Re-export server lifecycle functionality from internal/lifecycle
+var (
+	OnShutdown = lifecycle.OnShutdown
+	Ready      = lifecycle.Ready
+	SetReady   = lifecycle.SetReady
+)
+
+// Re-export server lifecycle types from internal/lifecycle
+type (
+	Server = lifecycle.Server
+)
+
 // Re-export upload functionality from internal/upload
 var (
 	NewFile               = upload.NewFile
@@ -1131,15 +4198,373 @@ var (
 
 // Re-export static functionality from internal/static
 var (
-	ServeStaticFile = static.ServeStaticFile
+	ServeStaticFile    = static.ServeStaticFile
+	ServeStaticRequest = static.ServeStaticRequest
 )
 
 // Re-export static types from internal/static
 type (
 	StaticConfig   = static.StaticConfig
+	StaticRequest  = static.StaticRequest
 	StaticResponse = static.StaticResponse
 )
 
+// Re-export compression functionality from internal/compress
+var (
+	NewCompressor = compress.New
+	EncodeETag    = compress.EncodeETag
+)
+
+// Re-export compression types from internal/compress
+type (
+	CompressionOptions = compress.Options
+	Compressor         = compress.Compressor
+	CompressionRequest = compress.Request
+	CompressionResult  = compress.Result
+	CompressionWriter  = compress.ResponseWriter
+)
+
+// Re-export content negotiation functionality from internal/formats
+var (
+	ConfigureFormats = formats.Configure
+	RegisterFormat   = formats.Register
+	LookupFormat     = formats.Lookup
+	MarshalCSV       = formats.MarshalCSV
+	UnmarshalCSV     = formats.UnmarshalCSV
+)
+
+// Re-export content negotiation types from internal/formats
+type (
+	FormatEncoder = formats.Encoder
+)
+
+// Media types of the built-in formats
+const (
+	MediaTypeJSON        = formats.JSON
+	MediaTypeCBOR        = formats.CBOR
+	MediaTypeMessagePack = formats.MessagePack
+	MediaTypeCSV         = formats.CSV
+)
+
+// Re-export server-sent event types from internal/sse
+type (
+	EventSender = sse.Sender
+	SSEMessage  = sse.Message
+	SSEOptions  = sse.Options
+)
+
+// Re-export WebSocket functionality from internal/ws
+var (
+	ErrWebSocketClosed = ws.ErrClosed
+)
+
+// Re-export WebSocket types from internal/ws
+type (
+	WebSocketConn[In, Out any] = ws.Conn[In, Out]
+	WebSocketOptions           = ws.Options
+	WebSocketMessageError      = ws.MessageError
+)
+
+// Re-export batch types from internal/batch
+type (
+	BatchOptions = batch.Options
+	BatchCall    = batch.BatchCall
+	BatchResult  = batch.BatchResult
+)
+
+// Re-export background job functionality from internal/jobs
+var (
+	NewJobQueue        = jobs.New
+	NewMemoryJobStore  = jobs.NewMemoryStore
+	NewPgxJobStore     = jobs.NewPgxStore
+	DefaultJobBackoff  = jobs.DefaultBackoff
+	PermanentJobError  = jobs.Permanent
+	ErrJobNotFound     = jobs.ErrNotFound
+	ErrJobInvalidState = jobs.ErrInvalidState
+	ErrJobClaimLost    = jobs.ErrClaimLost
+)
+
+// Re-export background job types from internal/jobs
+type (
+	JobQueue        = jobs.Queue
+	JobQueueOptions = jobs.Options
+	JobOptions      = jobs.EnqueueOptions
+	Job             = jobs.Job
+	JobStatus       = jobs.Status
+	JobListOptions  = jobs.ListOptions
+	JobStore        = jobs.Store
+	JobDefinition   = jobs.Definition
+	JobType[T any]  = jobs.Type[T]
+	MemoryJobStore  = jobs.MemoryStore
+	PgxJobStore     = jobs.PgxStore
+	PgxJobExecutor  = jobs.PgxExecutor
+)
+
+// Job statuses
+const (
+	JobPending   = jobs.StatusPending
+	JobRunning   = jobs.StatusRunning
+	JobSucceeded = jobs.StatusSucceeded
+	JobFailed    = jobs.StatusFailed
+	JobCancelled = jobs.StatusCancelled
+)
+
+// Re-export scheduled task functionality from internal/schedule
+var (
+	ParseSchedule        = schedule.Parse
+	EveryInterval        = schedule.Every
+	NewScheduler         = schedule.New
+	NewMemoryLocker      = schedule.NewMemoryLocker
+	NewPgxLocker         = schedule.NewPgxLocker
+	ErrScheduledPanicked = schedule.ErrPanicked
+)
+
+// Re-export scheduled task types from internal/schedule
+type (
+	Scheduler        = schedule.Scheduler
+	SchedulerOptions = schedule.Options
+	TaskOptions      = schedule.TaskOptions
+	ScheduledTask    = schedule.Info
+	ScheduleSpec     = schedule.Spec
+	ScheduleLocker   = schedule.Locker
+	MemoryLocker     = schedule.MemoryLocker
+	PgxLocker        = schedule.PgxLocker
+	PgxLockExecutor  = schedule.PgxExecutor
+)
+
+// Re-export error hook types from internal/hooks
+type (
+	ErrorEvent  = hooks.ErrorEvent
+	ErrorHook   = hooks.ErrorHook
+	ErrorSource = hooks.Source
+)
+
+// Error sources
+const (
+	ErrorSourceRequest = hooks.SourceRequest
+	ErrorSourceTask    = hooks.SourceTask
+	ErrorSourceFlags   = hooks.SourceFlags
+)
+
+// Re-export CORS functionality from internal/cors
+var (
+	NewCORSPolicy      = cors.New
+	DefaultCORSOptions = cors.DefaultOptions
+	CORSFromConfig     = cors.FromConfig
+)
+
+// Re-export CORS types from internal/cors
+type (
+	CORSOptions  = cors.Options
+	CORSPolicy   = cors.Policy
+	CORSRequest  = cors.Request
+	CORSResponse = cors.Response
+)
+
+// corsPolicies holds the router-level CORS policy bound to each root API
+var corsPolicies sync.Map
+
+// UseCORS binds the policy of the router's CORS middleware to an API, so procedures registered on
+// the API (or any of its groups) with WithCORS can override it for their operations
+// Example:
+//
+//	policy, err := goflux.NewCORSPolicy(goflux.CORSFromConfig(projectConfig.CORS))
+//	router.Use(gofluxchi.CORSMiddleware(policy))
+//	humaAPI := humachi.New(router, config)
+//	goflux.UseCORS(humaAPI, policy)
+func UseCORS(api huma.API, policy *CORSPolicy) {
+	corsPolicies.Store(rootAPI(api), policy)
+}
+
+// overrideCORS registers a procedure's CORS policy for every path the operation is served at
+func overrideCORS(api huma.API, operation huma.Operation, policy *cors.Policy) {
+	routerPolicy, ok := corsPolicies.Load(rootAPI(api))
+	if !ok {
+		panic(fmt.Sprintf("operation '%s' uses WithCORS but its API has no CORS policy - install the router's CORS middleware and bind its policy with goflux.UseCORS", operation.OperationID))
+	}
+	for _, path := range operationPaths(api, operation) {
+		routerPolicy.(*cors.Policy).Override(operation.Method, path, policy)
+	}
+}
+
+// rootAPI unwraps huma groups down to the API the router serves
+func rootAPI(api huma.API) huma.API {
+	for {
+		group, ok := api.(*huma.Group)
+		if !ok {
+			return api
+		}
+		api = group.API
+	}
+}
+
+// operationPaths returns the paths the router serves an operation at, after the prefixes of all
+// enclosing groups have been applied. Groups with several prefixes fan out to several paths
+func operationPaths(api huma.API, operation huma.Operation) []string {
+	group, ok := api.(*huma.Group)
+	if !ok {
+		return []string{operation.Path}
+	}
+
+	var paths []string
+	group.ModifyOperation(&operation, func(modified *huma.Operation) {
+		paths = append(paths, operationPaths(group.API, *modified)...)
+	})
+	return paths
+}
+
+// Re-export authentication functionality from internal/auth
+var (
+	PrincipalFromContext = auth.PrincipalFromContext
+	WithPrincipal        = auth.WithPrincipal
+)
+
+// Re-export authentication types from internal/auth
+type (
+	Principal = auth.Principal
+)
+
+// Re-export authorization functionality from internal/policy
+var (
+	ErrUnauthenticated = policy.ErrUnauthenticated
+	ErrForbidden       = policy.ErrForbidden
+)
+
+// Re-export authorization types from internal/policy
+type (
+	PolicyRequirements = policy.Requirements
+)
+
+// AuthorizationExtension is the OpenAPI operation extension listing required scopes, roles and permissions
+const AuthorizationExtension = policy.Extension
+
+// Re-export JWT functionality from internal/jwt
+var (
+	NewJWTVerifier      = jwt.New
+	JWTTokenFromContext = jwt.TokenFromContext
+)
+
+// Re-export JWT types from internal/jwt
+type (
+	JWTOptions  = jwt.Options
+	JWTVerifier = jwt.Verifier
+	JWTToken    = jwt.Token
+	JWTHeader   = jwt.Header
+	JWTClaims   = jwt.Claims
+)
+
+// Re-export JWT errors from internal/jwt
+var (
+	ErrMissingToken         = jwt.ErrMissingToken
+	ErrMalformedToken       = jwt.ErrMalformedToken
+	ErrUnsupportedAlgorithm = jwt.ErrUnsupportedAlgorithm
+	ErrKeyNotFound          = jwt.ErrKeyNotFound
+	ErrInvalidSignature     = jwt.ErrInvalidSignature
+	ErrTokenExpired         = jwt.ErrTokenExpired
+	ErrTokenNotYetValid     = jwt.ErrTokenNotYetValid
+	ErrMissingExpiry        = jwt.ErrMissingExpiry
+	ErrInvalidIssuer        = jwt.ErrInvalidIssuer
+	ErrInvalidAudience      = jwt.ErrInvalidAudience
+)
+
+// Re-export API key functionality from internal/apikey
+var (
+	NewAPIKeyAuthenticator = apikey.New
+	NewMemoryAPIKeyStore   = apikey.NewMemoryStore
+	GenerateAPIKey         = apikey.Generate
+	HashAPIKey             = apikey.Hash
+	APIKeyFromContext      = apikey.KeyFromContext
+	ErrMissingAPIKey       = apikey.ErrMissingKey
+	ErrInvalidAPIKey       = apikey.ErrInvalidKey
+	ErrExpiredAPIKey       = apikey.ErrExpiredKey
+	ErrRevokedAPIKey       = apikey.ErrRevokedKey
+)
+
+// Re-export API key types from internal/apikey
+type (
+	APIKey              = apikey.Key
+	APIKeyOptions       = apikey.Options
+	APIKeyAuthenticator = apikey.Authenticator
+	APIKeyStore         = apikey.Store
+	APIKeyStoreFunc     = apikey.StoreFunc
+	MemoryAPIKeyStore   = apikey.MemoryStore
+	APIKeyRateLimit     = apikey.RateLimit
+)
+
+// Re-export session functionality from internal/session
+var (
+	NewSessionManager     = session.New
+	NewMemorySessionStore = session.NewMemoryStore
+	NewFileSessionStore   = session.NewFileStore
+	NewPgxSessionStore    = session.NewPgxStore
+	SessionFromContext    = session.FromContext
+	ErrSessionNotFound    = session.ErrNotFound
+)
+
+// Re-export session types from internal/session
+type (
+	SessionOptions     = session.Options
+	SessionManager     = session.Manager
+	Session            = session.Session
+	SessionStore       = session.Store
+	MemorySessionStore = session.MemoryStore
+	FileSessionStore   = session.FileStore
+	PgxSessionStore    = session.PgxStore
+	PgxExecutor        = session.PgxExecutor
+)
+
+// Re-export ETag functionality from internal/etag
+var (
+	ComputeETag        = etag.Compute
+	ETagFromVersion    = etag.FromVersion
+	CheckPreconditions = etag.CheckPreconditions
+)
+
+// Re-export ETag types from internal/etag
+type (
+	ETagOptions   = etag.Options
+	Preconditions = etag.Preconditions
+)
+
+// Re-export idempotency functionality from internal/idempotency
+var (
+	NewMemoryIdempotencyStore = idempotency.NewMemoryStore
+	ErrIdempotencyKeyMissing  = idempotency.ErrMissingKey
+	ErrIdempotencyInProgress  = idempotency.ErrInProgress
+	ErrIdempotencyKeyMismatch = idempotency.ErrKeyMismatch
+)
+
+// Re-export idempotency types from internal/idempotency
+type (
+	IdempotencyOptions     = idempotency.Options
+	IdempotencyStore       = idempotency.Store
+	IdempotencyRecord      = idempotency.Record
+	MemoryIdempotencyStore = idempotency.MemoryStore
+)
+
+// Re-export OpenID Connect functionality from internal/oidc
+var (
+	NewOIDCRelyingParty = oidc.New
+	NewMockOIDCProvider = oidc.NewMockProvider
+	ErrOIDCInvalidState = oidc.ErrInvalidState
+	ErrOIDCMissingCode  = oidc.ErrMissingCode
+	ErrOIDCInvalidNonce = oidc.ErrInvalidNonce
+	ErrOIDCMissingToken = oidc.ErrMissingIDToken
+)
+
+// Re-export OpenID Connect types from internal/oidc
+type (
+	OIDCOptions       = oidc.Options
+	OIDCRelyingParty  = oidc.RelyingParty
+	OIDCProvider      = oidc.Provider
+	OIDCTokenResponse = oidc.TokenResponse
+	OIDCLoginResult   = oidc.LoginResult
+	OIDCProviderError = oidc.ProviderError
+	MockOIDCOptions   = oidc.MockOptions
+	MockOIDCProvider  = oidc.MockProvider
+	MockOIDCUser      = oidc.MockUser
+)
+
 // RegisterMultipartUpload creates a simple multipart file upload endpoint with minimal boilerplate
 func RegisterMultipartUpload(api huma.API, path string, handler interface{}, options ...func(*huma.Operation)) {
 	NewProcedure().RegisterMultipartUpload(api, path, handler, options...)
@@ -0,0 +1,249 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barisgit/goflux/internal/auth"
+)
+
+// Supported key locations, matching the OpenAPI "in" values for apiKey schemes
+const (
+	InHeader = "header"
+	InQuery  = "query"
+	InCookie = "cookie"
+)
+
+// Common API key errors
+var (
+	ErrMissingKey = errors.New("missing API key")
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrExpiredKey = errors.New("API key has expired")
+	ErrRevokedKey = errors.New("API key has been revoked")
+)
+
+// Key is the stored record of an API key. Only the hash of the secret is kept
+type Key struct {
+	// ID identifies the key in logs and admin tools (it is not secret)
+	ID string
+	// Hash is the hex encoded SHA-256 of the raw key, see Hash
+	Hash string
+	// Subject is the client or user the key belongs to
+	Subject string
+	// Scopes granted to the key
+	Scopes []string
+	// Roles granted to the key
+	Roles []string
+	// Tier selects the rate limit applied to the key (see Options.Tiers)
+	Tier string
+	// ExpiresAt is optional; zero means the key never expires
+	ExpiresAt time.Time
+	// Revoked keys are rejected
+	Revoked bool
+	// Metadata holds arbitrary application data
+	Metadata map[string]string
+}
+
+// Principal converts the key into a generic authenticated principal
+func (k *Key) Principal() *auth.Principal {
+	return &auth.Principal{
+		Subject: k.Subject,
+		Scheme:  "apiKey",
+		Scopes:  k.Scopes,
+		Roles:   k.Roles,
+		Claims: map[string]any{
+			"key_id": k.ID,
+			"tier":   k.Tier,
+		},
+	}
+}
+
+// Store looks up keys by the hash of their secret
+type Store interface {
+	// LookupHash returns the key with the given hash, or ErrInvalidKey if there is none
+	LookupHash(ctx context.Context, hash string) (*Key, error)
+}
+
+// Hash returns the hex encoded SHA-256 of a raw key. API keys are long random
+// strings, so a fast hash is sufficient and allows direct lookups
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generate creates a new random key with an optional prefix (e.g. "sk_live_").
+// The raw key must be shown to the user once; only the returned hash should be stored
+func Generate(prefix string) (raw string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("apikey: failed to generate key: %w", err)
+	}
+	raw = prefix + base64.RawURLEncoding.EncodeToString(b)
+	return raw, Hash(raw), nil
+}
+
+// Options configures API key authentication
+type Options struct {
+	// Store resolves key hashes (required)
+	Store Store
+	// Name is the header, query parameter or cookie carrying the key (default "X-API-Key")
+	Name string
+	// In is where the key is sent: "header", "query" or "cookie" (default "header")
+	In string
+	// Tiers maps Key.Tier to a rate limit. Keys with an unknown or empty tier are not limited
+	// unless a "" tier is configured
+	Tiers map[string]RateLimit
+}
+
+// Authenticator validates API keys from requests
+type Authenticator struct {
+	options Options
+	limiter *limiter
+}
+
+// New creates an authenticator
+func New(opts Options) (*Authenticator, error) {
+	if opts.Store == nil {
+		return nil, fmt.Errorf("apikey: Store is required")
+	}
+	if opts.Name == "" {
+		opts.Name = "X-API-Key"
+	}
+	if opts.In == "" {
+		opts.In = InHeader
+	}
+	switch opts.In {
+	case InHeader, InQuery, InCookie:
+	default:
+		return nil, fmt.Errorf("apikey: In must be %q, %q or %q", InHeader, InQuery, InCookie)
+	}
+
+	return &Authenticator{options: opts, limiter: newLimiter(opts.Tiers)}, nil
+}
+
+// Options returns the authenticator options with defaults applied
+func (a *Authenticator) Options() Options {
+	return a.options
+}
+
+// Extract reads the raw key from a request using the configured location
+func (a *Authenticator) Extract(header func(string) string, query func(string) string) string {
+	switch a.options.In {
+	case InQuery:
+		return query(a.options.Name)
+	case InCookie:
+		request := &http.Request{Header: http.Header{"Cookie": {header("Cookie")}}}
+		if c, err := request.Cookie(a.options.Name); err == nil {
+			return c.Value
+		}
+		return ""
+	default:
+		return strings.TrimSpace(header(a.options.Name))
+	}
+}
+
+// Authenticate resolves and validates a raw key
+func (a *Authenticator) Authenticate(ctx context.Context, raw string) (*Key, error) {
+	if raw == "" {
+		return nil, ErrMissingKey
+	}
+
+	key, err := a.options.Store.LookupHash(ctx, Hash(raw))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidKey
+	}
+	if key.Revoked {
+		return nil, ErrRevokedKey
+	}
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return nil, ErrExpiredKey
+	}
+
+	return key, nil
+}
+
+// Allow records a request for the key and reports the rate limit state
+func (a *Authenticator) Allow(key *Key) LimitResult {
+	return a.limiter.allow(key)
+}
+
+// ============================================================================
+// MEMORY STORE
+// ============================================================================
+
+// MemoryStore keeps keys in process memory, useful for tests and static key lists
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]*Key
+}
+
+// NewMemoryStore creates a store with the given keys
+func NewMemoryStore(keys ...*Key) *MemoryStore {
+	s := &MemoryStore{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		s.Add(key)
+	}
+	return s
+}
+
+// Add stores a key. Key.Hash must be set
+func (s *MemoryStore) Add(key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Hash] = key
+}
+
+// Remove deletes the key with the given ID
+func (s *MemoryStore) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, key := range s.keys {
+		if key.ID == id {
+			delete(s.keys, hash)
+		}
+	}
+}
+
+// LookupHash implements Store
+func (s *MemoryStore) LookupHash(ctx context.Context, hash string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// StoreFunc adapts a function to the Store interface, e.g. a database query
+type StoreFunc func(ctx context.Context, hash string) (*Key, error)
+
+// LookupHash implements Store
+func (f StoreFunc) LookupHash(ctx context.Context, hash string) (*Key, error) {
+	return f(ctx, hash)
+}
+
+type keyContextKey struct{}
+
+// WithKey returns a copy of ctx carrying the authenticated key
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the key stored by the API key middleware
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(*Key)
+	return key, ok && key != nil
+}
@@ -0,0 +1,128 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	raw, hash, err := Generate("sk_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(id string, configure func(*Key)) (string, *Key) {
+		secret := raw + "-" + id
+		k := &Key{ID: id, Hash: Hash(secret), Subject: "client-" + id}
+		if configure != nil {
+			configure(k)
+		}
+		return secret, k
+	}
+
+	validRaw := raw
+	valid := &Key{ID: "valid", Hash: hash, Subject: "client-valid"}
+	futureRaw, future := key("future", func(k *Key) { k.ExpiresAt = time.Now().Add(time.Hour) })
+	expiredRaw, expired := key("expired", func(k *Key) { k.ExpiresAt = time.Now().Add(-time.Minute) })
+	revokedRaw, revoked := key("revoked", func(k *Key) { k.Revoked = true })
+	revokedExpiredRaw, revokedExpired := key("revoked-expired", func(k *Key) {
+		k.Revoked = true
+		k.ExpiresAt = time.Now().Add(-time.Minute)
+	})
+	store := NewMemoryStore(valid, future, expired, revoked, revokedExpired)
+
+	authenticator, err := New(Options{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    error
+		subject string
+	}{
+		{"valid", validRaw, nil, "client-valid"},
+		{"expires later", futureRaw, nil, "client-future"},
+		{"missing", "", ErrMissingKey, ""},
+		{"unknown", "sk_test_unknown", ErrInvalidKey, ""},
+		{"hash instead of key", hash, ErrInvalidKey, ""},
+		{"expired", expiredRaw, ErrExpiredKey, ""},
+		{"revoked", revokedRaw, ErrRevokedKey, ""},
+		{"revoked and expired", revokedExpiredRaw, ErrRevokedKey, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticator.Authenticate(context.Background(), tt.raw)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && got.Subject != tt.subject {
+				t.Fatalf("Authenticate() subject = %q, want %q", got.Subject, tt.subject)
+			}
+			if tt.want != nil && got != nil {
+				t.Fatalf("Authenticate() returned key %q with error %v", got.ID, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateStoreErrors(t *testing.T) {
+	unavailable := errors.New("database unavailable")
+
+	tests := []struct {
+		name  string
+		store StoreFunc
+		want  error
+	}{
+		{"store failure", func(ctx context.Context, hash string) (*Key, error) { return nil, unavailable }, unavailable},
+		{"nil key", func(ctx context.Context, hash string) (*Key, error) { return nil, nil }, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := New(Options{Store: tt.store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := authenticator.Authenticate(context.Background(), "sk_test_key"); !errors.Is(err, tt.want) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	headers := map[string]string{
+		"X-API-Key":     "  header-key ",
+		"Authorization": "custom-header-key",
+		"Cookie":        "theme=dark; api_key=cookie-key",
+	}
+	query := url.Values{"api_key": {"query-key"}}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"default header", Options{}, "header-key"},
+		{"custom header", Options{Name: "Authorization"}, "custom-header-key"},
+		{"query", Options{In: InQuery, Name: "api_key"}, "query-key"},
+		{"cookie", Options{In: InCookie, Name: "api_key"}, "cookie-key"},
+		{"missing cookie", Options{In: InCookie, Name: "session"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Store = NewMemoryStore()
+			authenticator, err := New(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := authenticator.Extract(func(name string) string { return headers[name] }, query.Get)
+			if got != tt.want {
+				t.Fatalf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit allows Requests per Window for each key
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// LimitResult describes the rate limit state after a request
+type LimitResult struct {
+	// Allowed is false when the key exceeded its limit
+	Allowed bool
+	// Limited is false when no limit applies to the key
+	Limited bool
+	// Limit is the number of requests allowed per window
+	Limit int
+	// Remaining is the number of requests left in the current window
+	Remaining int
+	// Reset is when the current window ends
+	Reset time.Time
+}
+
+// limiter implements fixed-window counters per key ID
+type limiter struct {
+	tiers map[string]RateLimit
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+type window struct {
+	end   time.Time
+	count int
+}
+
+func newLimiter(tiers map[string]RateLimit) *limiter {
+	return &limiter{tiers: tiers, windows: make(map[string]*window)}
+}
+
+func (l *limiter) allow(key *Key) LimitResult {
+	tier, ok := l.tiers[key.Tier]
+	if !ok || tier.Requests <= 0 || tier.Window <= 0 {
+		return LimitResult{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	id := key.ID
+	if id == "" {
+		id = key.Hash
+	}
+
+	w, exists := l.windows[id]
+	if !exists || !now.Before(w.end) {
+		w = &window{end: now.Truncate(tier.Window).Add(tier.Window)}
+		l.windows[id] = w
+		l.evict(now)
+	}
+
+	result := LimitResult{Limited: true, Limit: tier.Requests, Reset: w.end}
+	if w.count >= tier.Requests {
+		return result
+	}
+
+	w.count++
+	result.Allowed = true
+	result.Remaining = tier.Requests - w.count
+	return result
+}
+
+// evict drops finished windows so idle keys don't accumulate; called with the lock held
+func (l *limiter) evict(now time.Time) {
+	if len(l.windows) < 1024 {
+		return
+	}
+	for id, w := range l.windows {
+		if !now.Before(w.end) {
+			delete(l.windows, id)
+		}
+	}
+}
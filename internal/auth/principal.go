@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal describes the authenticated caller of a request, independent of how it was authenticated
+type Principal struct {
+	// Subject uniquely identifies the caller (user ID, client ID, key ID, ...)
+	Subject string
+	// Scheme is the security scheme that authenticated the caller (e.g. "bearer")
+	Scheme string
+	// Scopes granted to the caller, e.g. from an OAuth2 "scope" claim
+	Scopes []string
+	// Roles assigned to the caller
+	Roles []string
+	// Permissions granted to the caller, e.g. "posts:delete"
+	Permissions []string
+	// Claims contains the raw attributes the authenticator extracted
+	Claims map[string]any
+}
+
+// HasScope reports whether the principal was granted the given scope
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// HasRole reports whether the principal has the given role
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasPermission reports whether the principal was granted the given permission
+func (p *Principal) HasPermission(permission string) bool {
+	return p != nil && slices.Contains(p.Permissions, permission)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx by an authentication middleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,374 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/barisgit/goflux/internal/sse"
+	"github.com/barisgit/goflux/internal/ws"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension marks the batch operation in OpenAPI so generated clients know where to send batched calls
+const Extension = "x-goflux-batch"
+
+// Options configures a batch endpoint
+type Options struct {
+	// MaxCalls is the largest number of calls accepted in one batch (default 50)
+	MaxCalls int
+	// Concurrency is how many calls of a batch run at the same time (default 8)
+	Concurrency int
+}
+
+// BatchCall invokes one registered operation
+type BatchCall struct {
+	OperationID string         `json:"operationId" minLength:"1" doc:"ID of the operation to call"`
+	Params      map[string]any `json:"params,omitempty" doc:"Path and query parameters by name"`
+	Body        any            `json:"body,omitempty" doc:"Request body of the operation"`
+}
+
+// BatchResult is the response one call would have had as a separate request
+type BatchResult struct {
+	Status int `json:"status" doc:"HTTP status of the call"`
+	Body   any `json:"body,omitempty" doc:"Response body of the call; JSON responses are embedded, others are strings"`
+}
+
+// Input is the request of the batch operation
+type Input struct {
+	Body []BatchCall
+}
+
+// Output is the response of the batch operation, one result per call in request order
+type Output struct {
+	Body []BatchResult
+}
+
+// Dispatcher executes batched calls by routing each one through the API as its own request,
+// so router middleware, procedure middleware, dependency injection and validation all apply
+type Dispatcher struct {
+	api     huma.API
+	options Options
+
+	once       sync.Once
+	operations map[string]*huma.Operation
+}
+
+// New creates a dispatcher for the operations registered with api
+func New(api huma.API, options Options) *Dispatcher {
+	if options.MaxCalls <= 0 {
+		options.MaxCalls = 50
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 8
+	}
+	return &Dispatcher{api: api, options: options}
+}
+
+// Annotate marks the batch operation with the x-goflux-batch extension and captures the
+// headers of incoming batches, which every call inherits
+func (d *Dispatcher) Annotate(operation *huma.Operation) {
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = map[string]any{"maxCalls": d.options.MaxCalls}
+	if operation.Description == "" {
+		operation.Description = "Executes several operations in one request. Each call responds with the status and body it would have had on its own."
+	}
+	operation.Middlewares = append(operation.Middlewares, d.capture)
+}
+
+type requestKey struct{}
+
+// origin is what calls inherit from the batch request
+type origin struct {
+	header     http.Header
+	host       string
+	remoteAddr string
+	tls        *tls.ConnectionState
+}
+
+func (d *Dispatcher) capture(ctx huma.Context, next func(huma.Context)) {
+	o := &origin{
+		header:     http.Header{},
+		host:       ctx.Host(),
+		remoteAddr: ctx.RemoteAddr(),
+		tls:        ctx.TLS(),
+	}
+	ctx.EachHeader(func(name, value string) {
+		o.header.Add(name, value)
+	})
+	next(huma.WithValue(ctx, requestKey{}, o))
+}
+
+// Handle executes the calls of a batch and returns their results in order
+func (d *Dispatcher) Handle(ctx context.Context, input *Input) (*Output, error) {
+	if len(input.Body) > d.options.MaxCalls {
+		return nil, huma.Error400BadRequest(fmt.Sprintf("a batch can hold at most %d calls, got %d", d.options.MaxCalls, len(input.Body)))
+	}
+	o, ok := ctx.Value(requestKey{}).(*origin)
+	if !ok {
+		return nil, fmt.Errorf("batch: the operation was not annotated by the dispatcher")
+	}
+	d.once.Do(d.index)
+
+	results := make([]BatchResult, len(input.Body))
+	slots := make(chan struct{}, d.options.Concurrency)
+	var wg sync.WaitGroup
+	for i, call := range input.Body {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i] = d.dispatch(ctx, o, call)
+		}()
+	}
+	wg.Wait()
+
+	return &Output{Body: results}, nil
+}
+
+// index maps operation IDs to operations. It runs on the first batch, once every operation has been registered
+func (d *Dispatcher) index() {
+	d.operations = map[string]*huma.Operation{}
+	for _, item := range d.api.OpenAPI().Paths {
+		for _, op := range []*huma.Operation{item.Get, item.Put, item.Post, item.Delete, item.Options, item.Head, item.Patch, item.Trace} {
+			if op != nil && op.OperationID != "" {
+				d.operations[op.OperationID] = op
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, o *origin, call BatchCall) BatchResult {
+	op, ok := d.operations[call.OperationID]
+	if !ok {
+		return errorResult(http.StatusNotFound, fmt.Sprintf("unknown operation %q", call.OperationID))
+	}
+	if reason := unbatchable(op); reason != "" {
+		return errorResult(http.StatusBadRequest, fmt.Sprintf("operation %q cannot be batched: %s", call.OperationID, reason))
+	}
+
+	req, err := newRequest(ctx, o, op, call)
+	if err != nil {
+		return errorResult(http.StatusBadRequest, fmt.Sprintf("invalid call to %q: %v", call.OperationID, err))
+	}
+	rec := &recorder{header: http.Header{}}
+	d.api.Adapter().ServeHTTP(rec, req)
+	return rec.result()
+}
+
+// unbatchable explains why an operation cannot be part of a batch, empty when it can
+func unbatchable(op *huma.Operation) string {
+	if _, ok := op.Extensions[Extension]; ok {
+		return "batches cannot be nested"
+	}
+	if _, ok := op.Extensions[ws.Extension]; ok {
+		return "it is a WebSocket endpoint"
+	}
+	for _, resp := range op.Responses {
+		if _, ok := resp.Content[sse.ContentType]; ok {
+			return "it streams server-sent events"
+		}
+	}
+	return ""
+}
+
+// skippedHeaders are batch request headers that describe the batch itself rather than its calls
+var skippedHeaders = map[string]bool{
+	"Accept":              true,
+	"Accept-Encoding":     true,
+	"Connection":          true,
+	"Content-Encoding":    true,
+	"Content-Length":      true,
+	"Content-Type":        true,
+	"Expect":              true,
+	"Idempotency-Key":     true,
+	"If-Match":            true,
+	"If-Modified-Since":   true,
+	"If-None-Match":       true,
+	"If-Range":            true,
+	"If-Unmodified-Since": true,
+	"Range":               true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// newRequest builds the request of a call: parameters fill the path template and the query
+// string, and headers such as Authorization, Cookie and X-CSRF-Token come from the batch
+func newRequest(ctx context.Context, o *origin, op *huma.Operation, call BatchCall) (*http.Request, error) {
+	path, query, err := expand(op, call.Params)
+	if err != nil {
+		return nil, err
+	}
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if call.Body != nil {
+		data, err := json.Marshal(call.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(detached{ctx}, op.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range o.header {
+		if !skippedHeaders[name] {
+			req.Header[name] = values
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Host = o.host
+	req.RemoteAddr = o.remoteAddr
+	req.TLS = o.tls
+	req.RequestURI = target
+	return req, nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// expand substitutes path parameters into the operation's path and puts the others in the query
+func expand(op *huma.Operation, params map[string]any) (string, url.Values, error) {
+	used := map[string]bool{}
+	var missing []string
+	path := pathParam.ReplaceAllStringFunc(op.Path, func(match string) string {
+		name := strings.TrimSuffix(match[1:len(match)-1], "...")
+		value, ok := params[name]
+		if !ok || value == nil {
+			missing = append(missing, name)
+			return match
+		}
+		used[name] = true
+		return url.PathEscape(format(value))
+	})
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("missing path parameters: %s", strings.Join(missing, ", "))
+	}
+
+	query := url.Values{}
+	for name, value := range params {
+		if used[name] || value == nil {
+			continue
+		}
+		list, isList := value.([]any)
+		if !isList {
+			query.Set(name, format(value))
+			continue
+		}
+		values := make([]string, len(list))
+		for i, item := range list {
+			values[i] = format(item)
+		}
+		if explode(op, name) {
+			query[name] = values
+		} else {
+			query.Set(name, strings.Join(values, ","))
+		}
+	}
+	return path, query, nil
+}
+
+// explode reports whether a query parameter takes one value per item instead of a comma-separated list
+func explode(op *huma.Operation, name string) bool {
+	for _, param := range op.Parameters {
+		if param.In == "query" && param.Name == name {
+			return param.Explode != nil && *param.Explode
+		}
+	}
+	return false
+}
+
+// format renders a JSON parameter value the way it would appear in a URL
+func format(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func errorResult(status int, message string) BatchResult {
+	return BatchResult{Status: status, Body: huma.NewError(status, message)}
+}
+
+// detached keeps the cancellation of the batch request but none of its values, so routers do not
+// mistake a call for a request they are already routing (chi keeps its route context there)
+type detached struct {
+	context.Context
+}
+
+func (detached) Value(any) any {
+	return nil
+}
+
+// recorder buffers the response of a call
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(p)
+}
+
+// Flush lets streaming handlers write into the buffer
+func (r *recorder) Flush() {}
+
+func (r *recorder) result() BatchResult {
+	res := BatchResult{Status: r.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	if r.body.Len() == 0 {
+		return res
+	}
+	if strings.Contains(r.header.Get("Content-Type"), "json") && json.Valid(r.body.Bytes()) {
+		res.Body = json.RawMessage(r.body.Bytes())
+	} else {
+		res.Body = r.body.String()
+	}
+	return res
+}
@@ -0,0 +1,310 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported content codings
+const (
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// Options configures response compression
+type Options struct {
+	// Encodings lists the enabled encodings in server preference order (default "br", "gzip")
+	Encodings []string
+	// MinSize is the smallest body in bytes worth compressing (default 1024)
+	MinSize int
+	// ContentTypes lists the compressible media types. A "*" matches any run of characters,
+	// e.g. "text/*" or "application/*+json" (default text, JSON, JavaScript, XML, SVG and WebAssembly).
+	// Server-sent event streams are never compressed
+	ContentTypes []string
+	// GzipLevel is the gzip compression level (default gzip.DefaultCompression)
+	GzipLevel int
+	// BrotliLevel is the brotli compression level from 0 to 11 (default 5, tuned for dynamic responses)
+	BrotliLevel int
+}
+
+// DefaultContentTypes are the media types compressed when Options.ContentTypes is empty
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/javascript",
+	"application/xml",
+	"application/*+xml",
+	"image/svg+xml",
+	"application/wasm",
+}
+
+// Request describes a response that may be compressed, router-agnostic
+type Request struct {
+	// Method of the request being answered
+	Method string
+	// AcceptEncoding is the request's Accept-Encoding header
+	AcceptEncoding string
+	// Status is the response status (0 means 200)
+	Status int
+	// ContentType and ContentEncoding are the response headers of the same name
+	ContentType     string
+	ContentEncoding string
+	// Size is the response body length in bytes
+	Size int
+}
+
+// Result contains the outcome of compressing a buffered response
+type Result struct {
+	// Body is the compressed body, or the original body when Encoding is empty
+	Body []byte
+	// Encoding is the Content-Encoding to send, empty when the body was left as is
+	Encoding string
+	// Vary is true when the response depends on Accept-Encoding and should carry Vary: Accept-Encoding
+	Vary bool
+}
+
+// Compressor negotiates and applies response compression
+type Compressor struct {
+	options     Options
+	gzipWriters sync.Pool
+	brWriters   sync.Pool
+}
+
+// New creates a compressor with defaults applied
+func New(opts Options) *Compressor {
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{Brotli, Gzip}
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = DefaultContentTypes
+	}
+	if opts.GzipLevel == 0 || opts.GzipLevel < gzip.HuffmanOnly || opts.GzipLevel > gzip.BestCompression {
+		opts.GzipLevel = gzip.DefaultCompression
+	}
+	if opts.BrotliLevel <= 0 || opts.BrotliLevel > brotli.BestCompression {
+		opts.BrotliLevel = 5
+	}
+	return &Compressor{options: opts}
+}
+
+// Options returns the compressor options with defaults applied
+func (c *Compressor) Options() Options {
+	return c.options
+}
+
+// Negotiate picks the encoding to use for an Accept-Encoding header, or "" for identity.
+// The client's q-values decide first, ties are broken by the server's preference order
+func (c *Compressor) Negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range c.options.Encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// Compressible reports whether responses of the given Content-Type may be compressed
+func (c *Compressor) Compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" || mediaType == "text/event-stream" {
+		return false
+	}
+	for _, pattern := range c.options.ContentTypes {
+		if matchMediaType(strings.ToLower(pattern), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMediaType matches a media type against a pattern with at most one "*"
+func matchMediaType(pattern, mediaType string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == mediaType
+	}
+	return len(mediaType) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix)
+}
+
+// Encoding decides how a response should be encoded. It returns the negotiated encoding
+// ("" to send the body as is) and whether the response varies by Accept-Encoding
+func (c *Compressor) Encoding(r Request) (encoding string, vary bool) {
+	if !c.eligible(r) {
+		return "", false
+	}
+	if r.Size < c.options.MinSize {
+		return "", true
+	}
+	return c.Negotiate(r.AcceptEncoding), true
+}
+
+// eligible reports whether a response could be compressed regardless of its size
+func (c *Compressor) eligible(r Request) bool {
+	switch {
+	case r.Method == http.MethodHead:
+		return false
+	case r.Status == http.StatusNoContent, r.Status == http.StatusNotModified,
+		r.Status == http.StatusPartialContent, r.Status > 0 && r.Status < 200:
+		return false
+	case r.ContentEncoding != "" && !strings.EqualFold(r.ContentEncoding, "identity"):
+		return false
+	}
+	return c.Compressible(r.ContentType)
+}
+
+// Apply compresses a fully buffered response body when the request allows it.
+// Encoding failures fall back to the uncompressed body
+func (c *Compressor) Apply(r Request, body []byte) Result {
+	r.Size = len(body)
+	encoding, vary := c.Encoding(r)
+	if encoding == "" {
+		return Result{Body: body, Vary: vary}
+	}
+	compressed, err := c.Compress(body, encoding)
+	if err != nil || len(compressed) >= len(body) {
+		return Result{Body: body, Vary: vary}
+	}
+	return Result{Body: compressed, Encoding: encoding, Vary: vary}
+}
+
+// Compress encodes body with the given encoding
+func (c *Compressor) Compress(body []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := c.NewWriter(&buf, encoding)
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder is a compressing writer. Close must be called to flush the stream and release the encoder
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// NewWriter returns a pooled encoder writing to w. Unknown encodings write w unchanged
+func (c *Compressor) NewWriter(w io.Writer, encoding string) Encoder {
+	switch encoding {
+	case Gzip:
+		gz, _ := c.gzipWriters.Get().(*gzip.Writer)
+		if gz == nil {
+			gz, _ = gzip.NewWriterLevel(w, c.options.GzipLevel)
+		} else {
+			gz.Reset(w)
+		}
+		return &pooledEncoder{encoder: gz, release: func() { c.gzipWriters.Put(gz) }}
+	case Brotli:
+		br, _ := c.brWriters.Get().(*brotli.Writer)
+		if br == nil {
+			br = brotli.NewWriterLevel(w, c.options.BrotliLevel)
+		} else {
+			br.Reset(w)
+		}
+		return &pooledEncoder{encoder: br, release: func() { c.brWriters.Put(br) }}
+	default:
+		return nopEncoder{w}
+	}
+}
+
+type pooledEncoder struct {
+	encoder Encoder
+	release func()
+	closed  bool
+}
+
+func (e *pooledEncoder) Write(p []byte) (int, error) {
+	return e.encoder.Write(p)
+}
+
+func (e *pooledEncoder) Flush() error {
+	return e.encoder.Flush()
+}
+
+func (e *pooledEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	err := e.encoder.Close()
+	e.release()
+	return err
+}
+
+type nopEncoder struct {
+	io.Writer
+}
+
+func (nopEncoder) Flush() error { return nil }
+func (nopEncoder) Close() error { return nil }
+
+// AddVary appends Accept-Encoding to a Vary header value unless it is already listed
+func AddVary(vary string) string {
+	for _, name := range strings.Split(vary, ",") {
+		name = strings.TrimSpace(name)
+		if name == "*" || strings.EqualFold(name, "Accept-Encoding") {
+			return vary
+		}
+	}
+	if strings.TrimSpace(vary) == "" {
+		return "Accept-Encoding"
+	}
+	return vary + ", Accept-Encoding"
+}
+
+// EncodeETag gives the ETag of a compressed variant its own tag by appending the encoding, e.g.
+// "abc" becomes "abc;gzip". The compressed bytes differ from the tagged representation, while
+// conditional requests compare tags without the suffix, so strong If-Match checks keep working
+func EncodeETag(tag, encoding string) string {
+	if !strings.HasSuffix(tag, `"`) || len(strings.TrimPrefix(tag, "W/")) <= 2 {
+		return tag
+	}
+	return strings.TrimSuffix(tag, `"`) + ";" + encoding + `"`
+}
@@ -0,0 +1,355 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// sink is the response a stream writes to once it has decided on an encoding
+type sink interface {
+	header() http.Header
+	writeHeader(status int)
+	body() io.Writer
+}
+
+// stream buffers the start of a response until it is large enough to decide whether
+// to compress it, then writes the headers and the (possibly encoded) body to the sink
+type stream struct {
+	compressor     *Compressor
+	sink           sink
+	method         string
+	acceptEncoding string
+
+	status    int
+	buffer    bytes.Buffer
+	committed bool
+	encoder   Encoder
+	writer    io.Writer
+}
+
+func (s *stream) setStatus(status int) {
+	if !s.committed {
+		s.status = status
+	}
+}
+
+func (s *stream) write(p []byte) (int, error) {
+	if s.committed {
+		return s.writer.Write(p)
+	}
+	s.buffer.Write(p)
+	if s.buffer.Len() >= s.compressor.options.MinSize {
+		if err := s.commit(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush commits the response with what has been buffered so far and flushes the encoder
+func (s *stream) flush() error {
+	if !s.committed {
+		if err := s.commit(); err != nil {
+			return err
+		}
+	}
+	if s.encoder != nil {
+		return s.encoder.Flush()
+	}
+	return nil
+}
+
+// close commits an unfinished response and terminates the encoded stream
+func (s *stream) close() error {
+	if !s.committed {
+		if s.status == 0 && s.buffer.Len() == 0 {
+			// Nothing was written, leave the response untouched
+			return nil
+		}
+		if err := s.commit(); err != nil {
+			return err
+		}
+	}
+	if s.encoder != nil {
+		return s.encoder.Close()
+	}
+	return nil
+}
+
+// commit decides on the encoding, writes the headers and the buffered body
+func (s *stream) commit() error {
+	s.committed = true
+	header := s.sink.header()
+
+	encoding, vary := s.compressor.Encoding(Request{
+		Method:          s.method,
+		AcceptEncoding:  s.acceptEncoding,
+		Status:          s.status,
+		ContentType:     header.Get("Content-Type"),
+		ContentEncoding: header.Get("Content-Encoding"),
+		Size:            s.buffer.Len(),
+	})
+	if vary {
+		header.Set("Vary", AddVary(header.Get("Vary")))
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+		header.Del("Content-Length")
+		if tag := header.Get("ETag"); tag != "" {
+			header.Set("ETag", EncodeETag(tag, encoding))
+		}
+	}
+
+	if s.status != 0 {
+		s.sink.writeHeader(s.status)
+	}
+
+	s.writer = s.sink.body()
+	if encoding != "" {
+		s.encoder = s.compressor.NewWriter(s.writer, encoding)
+		s.writer = s.encoder
+	}
+	if s.buffer.Len() > 0 {
+		if _, err := s.writer.Write(s.buffer.Bytes()); err != nil {
+			return fmt.Errorf("compress: failed to write response: %w", err)
+		}
+	}
+	s.buffer = bytes.Buffer{}
+	return nil
+}
+
+// ResponseWriter compresses a net/http response negotiated from the request's Accept-Encoding.
+// Close must be called once the handler returns
+type ResponseWriter struct {
+	http.ResponseWriter
+	stream *stream
+}
+
+// NewResponseWriter wraps w to compress the response to r
+func (c *Compressor) NewResponseWriter(w http.ResponseWriter, r *http.Request) *ResponseWriter {
+	rw := &ResponseWriter{ResponseWriter: w}
+	rw.stream = &stream{
+		compressor:     c,
+		sink:           httpSink{w},
+		method:         r.Method,
+		acceptEncoding: r.Header.Get("Accept-Encoding"),
+	}
+	return rw
+}
+
+// Handler wraps next so its responses are compressed. Protocol upgrades such as
+// WebSockets are passed through untouched
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rw := c.NewResponseWriter(w, r)
+		defer rw.Close()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// WriteHeader records the status; it is sent once the encoding has been decided
+func (w *ResponseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		// Informational responses such as 103 Early Hints go out immediately
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.stream.setStatus(status)
+}
+
+// Write buffers or compresses the body
+func (w *ResponseWriter) Write(p []byte) (int, error) {
+	if w.stream.status == 0 {
+		w.stream.setStatus(http.StatusOK)
+	}
+	return w.stream.write(p)
+}
+
+// WriteString implements io.StringWriter
+func (w *ResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends everything written so far, including a partial compressed block
+func (w *ResponseWriter) Flush() {
+	_ = w.stream.flush()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets protocol upgrades take over the connection when the underlying writer supports it
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("compress: %T does not support hijacking", w.ResponseWriter)
+	}
+	w.stream.committed = true
+	w.stream.writer = io.Discard
+	return hijacker.Hijack()
+}
+
+// Commit sends the status and headers now, deciding on the encoding from what has been written so far
+func (w *ResponseWriter) Commit() error {
+	if w.stream.committed {
+		return nil
+	}
+	if w.stream.status == 0 {
+		w.stream.setStatus(http.StatusOK)
+	}
+	return w.stream.commit()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close writes any buffered data and terminates the compressed stream
+func (w *ResponseWriter) Close() error {
+	return w.stream.close()
+}
+
+type httpSink struct {
+	w http.ResponseWriter
+}
+
+func (s httpSink) header() http.Header    { return s.w.Header() }
+func (s httpSink) writeHeader(status int) { s.w.WriteHeader(status) }
+func (s httpSink) body() io.Writer        { return s.w }
+
+// humaContext lets Context embed huma.Context without the field name clashing with its Context method
+type humaContext = huma.Context
+
+// Context compresses a response written through a huma.Context. Headers are held back
+// until the encoding has been decided. Close must be called once the handler returns
+type Context struct {
+	humaContext
+	stream  *stream
+	pending http.Header
+}
+
+// NewContext wraps ctx to compress its response
+func (c *Compressor) NewContext(ctx huma.Context) *Context {
+	wrapped := &Context{humaContext: ctx, pending: http.Header{}}
+	wrapped.stream = &stream{
+		compressor:     c,
+		sink:           humaSink{wrapped},
+		method:         ctx.Method(),
+		acceptEncoding: ctx.Header("Accept-Encoding"),
+	}
+	return wrapped
+}
+
+// SetStatus records the status; it is sent once the encoding has been decided
+func (c *Context) SetStatus(code int) {
+	if c.stream.committed {
+		c.humaContext.SetStatus(code)
+		return
+	}
+	c.stream.setStatus(code)
+}
+
+// Status returns the recorded status until it has been sent
+func (c *Context) Status() int {
+	if !c.stream.committed && c.stream.status != 0 {
+		return c.stream.status
+	}
+	return c.humaContext.Status()
+}
+
+// SetHeader sets a response header
+func (c *Context) SetHeader(name, value string) {
+	if c.stream.committed {
+		c.humaContext.SetHeader(name, value)
+		return
+	}
+	c.pending.Set(name, value)
+}
+
+// AppendHeader appends a response header
+func (c *Context) AppendHeader(name, value string) {
+	if c.stream.committed {
+		c.humaContext.AppendHeader(name, value)
+		return
+	}
+	c.pending.Add(name, value)
+}
+
+// BodyWriter returns a writer that buffers or compresses the body
+func (c *Context) BodyWriter() io.Writer {
+	return contextWriter{c}
+}
+
+// Unwrap returns the wrapped context, e.g. for connection upgrades that bypass compression
+func (c *Context) Unwrap() huma.Context {
+	return c.humaContext
+}
+
+// Close writes any buffered data and terminates the compressed stream
+func (c *Context) Close() error {
+	if !c.stream.committed && c.stream.status == 0 && c.stream.buffer.Len() == 0 {
+		// Nothing was written, but headers set by the handler must not be lost
+		humaSink{c}.flushHeaders()
+		return nil
+	}
+	return c.stream.close()
+}
+
+type contextWriter struct {
+	context *Context
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	return w.context.stream.write(p)
+}
+
+// Flush lets streaming handlers push partial responses to the client
+func (w contextWriter) Flush() {
+	_ = w.context.stream.flush()
+	if flusher, ok := w.context.humaContext.BodyWriter().(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type humaSink struct {
+	context *Context
+}
+
+func (s humaSink) header() http.Header {
+	return s.context.pending
+}
+
+func (s humaSink) writeHeader(status int) {
+	s.flushHeaders()
+	s.context.humaContext.SetStatus(status)
+}
+
+func (s humaSink) body() io.Writer {
+	// Headers set without an explicit status still have to go out before the body
+	s.flushHeaders()
+	return s.context.humaContext.BodyWriter()
+}
+
+func (s humaSink) flushHeaders() {
+	for name, values := range s.context.pending {
+		for i, value := range values {
+			if i == 0 {
+				s.context.humaContext.SetHeader(name, value)
+			} else {
+				s.context.humaContext.AppendHeader(name, value)
+			}
+		}
+	}
+	s.context.pending = http.Header{}
+}
@@ -0,0 +1,342 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/barisgit/goflux/config"
+)
+
+// Options configures cross-origin resource sharing behavior
+type Options struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin requests.
+	// Supports "*" (any origin) and subdomain wildcards like "https://*.example.com"
+	AllowedOrigins []string
+	// AllowedMethods lists the methods returned in preflight responses
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers returned in preflight responses.
+	// A single "*" reflects whatever the browser asks for
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers the browser is allowed to read
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and Authorization headers on cross-origin requests
+	AllowCredentials bool
+	// MaxAge is the preflight cache duration in seconds (0 omits the header)
+	MaxAge int
+}
+
+// Request contains the parts of an incoming request relevant to CORS, router-agnostic
+type Request struct {
+	Method                      string
+	Path                        string
+	Origin                      string
+	AccessControlRequestMethod  string
+	AccessControlRequestHeaders string
+}
+
+// Response contains the result of CORS processing
+type Response struct {
+	// Headers to set on the response
+	Headers map[string]string
+	// Preflight is true when the request was a preflight and must be answered without calling the handler
+	Preflight bool
+	// StatusCode is the status to use for preflight responses
+	StatusCode int
+	// Allowed is false when the origin (or preflight method) was rejected
+	Allowed bool
+}
+
+// ErrWildcardCredentials is returned when any origin is allowed together with credentials
+var ErrWildcardCredentials = errors.New("cors: wildcard origin '*' cannot be combined with AllowCredentials, list the origins explicitly")
+
+// Policy evaluates CORS requests against a set of options
+type Policy struct {
+	options        Options
+	allowAnyOrigin bool
+	exactOrigins   map[string]bool
+	wildcards      []wildcardOrigin
+	allowAnyHeader bool
+	allowedHeaders map[string]bool
+	allowedMethods map[string]bool
+
+	overridesMu sync.RWMutex
+	overrides   []routeOverride
+}
+
+type wildcardOrigin struct {
+	prefix string // "https://"
+	suffix string // ".example.com"
+}
+
+// DefaultOptions returns permissive defaults suitable for local development
+func DefaultOptions() Options {
+	return Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         600,
+	}
+}
+
+// FromConfig converts the cors section of flux.yaml into policy options
+func FromConfig(cfg config.CORSConfig) Options {
+	opts := Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+
+	// Fill in the same defaults the config manager applies
+	defaults := DefaultOptions()
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = defaults.AllowedMethods
+	}
+	if len(opts.AllowedHeaders) == 0 {
+		opts.AllowedHeaders = defaults.AllowedHeaders
+	}
+
+	return opts
+}
+
+// New creates a new policy from the given options.
+// Allowing any origin with credentials would reflect every origin with cookies attached, so it is rejected
+func New(opts Options) (*Policy, error) {
+	p := &Policy{
+		options:        opts,
+		exactOrigins:   make(map[string]bool),
+		allowedHeaders: make(map[string]bool),
+		allowedMethods: make(map[string]bool),
+	}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			p.allowAnyOrigin = true
+		case strings.Contains(origin, "://*."):
+			parts := strings.SplitN(origin, "*", 2)
+			p.wildcards = append(p.wildcards, wildcardOrigin{prefix: parts[0], suffix: parts[1]})
+		default:
+			p.exactOrigins[origin] = true
+		}
+	}
+
+	for _, header := range opts.AllowedHeaders {
+		if header == "*" {
+			p.allowAnyHeader = true
+			continue
+		}
+		p.allowedHeaders[strings.ToLower(header)] = true
+	}
+
+	for _, method := range opts.AllowedMethods {
+		p.allowedMethods[strings.ToUpper(method)] = true
+	}
+
+	if p.allowAnyOrigin && opts.AllowCredentials {
+		return nil, ErrWildcardCredentials
+	}
+
+	return p, nil
+}
+
+// Options returns the options the policy was created with
+func (p *Policy) Options() Options {
+	return p.options
+}
+
+// IsOriginAllowed checks whether the given origin may access the API
+func (p *Policy) IsOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.allowAnyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.exactOrigins[origin] {
+		return true
+	}
+
+	for _, w := range p.wildcards {
+		if strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) &&
+			len(origin) > len(w.prefix)+len(w.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Evaluate processes a request and returns the headers to apply.
+// Route overrides registered with Override take precedence over the policy itself
+func (p *Policy) Evaluate(req Request) Response {
+	method := req.Method
+	preflight := req.Method == http.MethodOptions && req.AccessControlRequestMethod != ""
+	if preflight {
+		method = req.AccessControlRequestMethod
+	}
+
+	if override := p.lookupOverride(method, req.Path); override != nil {
+		return override.evaluate(req, preflight)
+	}
+
+	return p.evaluate(req, preflight)
+}
+
+// evaluate applies this policy without consulting route overrides
+func (p *Policy) evaluate(req Request, preflight bool) Response {
+	resp := Response{
+		Headers:    map[string]string{"Vary": "Origin"},
+		Preflight:  preflight,
+		StatusCode: http.StatusNoContent,
+	}
+
+	// Not a cross-origin request, nothing to do
+	if req.Origin == "" {
+		resp.Preflight = false
+		resp.Allowed = true
+		return resp
+	}
+
+	if !p.IsOriginAllowed(req.Origin) {
+		resp.StatusCode = http.StatusForbidden
+		return resp
+	}
+
+	if !preflight {
+		p.setOriginHeaders(resp.Headers, req.Origin)
+		if len(p.options.ExposedHeaders) > 0 {
+			resp.Headers["Access-Control-Expose-Headers"] = strings.Join(p.options.ExposedHeaders, ", ")
+		}
+		resp.Allowed = true
+		return resp
+	}
+
+	// Preflight: check the requested method and headers before granting anything
+	resp.Headers["Vary"] = "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+
+	requestedMethod := strings.ToUpper(req.AccessControlRequestMethod)
+	if !p.allowedMethods[requestedMethod] {
+		resp.StatusCode = http.StatusForbidden
+		return resp
+	}
+
+	allowedHeaders := p.filterHeaders(req.AccessControlRequestHeaders)
+	if allowedHeaders == nil {
+		resp.StatusCode = http.StatusForbidden
+		return resp
+	}
+
+	p.setOriginHeaders(resp.Headers, req.Origin)
+	resp.Headers["Access-Control-Allow-Methods"] = strings.Join(p.options.AllowedMethods, ", ")
+	if len(allowedHeaders) > 0 {
+		resp.Headers["Access-Control-Allow-Headers"] = strings.Join(allowedHeaders, ", ")
+	}
+	if p.options.MaxAge > 0 {
+		resp.Headers["Access-Control-Max-Age"] = strconv.Itoa(p.options.MaxAge)
+	}
+
+	resp.Allowed = true
+	return resp
+}
+
+// setOriginHeaders sets the allow-origin and credentials headers for an accepted origin
+func (p *Policy) setOriginHeaders(headers map[string]string, origin string) {
+	// Reflect the request origin unless any origin is allowed (New rules out credentials then)
+	if p.allowAnyOrigin {
+		headers["Access-Control-Allow-Origin"] = "*"
+	} else {
+		headers["Access-Control-Allow-Origin"] = origin
+	}
+
+	if p.options.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+}
+
+// filterHeaders validates the requested headers, returning nil if any of them is not allowed
+func (p *Policy) filterHeaders(requested string) []string {
+	headers := []string{}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !p.allowAnyHeader && !p.allowedHeaders[strings.ToLower(header)] {
+			return nil
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// ============================================================================
+// ROUTE OVERRIDES
+// ============================================================================
+
+// routeOverride binds a policy to an operation's method and path template
+type routeOverride struct {
+	method   string
+	segments []string
+	policy   *Policy
+}
+
+// Override registers a policy for a single operation, e.g. ("GET", "/api/public/{id}").
+// The path must be the full path the router serves, including any group prefix
+func (p *Policy) Override(method, path string, policy *Policy) {
+	p.overridesMu.Lock()
+	defer p.overridesMu.Unlock()
+
+	p.overrides = append(p.overrides, routeOverride{
+		method:   strings.ToUpper(method),
+		segments: splitPath(path),
+		policy:   policy,
+	})
+}
+
+// lookupOverride finds the policy registered for a method and concrete request path
+func (p *Policy) lookupOverride(method, path string) *Policy {
+	p.overridesMu.RLock()
+	defer p.overridesMu.RUnlock()
+
+	if len(p.overrides) == 0 {
+		return nil
+	}
+
+	method = strings.ToUpper(method)
+	segments := splitPath(path)
+	for _, o := range p.overrides {
+		if o.method == method && matchSegments(o.segments, segments) {
+			return o.policy
+		}
+	}
+	return nil
+}
+
+// matchSegments matches a path template ({param} segments match anything) against a concrete path
+func matchSegments(template, path []string) bool {
+	if len(template) != len(path) {
+		return false
+	}
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+		if segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,427 @@
+package envconfig
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/joho/godotenv"
+)
+
+// Options configures Load
+type Options struct {
+	// Environment selects the .env files to read (default: $GO_ENV, then $APP_ENV, then "development")
+	Environment string
+	// Files are read in order, earlier files taking precedence over later ones. The default is
+	// .env.{environment}.local, .env.local (skipped in the test environment), .env.{environment} and .env.
+	// Missing files are skipped. Variables set in the process environment always win over files
+	Files []string
+	// Prefix is prepended to every variable name, e.g. "MYAPP_"
+	Prefix string
+	// Args are command-line arguments checked for the fields' `flag` tags, which take precedence
+	// over everything else. Arguments that match no flag are ignored, so os.Args[1:] can be passed
+	// as is
+	Args []string
+	// LookupEnv reads the process environment (default os.LookupEnv)
+	LookupEnv func(key string) (string, bool)
+}
+
+// Problem describes one field that could not be loaded
+type Problem struct {
+	Variable string
+	Doc      string
+	Message  string
+}
+
+// Error lists every problem found while loading, so all of them can be fixed at once
+type Error struct {
+	Type     string
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config: %d problem(s) loading %s:", len(e.Problems), e.Type)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s %s", p.Variable, p.Message)
+		if p.Doc != "" {
+			fmt.Fprintf(&b, " (%s)", p.Doc)
+		}
+	}
+	return b.String()
+}
+
+// field is a leaf of the config struct bound to a variable
+type field struct {
+	value    reflect.Value
+	variable string
+	flag     string
+	def      string
+	hasDef   bool
+	required bool
+	secret   bool
+	doc      string
+}
+
+// Load fills a T from flags, the environment, .env files and `default` tags, in that order of
+// precedence. Fields are configured with struct tags:
+//
+//	env:"DATABASE_URL"   variable name (default: the field name in SCREAMING_SNAKE_CASE)
+//	default:"3000"       value used when the variable is not set
+//	required:"true"      fail when the variable is not set and has no default
+//	secret:"true"        hide the value in Format
+//	flag:"port"          also read --port=value / --port value from Options.Args
+//	doc:"..."            shown next to problems
+//
+// Nested structs prefix their fields with their own variable name and an underscore; `env:""` on a
+// nested struct drops the prefix. Supported types are strings, booleans, numbers, time.Duration,
+// comma-separated slices of those and types implementing encoding.TextUnmarshaler
+func Load[T any](options Options) (*T, error) {
+	cfg := new(T)
+	if err := Fill(cfg, options); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Fill fills the struct pointed to by target, see Load
+func Fill(target any, options Options) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: target must be a pointer to a struct, got %T", target)
+	}
+	if options.LookupEnv == nil {
+		options.LookupEnv = os.LookupEnv
+	}
+
+	files, err := readFiles(options)
+	if err != nil {
+		return err
+	}
+	flags := parseArgs(options.Args)
+
+	fields, err := collect(rv.Elem(), options.Prefix)
+	if err != nil {
+		return err
+	}
+
+	configErr := &Error{Type: rv.Elem().Type().String()}
+	for _, f := range fields {
+		raw, found := "", false
+		if f.flag != "" {
+			raw, found = flags[f.flag]
+		}
+		if !found {
+			raw, found = options.LookupEnv(f.variable)
+		}
+		if !found {
+			raw, found = files[f.variable]
+		}
+		if !found && f.hasDef {
+			raw, found = f.def, true
+		}
+		if !found {
+			if f.required {
+				configErr.Problems = append(configErr.Problems, Problem{Variable: f.variable, Doc: f.doc, Message: "is required but not set"})
+			}
+			continue
+		}
+		if f.required && raw == "" {
+			configErr.Problems = append(configErr.Problems, Problem{Variable: f.variable, Doc: f.doc, Message: "is required but empty"})
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			configErr.Problems = append(configErr.Problems, Problem{Variable: f.variable, Doc: f.doc, Message: err.Error()})
+		}
+	}
+
+	if len(configErr.Problems) > 0 {
+		return configErr
+	}
+	return nil
+}
+
+// readFiles merges the .env files, earlier files winning
+func readFiles(options Options) (map[string]string, error) {
+	files := options.Files
+	if files == nil {
+		environment := Environment(options)
+		files = []string{".env." + environment + ".local"}
+		if environment != "test" {
+			files = append(files, ".env.local")
+		}
+		files = append(files, ".env."+environment, ".env")
+	}
+
+	merged := map[string]string{}
+	for _, name := range files {
+		values, err := godotenv.Read(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: failed to read %s: %w", name, err)
+		}
+		for key, value := range values {
+			if _, exists := merged[key]; !exists {
+				merged[key] = value
+			}
+		}
+	}
+	return merged, nil
+}
+
+// Environment returns the environment selected by options
+func Environment(options Options) string {
+	if options.Environment != "" {
+		return options.Environment
+	}
+	lookup := options.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	for _, key := range []string{"GO_ENV", "APP_ENV"} {
+		if value, ok := lookup(key); ok && value != "" {
+			return value
+		}
+	}
+	return "development"
+}
+
+// parseArgs extracts --name=value and --name value pairs; a flag without a value is "true"
+func parseArgs(args []string) map[string]string {
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if key, value, ok := strings.Cut(name, "="); ok {
+			flags[key] = value
+			continue
+		}
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			flags[name] = args[i+1]
+			i++
+		} else {
+			flags[name] = "true"
+		}
+	}
+	return flags
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// collect walks the struct and binds its leaf fields to variables
+func collect(v reflect.Value, prefix string) ([]field, error) {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, hasName := sf.Tag.Lookup("env")
+		if name == "-" {
+			continue
+		}
+		if !hasName {
+			name = screamingSnake(sf.Name)
+		}
+
+		fv := v.Field(i)
+		if isNested(sf.Type) {
+			nestedPrefix := prefix
+			if name != "" {
+				nestedPrefix += name + "_"
+			}
+			if sf.Type.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			nested, err := collect(fv, nestedPrefix)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("config: field %s has an empty env tag", sf.Name)
+		}
+
+		def, hasDef := sf.Tag.Lookup("default")
+		fields = append(fields, field{
+			value:    fv,
+			variable: prefix + name,
+			flag:     sf.Tag.Get("flag"),
+			def:      def,
+			hasDef:   hasDef,
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			doc:      sf.Tag.Get("doc"),
+		})
+	}
+	return fields, nil
+}
+
+// isNested reports whether a field is a struct of further variables rather than a value
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("is invalid: %w", err)
+		}
+		return nil
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("is not a valid duration: %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("is not a valid boolean: %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid integer: %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid unsigned integer: %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid number: %q", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Format lists the variables of a loaded config as NAME=value lines, sorted by name, with the
+// values of secret fields redacted. Use it to log the effective configuration at startup
+func Format(cfg any, options Options) string {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Sprint(cfg)
+	}
+	// Work on a copy so nil nested pointers are not allocated in cfg
+	clone := reflect.New(rv.Type()).Elem()
+	clone.Set(rv)
+	fields, err := collect(clone, options.Prefix)
+	if err != nil {
+		return err.Error()
+	}
+
+	lines := make([]string, 0, len(fields))
+	for _, f := range fields {
+		value := formatValue(f.value)
+		if f.secret && value != "" {
+			value = "******"
+		}
+		lines = append(lines, f.variable+"="+value)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// screamingSnake turns DatabaseURL into DATABASE_URL
+func screamingSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,323 @@
+package errcodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension is the root OpenAPI extension listing the error codes used by operations, so clients can
+// build a union of them
+const Extension = "x-goflux-errors"
+
+// OperationExtension lists the error codes an operation can respond with
+const OperationExtension = "x-goflux-error-codes"
+
+// Definition declares an application error code
+type Definition struct {
+	// Code identifies the error, e.g. "user_not_found"
+	Code string
+	// Status is the HTTP status of the error (4xx or 5xx)
+	Status int
+	// Message is the template of the error's detail. {name} placeholders are replaced with the JSON
+	// field of the same name of the details, e.g. "User {id} was not found"
+	Message string
+	// Details is the type of the error's details, nil for errors without details
+	Details reflect.Type
+}
+
+var codePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// Validate checks a definition
+func (d *Definition) Validate() error {
+	if !codePattern.MatchString(d.Code) {
+		return fmt.Errorf("errcodes: invalid error code %q, use lowercase letters, digits, '_', '.' and '-'", d.Code)
+	}
+	if d.Status < 400 || d.Status > 599 {
+		return fmt.Errorf("errcodes: status %d of error code %q is not an error status", d.Status, d.Code)
+	}
+	return nil
+}
+
+// New creates an error of the code with details, rendering the message template
+func (d *Definition) New(details any) *Error {
+	e := &Error{
+		Title:  http.StatusText(d.Status),
+		Status: d.Status,
+		Detail: Render(d.Message, details),
+		Code:   d.Code,
+	}
+	if d.Details != nil {
+		e.Details = details
+	}
+	return e
+}
+
+// SchemaName is the name of the component schema of the code's error body, e.g. UserNotFoundError
+func (d *Definition) SchemaName() string {
+	var b strings.Builder
+	upper := true
+	for _, r := range d.Code {
+		if r == '_' || r == '.' || r == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if !strings.HasSuffix(name, "Error") {
+		name += "Error"
+	}
+	return name
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// Render replaces the {name} placeholders of a message template with the JSON fields of details.
+// Unknown placeholders are kept as they are
+func Render(template string, details any) string {
+	if details == nil || !strings.Contains(template, "{") {
+		return template
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return template
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return template
+	}
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := fields[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		if s, ok := value.(string); ok {
+			return s
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	})
+}
+
+// Error is an error response with an application error code. It is written as an RFC 9457 problem
+// with the code and details next to the standard fields
+type Error struct {
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail"`
+	Code    string `json:"code"`
+	Details any    `json:"details,omitempty"`
+
+	cause error
+}
+
+// Error returns the rendered message
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// GetStatus returns the HTTP status of the error
+func (e *Error) GetStatus() int {
+	return e.Status
+}
+
+// ContentType sends errors as application/problem+json, like huma's error model
+func (e *Error) ContentType(ct string) string {
+	if ct == "application/json" {
+		return "application/problem+json"
+	}
+	if ct == "application/cbor" {
+		return "application/problem+cbor"
+	}
+	return ct
+}
+
+// Unwrap returns the cause attached with WithCause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithCause returns a copy of the error wrapping cause, which is logged and matched by errors.Is but
+// never sent to clients
+func (e *Error) WithCause(cause error) *Error {
+	wrapped := *e
+	wrapped.cause = cause
+	return &wrapped
+}
+
+// Registry holds the error codes of a service. Codes are unique, so clients can rely on them
+type Registry struct {
+	mu    sync.RWMutex
+	codes map[string]*Definition
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{codes: map[string]*Definition{}}
+}
+
+// Default is the registry used by the goflux package
+var Default = NewRegistry()
+
+// Define adds a code to the registry
+func (r *Registry) Define(definition *Definition) error {
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.codes[definition.Code]; exists {
+		return fmt.Errorf("errcodes: error code %q is defined twice", definition.Code)
+	}
+	r.codes[definition.Code] = definition
+	return nil
+}
+
+// Get returns the definition of a code
+func (r *Registry) Get(code string) (*Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.codes[code]
+	return definition, ok
+}
+
+// SpecCode is an error code as listed in the root extension
+type SpecCode struct {
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Schema  string `json:"schema"`
+}
+
+// Annotate documents the error codes of an operation: each status gets a response whose schema is
+// one of the codes' bodies (plus the generic error model if the status was already documented), and
+// the codes are listed in the operation and root extensions
+func Annotate(operation *huma.Operation, oapi *huma.OpenAPI, definitions []*Definition) {
+	if len(definitions) == 0 {
+		return
+	}
+	registry := oapi.Components.Schemas
+
+	byStatus := map[int][]*Definition{}
+	codes := make([]string, 0, len(definitions))
+	for _, definition := range definitions {
+		group := byStatus[definition.Status]
+		if !slices.ContainsFunc(group, func(d *Definition) bool { return d.Code == definition.Code }) {
+			byStatus[definition.Status] = append(byStatus[definition.Status], definition)
+			codes = append(codes, definition.Code)
+		}
+		registerSchema(registry, definition)
+	}
+
+	if operation.Responses == nil {
+		operation.Responses = map[string]*huma.Response{}
+	}
+	for status, group := range byStatus {
+		key := strconv.Itoa(status)
+		var schemas []*huma.Schema
+		mapping := map[string]string{}
+		descriptions := make([]string, len(group))
+		for i, definition := range group {
+			ref := "#/components/schemas/" + definition.SchemaName()
+			schemas = append(schemas, &huma.Schema{Ref: ref})
+			mapping[definition.Code] = ref
+			descriptions[i] = fmt.Sprintf("`%s`: %s", definition.Code, definition.Message)
+		}
+
+		// Keep the generic error of statuses goflux or huma already documented, e.g. 400 and 422
+		discriminated := true
+		if existing, ok := operation.Responses[key]; ok {
+			for _, media := range existing.Content {
+				if media.Schema != nil {
+					schemas = append(schemas, media.Schema)
+					discriminated = false
+					break
+				}
+			}
+		}
+		operation.Errors = slices.DeleteFunc(slices.Clone(operation.Errors), func(s int) bool { return s == status })
+
+		// Always a oneOf, also for a single code: the code schemas have no Go type, which huma's schema
+		// link transformer expects behind a response $ref
+		schema := &huma.Schema{OneOf: schemas}
+		if discriminated {
+			schema.Discriminator = &huma.Discriminator{PropertyName: "code", Mapping: mapping}
+		}
+		description := http.StatusText(status)
+		if description == "" {
+			description = "Error"
+		}
+		operation.Responses[key] = &huma.Response{
+			Description: description + "\n\n" + strings.Join(descriptions, "\n\n"),
+			Content: map[string]*huma.MediaType{
+				"application/problem+json": {Schema: schema},
+			},
+		}
+	}
+
+	sort.Strings(codes)
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[OperationExtension] = codes
+
+	if oapi.Extensions == nil {
+		oapi.Extensions = map[string]any{}
+	}
+	listed, _ := oapi.Extensions[Extension].([]SpecCode)
+	for _, definition := range definitions {
+		if !slices.ContainsFunc(listed, func(entry SpecCode) bool { return entry.Code == definition.Code }) {
+			listed = append(listed, SpecCode{
+				Code:    definition.Code,
+				Status:  definition.Status,
+				Message: definition.Message,
+				Schema:  definition.SchemaName(),
+			})
+		}
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].Code < listed[j].Code
+	})
+	oapi.Extensions[Extension] = listed
+}
+
+// registerSchema adds the component schema of a code's error body
+func registerSchema(registry huma.Registry, definition *Definition) {
+	schemas := registry.Map()
+	name := definition.SchemaName()
+	if _, exists := schemas[name]; exists {
+		return
+	}
+	properties := map[string]*huma.Schema{
+		"title":  {Type: huma.TypeString, Description: "Short summary of the status"},
+		"status": {Type: huma.TypeInteger, Enum: []any{definition.Status}, Description: "HTTP status code"},
+		"detail": {Type: huma.TypeString, Description: "Explanation of this occurrence of the error"},
+		"code":   {Type: huma.TypeString, Enum: []any{definition.Code}, Description: "Application error code"},
+	}
+	required := []string{"title", "status", "detail", "code"}
+	if definition.Details != nil {
+		properties["details"] = registry.Schema(definition.Details, true, name+"Details")
+		required = append(required, "details")
+	}
+	schemas[name] = &huma.Schema{
+		Type:        huma.TypeObject,
+		Description: definition.Message,
+		Properties:  properties,
+		Required:    required,
+	}
+}
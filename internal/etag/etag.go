@@ -0,0 +1,223 @@
+package etag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Options configures automatic ETags
+type Options struct {
+	// Weak marks generated ETags as weak validators (W/"..."). If-Match then compares weakly
+	Weak bool
+}
+
+// Tag values for output fields that provide validators:
+//
+//	Version   int       `json:"version" etag:"version"`    // becomes the ETag
+//	UpdatedAt time.Time `json:"updatedAt" etag:"modified"` // becomes Last-Modified
+const (
+	TagName     = "etag"
+	TagVersion  = "version"
+	TagModified = "modified"
+)
+
+// Compute returns an ETag derived from a hash of the response body
+func Compute(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	return format(base64.RawURLEncoding.EncodeToString(sum[:16]), weak)
+}
+
+// FromVersion returns the ETag for a version value, e.g. `"3"` for version 3
+func FromVersion(version any, weak bool) string {
+	return format(fmt.Sprint(version), weak)
+}
+
+func format(opaque string, weak bool) string {
+	opaque = strings.ReplaceAll(opaque, `"`, "")
+	opaque = strings.ReplaceAll(opaque, ";", "") // Reserved for the content encoding of compressed variants
+	if weak {
+		return `W/"` + opaque + `"`
+	}
+	return `"` + opaque + `"`
+}
+
+// opaque strips the weakness indicator, quotes and the content encoding suffix of a compressed
+// variant (e.g. "abc;gzip") from an ETag
+func opaque(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimPrefix(tag, "W/")
+	tag = strings.Trim(tag, `"`)
+	tag, _, _ = strings.Cut(tag, ";")
+	return tag
+}
+
+// Matches reports whether a comma separated If-None-Match header value matches tag using the
+// weak comparison of RFC 9110: the weakness indicator is ignored, and "*" matches any existing resource
+func Matches(header, tag string) bool {
+	return match(header, tag, false)
+}
+
+// MatchesStrong reports whether a comma separated If-Match header value matches tag using the
+// strong comparison of RFC 9110: weak tags never match, and "*" matches any existing resource
+func MatchesStrong(header, tag string) bool {
+	return match(header, tag, true)
+}
+
+func match(header, tag string, strong bool) bool {
+	if tag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if candidate == "" || (strong && (isWeak(candidate) || isWeak(tag))) {
+			continue
+		}
+		if opaque(candidate) == opaque(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWeak reports whether an ETag carries the weakness indicator
+func isWeak(tag string) bool {
+	return strings.HasPrefix(strings.TrimSpace(tag), "W/")
+}
+
+// NotModified evaluates If-None-Match and If-Modified-Since for a GET or HEAD request.
+// If-Modified-Since is only considered when If-None-Match is absent
+func NotModified(ifNoneMatch, ifModifiedSince, tag string, modified time.Time) bool {
+	if ifNoneMatch != "" {
+		return Matches(ifNoneMatch, tag)
+	}
+	if ifModifiedSince != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// Validators locates the version and modification time declared on an output struct,
+// looking at its top-level fields and the fields of its Body struct
+func Validators(output reflect.Value) (version reflect.Value, modified time.Time) {
+	scan := func(value reflect.Value) {
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			switch field.Tag.Get(TagName) {
+			case TagVersion:
+				version = value.Field(i)
+			case TagModified:
+				if t, ok := value.Field(i).Interface().(time.Time); ok {
+					modified = t
+				}
+			}
+		}
+	}
+
+	scan(output)
+	if output.Kind() == reflect.Struct {
+		if body := output.FieldByName("Body"); body.IsValid() {
+			scan(body)
+		}
+	}
+	return version, modified
+}
+
+// Preconditions holds the conditional headers of a write request
+type Preconditions struct {
+	IfMatch           string
+	IfNoneMatch       string
+	IfUnmodifiedSince string
+}
+
+// IsEmpty reports whether the request sent no conditional headers
+func (p Preconditions) IsEmpty() bool {
+	return p.IfMatch == "" && p.IfNoneMatch == "" && p.IfUnmodifiedSince == ""
+}
+
+// Check compares the preconditions with the current state of the resource.
+// version is nil when the resource does not exist. If-Match is compared strongly, or weakly when
+// weak tags are configured so clients can send back the tags they received.
+// A failed precondition returns 412 Precondition Failed
+func (p Preconditions) Check(version any, modified time.Time, weak bool) error {
+	current := ""
+	if version != nil {
+		current = FromVersion(version, weak)
+	}
+
+	ifMatch := MatchesStrong
+	if weak {
+		ifMatch = Matches
+	}
+	if p.IfMatch != "" && !ifMatch(p.IfMatch, current) {
+		return huma.Error412PreconditionFailed("If-Match precondition failed", &huma.ErrorDetail{
+			Message:  "resource has ETag " + current,
+			Location: "headers.If-Match",
+			Value:    p.IfMatch,
+		})
+	}
+	if p.IfNoneMatch != "" && Matches(p.IfNoneMatch, current) {
+		return huma.Error412PreconditionFailed("If-None-Match precondition failed", &huma.ErrorDetail{
+			Message:  "resource already exists with ETag " + current,
+			Location: "headers.If-None-Match",
+			Value:    p.IfNoneMatch,
+		})
+	}
+	if p.IfUnmodifiedSince != "" && !modified.IsZero() {
+		since, err := http.ParseTime(p.IfUnmodifiedSince)
+		if err == nil && modified.Truncate(time.Second).After(since) {
+			return huma.Error412PreconditionFailed("If-Unmodified-Since precondition failed", &huma.ErrorDetail{
+				Message:  "resource was modified at " + modified.UTC().Format(http.TimeFormat),
+				Location: "headers.If-Unmodified-Since",
+				Value:    p.IfUnmodifiedSince,
+			})
+		}
+	}
+	return nil
+}
+
+type preconditionsKey struct{}
+
+// conditionalRequest is stored in the context so handlers can check preconditions
+type conditionalRequest struct {
+	preconditions Preconditions
+	weak          bool
+}
+
+// WithPreconditions returns a copy of ctx carrying the request's conditional headers
+func WithPreconditions(ctx context.Context, preconditions Preconditions, weak bool) context.Context {
+	return context.WithValue(ctx, preconditionsKey{}, conditionalRequest{preconditions: preconditions, weak: weak})
+}
+
+// CheckPreconditions checks the conditional headers stored in ctx against the current
+// version and modification time of a resource (see Preconditions.Check)
+func CheckPreconditions(ctx context.Context, version any, modified time.Time) error {
+	request, ok := ctx.Value(preconditionsKey{}).(conditionalRequest)
+	if !ok {
+		return nil
+	}
+	return request.preconditions.Check(version, modified, request.weak)
+}
@@ -0,0 +1,54 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestPreconditionsCheck(t *testing.T) {
+	modified := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		preconditions Preconditions
+		version       any
+		weak          bool
+		fails         bool
+	}{
+		{"no preconditions", Preconditions{}, 3, false, false},
+		{"If-Match current", Preconditions{IfMatch: `"3"`}, 3, false, false},
+		{"If-Match stale", Preconditions{IfMatch: `"2"`}, 3, false, true},
+		{"If-Match one of several", Preconditions{IfMatch: `"2", "3"`}, 3, false, false},
+		{"If-Match weak tag against strong tags", Preconditions{IfMatch: `W/"3"`}, 3, false, true},
+		{"If-Match compressed variant", Preconditions{IfMatch: `"3;gzip"`}, 3, false, false},
+		{"If-Match any", Preconditions{IfMatch: "*"}, 3, false, false},
+		{"If-Match any on missing resource", Preconditions{IfMatch: "*"}, nil, false, true},
+		{"weak If-Match echoed", Preconditions{IfMatch: `W/"3"`}, 3, true, false},
+		{"weak If-Match compressed variant", Preconditions{IfMatch: `W/"3;br"`}, 3, true, false},
+		{"weak If-Match stale", Preconditions{IfMatch: `W/"2"`}, 3, true, true},
+		{"If-None-Match any on missing resource", Preconditions{IfNoneMatch: "*"}, nil, false, false},
+		{"If-None-Match any on existing resource", Preconditions{IfNoneMatch: "*"}, 3, false, true},
+		{"If-None-Match weak against current", Preconditions{IfNoneMatch: `W/"3"`}, 3, false, true},
+		{"If-Unmodified-Since before change", Preconditions{IfUnmodifiedSince: modified.Add(-time.Hour).Format(http.TimeFormat)}, 3, false, true},
+		{"If-Unmodified-Since after change", Preconditions{IfUnmodifiedSince: modified.Format(http.TimeFormat)}, 3, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.preconditions.Check(tt.version, modified, tt.weak)
+			if !tt.fails {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			var se huma.StatusError
+			if !errors.As(err, &se) || se.GetStatus() != http.StatusPreconditionFailed {
+				t.Fatalf("Check() error = %v, want 412", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/barisgit/goflux/internal/lifecycle"
 	"github.com/danielgtaylor/huma/v2"
 )
 
@@ -17,6 +18,7 @@ type HealthResponse struct {
 }
 
 // AddHealthCheck adds a standard health check endpoint to a Huma API
+// It answers 503 once the server has started shutting down
 func AddHealthCheck(api huma.API, path string, serviceName string, version string) {
 	if path == "" {
 		path = "/api/health"
@@ -30,6 +32,10 @@ func AddHealthCheck(api huma.API, path string, serviceName string, version strin
 		Description: "Check if the service is running and healthy",
 		Tags:        []string{"Health"},
 	}, func(ctx context.Context, input *struct{}) (*HealthResponse, error) {
+		if !lifecycle.Ready() {
+			return nil, huma.Error503ServiceUnavailable("Service is shutting down")
+		}
+
 		resp := &HealthResponse{}
 		resp.Body.Status = "ok"
 
@@ -46,6 +52,7 @@ func AddHealthCheck(api huma.API, path string, serviceName string, version strin
 }
 
 // CustomHealthCheck allows users to provide their own health check logic
+// Like AddHealthCheck it answers 503 without calling healthFunc once the server is shutting down
 func CustomHealthCheck(api huma.API, path string, healthFunc func(ctx context.Context) (*HealthResponse, error)) {
 	if path == "" {
 		path = "/api/health"
@@ -59,6 +66,9 @@ func CustomHealthCheck(api huma.API, path string, healthFunc func(ctx context.Co
 		Description: "Check if the service is running and healthy",
 		Tags:        []string{"Health"},
 	}, func(ctx context.Context, input *struct{}) (*HealthResponse, error) {
+		if !lifecycle.Ready() {
+			return nil, huma.Error503ServiceUnavailable("Service is shutting down")
+		}
 		return healthFunc(ctx)
 	})
 }
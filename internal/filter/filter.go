@@ -0,0 +1,578 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension documents the filterable and sortable fields of an operation
+const Extension = "x-goflux-filters"
+
+// Op is a filter operator
+type Op string
+
+// Filter operators
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpIn       Op = "in"
+	OpContains Op = "contains"
+	OpPrefix   Op = "prefix"
+	OpNull     Op = "null"
+)
+
+// Value kinds, as documented for clients
+const (
+	KindString  = "string"
+	KindNumber  = "number"
+	KindInteger = "integer"
+	KindBoolean = "boolean"
+	KindDate    = "date"
+)
+
+var defaultOps = map[string][]Op{
+	KindString:  {OpEq, OpNe, OpIn, OpContains, OpPrefix},
+	KindNumber:  {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn},
+	KindInteger: {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn},
+	KindBoolean: {OpEq},
+	KindDate:    {OpEq, OpGt, OpGte, OpLt, OpLte},
+}
+
+var validOps = []Op{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpContains, OpPrefix, OpNull}
+
+// Field is a whitelisted field
+type Field struct {
+	// Name is the field name used in query parameters
+	Name string `json:"name"`
+	// Column is the SQL expression the field maps to (default: Name)
+	Column string `json:"-"`
+	// Kind is the kind of the values
+	Kind string `json:"type"`
+	// Ops are the operators allowed on the field, empty when it can only be sorted
+	Ops []Op `json:"ops,omitempty"`
+	// Sortable fields can be used in the sort parameter
+	Sortable bool `json:"sortable,omitempty"`
+
+	goType reflect.Type
+}
+
+// Schema is the whitelist of a filter struct
+type Schema struct {
+	// Name is the name of the struct, used for the client's filter types
+	Name   string  `json:"name"`
+	Fields []Field `json:"fields"`
+	// DefaultSort applies when the request has no sort parameter
+	DefaultSort []SortKey `json:"-"`
+	// MaxConditions limits the number of filters of a request
+	MaxConditions int `json:"-"`
+}
+
+// Options configures a schema
+type Options struct {
+	// DefaultSort applies when the request has no sort parameter, e.g. "-created_at,id"
+	DefaultSort string
+	// MaxConditions limits the number of filters of a request (default 20)
+	MaxConditions int
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[interface{ UnmarshalText([]byte) error }]()
+)
+
+// Compile builds the schema of a filter struct. Every exported field is whitelisted:
+//
+//	filter:"created_at"   name in query parameters (default: the json name or snake_case field name), "-" skips the field
+//	column:"u.created_at" SQL expression (default: the name)
+//	ops:"eq,gt,lt"        allowed operators (default: by type), "-" for sort-only fields
+//	sort:"true"           allow sorting by the field
+func Compile(t reflect.Type, options Options) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("filter: %s is not a struct", t)
+	}
+
+	schema := &Schema{Name: t.Name(), MaxConditions: options.MaxConditions}
+	if schema.MaxConditions <= 0 {
+		schema.MaxConditions = 20
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := sf.Tag.Get("filter")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+		}
+		if name == "" || name == "-" {
+			name = snakeCase(sf.Name)
+		}
+
+		kind, err := kindOf(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("filter: field %s.%s: %w", t.Name(), sf.Name, err)
+		}
+		field := Field{
+			Name:     name,
+			Column:   sf.Tag.Get("column"),
+			Kind:     kind,
+			Ops:      defaultOps[kind],
+			Sortable: sf.Tag.Get("sort") == "true",
+			goType:   sf.Type,
+		}
+		if field.Column == "" {
+			field.Column = name
+		}
+		if ops, ok := sf.Tag.Lookup("ops"); ok {
+			field.Ops = nil
+			if ops != "-" {
+				for _, op := range strings.Split(ops, ",") {
+					op := Op(strings.TrimSpace(op))
+					if !slices.Contains(validOps, op) {
+						return nil, fmt.Errorf("filter: field %s.%s has unknown operator %q", t.Name(), sf.Name, op)
+					}
+					field.Ops = append(field.Ops, op)
+				}
+			}
+		}
+		if slices.ContainsFunc(schema.Fields, func(f Field) bool { return f.Name == name }) {
+			return nil, fmt.Errorf("filter: field name %q is used twice in %s", name, t.Name())
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+
+	if options.DefaultSort != "" {
+		keys, problems := schema.parseSort(options.DefaultSort)
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("filter: invalid default sort of %s: %s", t.Name(), problems[0].Message)
+		}
+		schema.DefaultSort = keys
+	}
+	return schema, nil
+}
+
+func kindOf(t reflect.Type) (string, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return KindDate, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return KindString, nil
+	case reflect.Bool:
+		return KindBoolean, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindInteger, nil
+	case reflect.Float32, reflect.Float64:
+		return KindNumber, nil
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return KindString, nil
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+func (s *Schema) field(name string) (*Field, bool) {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Condition is one filter of a request. Value holds the parsed value, typed like the struct field
+// (time.Time for dates); Values holds the values of the in operator
+type Condition struct {
+	Field  string
+	Column string
+	Op     Op
+	Value  any
+	Values []any
+}
+
+// SortKey is one key of the sort order
+type SortKey struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Query is the parsed filters and sort order of a list request. Conditions are combined with AND
+type Query struct {
+	Conditions []Condition
+	Sort       []SortKey
+}
+
+// Parse reads filter[field][op]=value (filter[field]=value means eq) and sort=-created_at,name from
+// the query parameters. All invalid parameters are reported at once with a 422 error
+func (s *Schema) Parse(values url.Values) (*Query, error) {
+	query := &Query{}
+	var problems []*huma.ErrorDetail
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		location := "query." + key
+		name, op, ok := parseKey(key)
+		if !ok {
+			problems = append(problems, &huma.ErrorDetail{Location: location, Message: "expected filter[field] or filter[field][operator]"})
+			continue
+		}
+		field, ok := s.field(name)
+		if !ok || len(field.Ops) == 0 {
+			problems = append(problems, &huma.ErrorDetail{Location: location, Message: fmt.Sprintf("cannot filter by %q", name), Value: name})
+			continue
+		}
+		if !slices.Contains(field.Ops, op) {
+			problems = append(problems, &huma.ErrorDetail{Location: location, Message: fmt.Sprintf("operator %q is not allowed on %s, expected one of %s", op, name, joinOps(field.Ops)), Value: string(op)})
+			continue
+		}
+
+		for _, raw := range values[key] {
+			condition := Condition{Field: field.Name, Column: field.Column, Op: op}
+			var err error
+			switch op {
+			case OpIn:
+				for _, part := range strings.Split(raw, ",") {
+					var value any
+					if value, err = field.parse(strings.TrimSpace(part)); err != nil {
+						break
+					}
+					condition.Values = append(condition.Values, value)
+				}
+			case OpNull:
+				condition.Value, err = strconv.ParseBool(raw)
+				if err != nil {
+					err = fmt.Errorf("expected true or false")
+				}
+			default:
+				condition.Value, err = field.parse(raw)
+			}
+			if err != nil {
+				problems = append(problems, &huma.ErrorDetail{Location: location, Message: err.Error(), Value: raw})
+				continue
+			}
+			query.Conditions = append(query.Conditions, condition)
+		}
+	}
+	if len(query.Conditions) > s.MaxConditions {
+		problems = append(problems, &huma.ErrorDetail{Location: "query.filter", Message: fmt.Sprintf("at most %d filters are allowed", s.MaxConditions)})
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		keys, sortProblems := s.parseSort(raw)
+		problems = append(problems, sortProblems...)
+		query.Sort = keys
+	} else {
+		query.Sort = append([]SortKey{}, s.DefaultSort...)
+	}
+
+	if len(problems) > 0 {
+		errs := make([]error, len(problems))
+		for i, problem := range problems {
+			errs[i] = problem
+		}
+		return nil, huma.Error422UnprocessableEntity("Invalid filter or sort parameters", errs...)
+	}
+	return query, nil
+}
+
+func (s *Schema) parseSort(raw string) ([]SortKey, []*huma.ErrorDetail) {
+	var keys []SortKey
+	var problems []*huma.ErrorDetail
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Desc = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+		field, ok := s.field(part)
+		if !ok || !field.Sortable {
+			problems = append(problems, &huma.ErrorDetail{Location: "query.sort", Message: fmt.Sprintf("cannot sort by %q", part), Value: raw})
+			continue
+		}
+		if slices.ContainsFunc(keys, func(k SortKey) bool { return k.Field == field.Name }) {
+			problems = append(problems, &huma.ErrorDetail{Location: "query.sort", Message: fmt.Sprintf("%q is sorted by twice", part), Value: raw})
+			continue
+		}
+		key.Field = field.Name
+		key.Column = field.Column
+		keys = append(keys, key)
+	}
+	return keys, problems
+}
+
+// parseKey splits filter[name][op] and filter[name]
+func parseKey(key string) (string, Op, bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	name, rest, ok := strings.Cut(rest, "]")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return name, OpEq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return name, Op(rest[1 : len(rest)-1]), true
+}
+
+// parse converts a raw value to the field's type
+func (f *Field) parse(raw string) (any, error) {
+	t := f.goType
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("expected an RFC 3339 date-time or a YYYY-MM-DD date")
+	}
+
+	value := reflect.New(t).Elem()
+	if unmarshaler, ok := value.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		if err := unmarshaler.UnmarshalText([]byte(raw)); err != nil {
+			return nil, fmt.Errorf("invalid value: %v", err)
+		}
+		return value.Interface(), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer")
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a non-negative integer")
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		value.SetFloat(n)
+	}
+	return value.Interface(), nil
+}
+
+// Has reports whether the query filters on a field
+func (q *Query) Has(field string) bool {
+	return slices.ContainsFunc(q.Conditions, func(c Condition) bool { return c.Field == field })
+}
+
+// Where translates the conditions to a parameterized SQL expression for pgx, numbering the
+// placeholders from firstArg ($1 when firstArg is 1). It returns "TRUE" without conditions, so the
+// result can always follow WHERE
+func (q *Query) Where(firstArg int) (string, []any) {
+	if len(q.Conditions) == 0 {
+		return "TRUE", nil
+	}
+	var clauses []string
+	var args []any
+	placeholder := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(firstArg+len(args)-1)
+	}
+	for _, c := range q.Conditions {
+		switch c.Op {
+		case OpEq:
+			clauses = append(clauses, c.Column+" = "+placeholder(c.Value))
+		case OpNe:
+			clauses = append(clauses, c.Column+" <> "+placeholder(c.Value))
+		case OpGt:
+			clauses = append(clauses, c.Column+" > "+placeholder(c.Value))
+		case OpGte:
+			clauses = append(clauses, c.Column+" >= "+placeholder(c.Value))
+		case OpLt:
+			clauses = append(clauses, c.Column+" < "+placeholder(c.Value))
+		case OpLte:
+			clauses = append(clauses, c.Column+" <= "+placeholder(c.Value))
+		case OpIn:
+			clauses = append(clauses, c.Column+" = ANY("+placeholder(c.Values)+")")
+		case OpContains:
+			clauses = append(clauses, c.Column+" ILIKE "+placeholder("%"+escapeLike(fmt.Sprint(c.Value))+"%"))
+		case OpPrefix:
+			clauses = append(clauses, c.Column+" LIKE "+placeholder(escapeLike(fmt.Sprint(c.Value))+"%"))
+		case OpNull:
+			if isNull, _ := c.Value.(bool); isNull {
+				clauses = append(clauses, c.Column+" IS NULL")
+			} else {
+				clauses = append(clauses, c.Column+" IS NOT NULL")
+			}
+		}
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// OrderBy translates the sort order to an SQL ORDER BY list, e.g. "created_at DESC, name ASC".
+// It returns "" without sort keys
+func (q *Query) OrderBy() string {
+	parts := make([]string, len(q.Sort))
+	for i, key := range q.Sort {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts[i] = key.Column + " " + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func joinOps(ops []Op) string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Annotate documents the filter and sort parameters of an operation
+func (s *Schema) Annotate(operation *huma.Operation) {
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = s
+
+	properties := map[string]*huma.Schema{}
+	var sortable []string
+	for _, field := range s.Fields {
+		if field.Sortable {
+			sortable = append(sortable, field.Name)
+		}
+		if len(field.Ops) == 0 {
+			continue
+		}
+		ops := map[string]*huma.Schema{}
+		for _, op := range field.Ops {
+			switch op {
+			case OpIn:
+				ops[string(op)] = &huma.Schema{Type: huma.TypeString, Description: "Comma-separated values"}
+			case OpNull:
+				ops[string(op)] = &huma.Schema{Type: huma.TypeBoolean}
+			default:
+				ops[string(op)] = valueSchema(field.Kind)
+			}
+		}
+		properties[field.Name] = &huma.Schema{Type: huma.TypeObject, Properties: ops}
+	}
+
+	if len(properties) > 0 {
+		explode := true
+		operation.Parameters = append(operation.Parameters, &huma.Param{
+			Name:        "filter",
+			In:          "query",
+			Description: "Filters as filter[field][operator]=value; filter[field]=value means eq",
+			Style:       "deepObject",
+			Explode:     &explode,
+			Schema:      &huma.Schema{Type: huma.TypeObject, Properties: properties},
+		})
+	}
+
+	for _, param := range operation.Parameters {
+		if param.In != "query" || param.Name != "sort" {
+			continue
+		}
+		description := "Comma-separated sort keys, prefixed with - for descending order"
+		if len(sortable) > 0 {
+			description += ". Sortable fields: " + strings.Join(sortable, ", ")
+		}
+		param.Description = description
+		if param.Schema != nil {
+			schema := *param.Schema
+			schema.Description = description
+			param.Schema = &schema
+		}
+	}
+}
+
+func valueSchema(kind string) *huma.Schema {
+	switch kind {
+	case KindInteger:
+		return &huma.Schema{Type: huma.TypeInteger}
+	case KindNumber:
+		return &huma.Schema{Type: huma.TypeNumber}
+	case KindBoolean:
+		return &huma.Schema{Type: huma.TypeBoolean}
+	case KindDate:
+		return &huma.Schema{Type: huma.TypeString, Format: "date-time"}
+	}
+	return &huma.Schema{Type: huma.TypeString}
+}
+
+// SortParams is the documented sort parameter of filter dependencies
+type SortParams struct {
+	Sort string `query:"sort" doc:"Comma-separated sort keys, prefixed with - for descending order"`
+}
+
+// snakeCase turns CreatedAt into created_at
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
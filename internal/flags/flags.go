@@ -0,0 +1,336 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Extension is the root OpenAPI extension listing the flag keys, so clients can type them
+const Extension = "x-goflux-flags"
+
+// OperationExtension names the flag that gates an operation
+const OperationExtension = "x-goflux-flag"
+
+// Flag describes a feature flag and who it is enabled for
+type Flag struct {
+	// Key identifies the flag, e.g. "new-checkout"
+	Key string `json:"key" yaml:"key"`
+	// Description is exported to the OpenAPI spec and the generated client
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Enabled is the kill switch: a disabled flag is off for everyone, including targeted users
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Users and Tenants always get the flag while it is enabled
+	Users   []string `json:"users,omitempty" yaml:"users,omitempty"`
+	Tenants []string `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+	// Rollout is the percentage (0-100) of other users that get the flag. Users are bucketed by a
+	// hash of the flag key and their user ID (or tenant ID without a user), so a user keeps the same
+	// answer while the percentage only grows. Nil means everyone, requests without a user or tenant
+	// only get partially rolled out flags at 100
+	Rollout *float64 `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+}
+
+// Target is who a flag is evaluated for
+type Target struct {
+	User   string
+	Tenant string
+}
+
+// Evaluate reports whether the flag is on for target
+func (f *Flag) Evaluate(target Target) bool {
+	if f == nil || !f.Enabled {
+		return false
+	}
+	if (target.User != "" && slices.Contains(f.Users, target.User)) ||
+		(target.Tenant != "" && slices.Contains(f.Tenants, target.Tenant)) {
+		return true
+	}
+	if f.Rollout == nil {
+		return true
+	}
+	if *f.Rollout >= 100 {
+		return true
+	}
+	bucketKey := target.User
+	if bucketKey == "" {
+		bucketKey = target.Tenant
+	}
+	if bucketKey == "" || *f.Rollout <= 0 {
+		return false
+	}
+	return bucket(f.Key, bucketKey) < *f.Rollout
+}
+
+// bucket maps a flag and identity to a stable percentage in [0, 100)
+func bucket(key, identity string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{':'})
+	h.Write([]byte(identity))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// File is the format of flag files:
+//
+//	flags:
+//	  - key: new-checkout
+//	    enabled: true
+//	    rollout: 25
+//	    tenants: [acme]
+type File struct {
+	Flags []Flag `json:"flags" yaml:"flags"`
+}
+
+// Store holds the flags of a service. Flags defined in code are the defaults; flags loaded from a
+// file replace those with the same key and are themselves replaced on every reload
+type Store struct {
+	mu      sync.RWMutex
+	defined map[string]Flag
+	loaded  map[string]Flag
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{defined: map[string]Flag{}, loaded: map[string]Flag{}}
+}
+
+// Default is the store used by the goflux package
+var Default = NewStore()
+
+// Define adds or replaces a flag defined in code
+func (s *Store) Define(flag Flag) error {
+	if err := validate(flag); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defined[flag.Key] = flag
+	return nil
+}
+
+// Get returns the effective definition of a flag
+func (s *Store) Get(key string) (*Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if flag, ok := s.loaded[key]; ok {
+		return &flag, true
+	}
+	if flag, ok := s.defined[key]; ok {
+		return &flag, true
+	}
+	return nil, false
+}
+
+// List returns the effective flags, sorted by key
+func (s *Store) List() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	merged := make(map[string]Flag, len(s.defined)+len(s.loaded))
+	for key, flag := range s.defined {
+		merged[key] = flag
+	}
+	for key, flag := range s.loaded {
+		merged[key] = flag
+	}
+	list := make([]Flag, 0, len(merged))
+	for _, flag := range merged {
+		list = append(list, flag)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Enabled reports whether a flag is on for target. Unknown flags are off
+func (s *Store) Enabled(key string, target Target) bool {
+	flag, _ := s.Get(key)
+	return flag.Evaluate(target)
+}
+
+// LoadFile replaces the flags loaded from a file with the contents of a YAML or JSON file
+// (chosen by extension, YAML for anything but .json). On error the current flags are kept
+func (s *Store) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("flags: failed to read %s: %w", path, err)
+	}
+
+	var file File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return fmt.Errorf("flags: failed to parse %s: %w", path, err)
+	}
+
+	loaded := make(map[string]Flag, len(file.Flags))
+	for _, flag := range file.Flags {
+		if err := validate(flag); err != nil {
+			return fmt.Errorf("flags: invalid flag in %s: %w", path, err)
+		}
+		if _, exists := loaded[flag.Key]; exists {
+			return fmt.Errorf("flags: flag %q is defined twice in %s", flag.Key, path)
+		}
+		loaded[flag.Key] = flag
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loaded = loaded
+	return nil
+}
+
+// Watch reloads the file whenever its modification time changes, checking every interval
+// (default 2 seconds), until ctx is cancelled. Reload errors are passed to onError and the previous
+// flags stay in effect
+func (s *Store) Watch(ctx context.Context, path string, interval time.Duration, onError func(err error)) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+		if err := s.LoadFile(path); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func validate(flag Flag) error {
+	if flag.Key == "" {
+		return fmt.Errorf("flags: flag key is required")
+	}
+	if flag.Rollout != nil && (*flag.Rollout < 0 || *flag.Rollout > 100) {
+		return fmt.Errorf("flags: rollout of flag %q must be between 0 and 100", flag.Key)
+	}
+	return nil
+}
+
+// SpecFlag is a flag as listed in the OpenAPI extension
+type SpecFlag struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+}
+
+// SpecExtension lists the flags of a store when the spec is marshalled, so flags defined or loaded
+// after the extension was set are included
+type SpecExtension struct {
+	Store *Store
+}
+
+// MarshalJSON lists the effective flags
+func (e SpecExtension) MarshalJSON() ([]byte, error) {
+	list := e.Store.List()
+	specFlags := make([]SpecFlag, len(list))
+	for i, flag := range list {
+		specFlags[i] = SpecFlag{Key: flag.Key, Description: flag.Description}
+	}
+	return json.Marshal(specFlags)
+}
+
+// Document returns a copy of an OpenAPI document without the operations gated by a flag that is
+// off for everyone, i.e. disabled or unknown. Documents built per request follow flag reloads
+func Document(oapi *huma.OpenAPI, store *Store) *huma.OpenAPI {
+	document := *oapi
+	document.Paths = map[string]*huma.PathItem{}
+	for path, item := range oapi.Paths {
+		filtered := *item
+		keep := false
+		for _, operation := range []**huma.Operation{
+			&filtered.Get, &filtered.Put, &filtered.Post, &filtered.Delete,
+			&filtered.Options, &filtered.Head, &filtered.Patch, &filtered.Trace,
+		} {
+			if *operation == nil {
+				continue
+			}
+			keys, _ := (*operation).Extensions[OperationExtension].([]string)
+			for _, key := range keys {
+				if flag, ok := store.Get(key); !ok || !flag.Enabled {
+					*operation = nil
+					break
+				}
+			}
+			if *operation != nil {
+				keep = true
+			}
+		}
+		if keep {
+			document.Paths[path] = &filtered
+		}
+	}
+	return &document
+}
+
+// Evaluator answers flag questions for one target
+type Evaluator struct {
+	store  *Store
+	target Target
+}
+
+// NewEvaluator creates an evaluator for target
+func NewEvaluator(store *Store, target Target) *Evaluator {
+	return &Evaluator{store: store, target: target}
+}
+
+// Enabled reports whether a flag is on for the evaluator's target
+func (e *Evaluator) Enabled(key string) bool {
+	return e.store.Enabled(key, e.target)
+}
+
+// Target returns who the evaluator answers for
+func (e *Evaluator) Target() Target {
+	return e.target
+}
+
+// All evaluates every flag
+func (e *Evaluator) All() map[string]bool {
+	list := e.store.List()
+	states := make(map[string]bool, len(list))
+	for _, flag := range list {
+		states[flag.Key] = flag.Evaluate(e.target)
+	}
+	return states
+}
+
+type targetKey struct{}
+
+// WithTarget returns a copy of ctx whose flags are evaluated for target, overriding the target
+// derived from the authenticated principal
+func WithTarget(ctx context.Context, target Target) context.Context {
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// TargetFromContext returns the target stored by WithTarget
+func TargetFromContext(ctx context.Context) (Target, bool) {
+	target, ok := ctx.Value(targetKey{}).(Target)
+	return target, ok
+}
@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestDocument(t *testing.T) {
+	store := NewStore()
+	for _, flag := range []Flag{{Key: "on", Enabled: true}, {Key: "off"}} {
+		if err := store.Define(flag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gated := func(keys ...string) *huma.Operation {
+		operation := &huma.Operation{}
+		if len(keys) > 0 {
+			operation.Extensions = map[string]any{OperationExtension: keys}
+		}
+		return operation
+	}
+	oapi := &huma.OpenAPI{Paths: map[string]*huma.PathItem{
+		"/open":    {Get: gated()},
+		"/on":      {Get: gated("on")},
+		"/off":     {Get: gated("off")},
+		"/unknown": {Get: gated("unknown")},
+		"/both":    {Get: gated("on", "off")},
+		"/mixed":   {Get: gated("on"), Post: gated("off")},
+	}}
+
+	tests := []struct {
+		path       string
+		get, post  bool
+		pathListed bool
+	}{
+		{"/open", true, false, true},
+		{"/on", true, false, true},
+		{"/off", false, false, false},
+		{"/unknown", false, false, false},
+		{"/both", false, false, false},
+		{"/mixed", true, false, true},
+	}
+
+	document := Document(oapi, store)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			item, ok := document.Paths[tt.path]
+			if ok != tt.pathListed {
+				t.Fatalf("path listed = %v, want %v", ok, tt.pathListed)
+			}
+			if !ok {
+				return
+			}
+			if (item.Get != nil) != tt.get || (item.Post != nil) != tt.post {
+				t.Fatalf("GET listed = %v, POST listed = %v; want %v, %v", item.Get != nil, item.Post != nil, tt.get, tt.post)
+			}
+		})
+	}
+	if oapi.Paths["/mixed"].Post == nil {
+		t.Fatal("Document() modified the source document")
+	}
+
+	// Documents follow flag changes
+	if err := store.Define(Flag{Key: "off", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Document(oapi, store).Paths["/off"]; !ok {
+		t.Fatal("Document() left out an operation whose flag was enabled")
+	}
+}
@@ -0,0 +1,54 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Both handles read `json` struct tags, so bodies keep the field names of their JSON form
+var (
+	cborHandle = func() *codec.CborHandle {
+		h := &codec.CborHandle{}
+		h.MapType = reflect.TypeOf(map[string]any(nil))
+		return h
+	}()
+
+	msgpackHandle = func() *codec.MsgpackHandle {
+		h := &codec.MsgpackHandle{}
+		// Use the current spec: str8, bin and timestamp extension types
+		h.WriteExt = true
+		h.MapType = reflect.TypeOf(map[string]any(nil))
+		return h
+	}()
+)
+
+func marshalCBOR(w io.Writer, v any) error {
+	if err := codec.NewEncoder(w, cborHandle).Encode(v); err != nil {
+		return fmt.Errorf("cbor: %w", err)
+	}
+	return nil
+}
+
+func unmarshalCBOR(data []byte, v any) error {
+	if err := codec.NewDecoderBytes(data, cborHandle).Decode(v); err != nil {
+		return fmt.Errorf("cbor: %w", err)
+	}
+	return nil
+}
+
+func marshalMessagePack(w io.Writer, v any) error {
+	if err := codec.NewEncoder(w, msgpackHandle).Encode(v); err != nil {
+		return fmt.Errorf("msgpack: %w", err)
+	}
+	return nil
+}
+
+func unmarshalMessagePack(data []byte, v any) error {
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(v); err != nil {
+		return fmt.Errorf("msgpack: %w", err)
+	}
+	return nil
+}
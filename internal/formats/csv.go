@@ -0,0 +1,396 @@
+package formats
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flushEvery is how many CSV rows are buffered before they are pushed to the client
+const flushEvery = 100
+
+// column is a CSV column backed by a (possibly embedded) struct field
+type column struct {
+	name  string
+	index []int
+}
+
+// MarshalCSV writes a slice as CSV with a header row. Struct elements get one column per
+// field named after its json tag, map elements one column per key of the first element,
+// and scalar elements a single "value" column. Nested values are written as JSON, and a
+// value that is not a list (such as an error response) is written as a single row.
+// Rows are encoded one at a time and flushed periodically, so large exports stream
+func MarshalCSV(w io.Writer, v any) error {
+	list := reflect.ValueOf(v)
+	for list.Kind() == reflect.Pointer || list.Kind() == reflect.Interface {
+		if list.IsNil() {
+			return nil
+		}
+		list = list.Elem()
+	}
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array || list.Type().Elem().Kind() == reflect.Uint8 {
+		single := reflect.MakeSlice(reflect.SliceOf(list.Type()), 1, 1)
+		single.Index(0).Set(list)
+		list = single
+	}
+
+	writer := csv.NewWriter(w)
+	elemType := derefType(list.Type().Elem())
+
+	var header []string
+	var row func(elem reflect.Value) []string
+	switch {
+	case elemType.Kind() == reflect.Struct && elemType != reflect.TypeFor[time.Time]():
+		columns := structColumns(elemType, nil)
+		for _, c := range columns {
+			header = append(header, c.name)
+		}
+		row = func(elem reflect.Value) []string {
+			record := make([]string, len(columns))
+			elem = derefValue(elem)
+			if !elem.IsValid() {
+				return record
+			}
+			for i, c := range columns {
+				if field, err := elem.FieldByIndexErr(c.index); err == nil {
+					record[i] = cell(field)
+				}
+			}
+			return record
+		}
+	case elemType.Kind() == reflect.Map:
+		var keys []reflect.Value
+		if list.Len() > 0 {
+			if first := derefValue(list.Index(0)); first.IsValid() {
+				keys = first.MapKeys()
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			header = append(header, fmt.Sprint(key))
+		}
+		row = func(elem reflect.Value) []string {
+			record := make([]string, len(keys))
+			elem = derefValue(elem)
+			if !elem.IsValid() {
+				return record
+			}
+			for i, key := range keys {
+				record[i] = cell(elem.MapIndex(key))
+			}
+			return record
+		}
+	default:
+		header = []string{"value"}
+		row = func(elem reflect.Value) []string {
+			return []string{cell(elem)}
+		}
+	}
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("csv: %w", err)
+	}
+	for i := 0; i < list.Len(); i++ {
+		if err := writer.Write(row(list.Index(i))); err != nil {
+			return fmt.Errorf("csv: %w", err)
+		}
+		if (i+1)%flushEvery == 0 {
+			writer.Flush()
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("csv: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalCSV decodes CSV with a header row into a pointer to a slice. Columns are matched
+// to struct fields by json name (case-insensitive); unknown columns are ignored.
+// Decoding into a single struct or map requires exactly one row
+func UnmarshalCSV(data []byte, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("csv: cannot decode into %T", v)
+	}
+	list := target.Elem()
+	if list.Kind() == reflect.Interface && list.NumMethod() == 0 {
+		// Decoding into any yields a list of string maps
+		var records []map[string]string
+		if err := UnmarshalCSV(data, &records); err != nil {
+			return err
+		}
+		list.Set(reflect.ValueOf(records))
+		return nil
+	}
+	if list.Kind() != reflect.Slice || list.Type().Elem().Kind() == reflect.Uint8 {
+		rows := reflect.New(reflect.SliceOf(list.Type()))
+		if err := UnmarshalCSV(data, rows.Interface()); err != nil {
+			return err
+		}
+		if rows.Elem().Len() != 1 {
+			return fmt.Errorf("csv: expected exactly one row for %T, got %d", v, rows.Elem().Len())
+		}
+		list.Set(rows.Elem().Index(0))
+		return nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return fmt.Errorf("csv: %w", err)
+	}
+	if len(records) == 0 {
+		list.Set(reflect.MakeSlice(list.Type(), 0, 0))
+		return nil
+	}
+	header, rows := records[0], records[1:]
+
+	elemType := list.Type().Elem()
+	baseType := derefType(elemType)
+	result := reflect.MakeSlice(list.Type(), 0, len(rows))
+
+	var columns []column
+	if baseType.Kind() == reflect.Struct {
+		byName := map[string]column{}
+		for _, c := range structColumns(baseType, nil) {
+			byName[strings.ToLower(c.name)] = c
+		}
+		columns = make([]column, len(header))
+		for i, name := range header {
+			columns[i] = byName[strings.ToLower(strings.TrimSpace(name))]
+		}
+	}
+
+	for line, record := range rows {
+		elem := reflect.New(baseType).Elem()
+		switch {
+		case baseType.Kind() == reflect.Struct && baseType != reflect.TypeFor[time.Time]():
+			for i, value := range record {
+				if i >= len(columns) || columns[i].index == nil {
+					continue
+				}
+				field := fieldByIndexAlloc(elem, columns[i].index)
+				if err := setCell(field, value); err != nil {
+					return fmt.Errorf("csv: line %d, column %q: %w", line+2, header[i], err)
+				}
+			}
+		case baseType.Kind() == reflect.Map:
+			elem = reflect.MakeMapWithSize(baseType, len(record))
+			for i, value := range record {
+				if i >= len(header) {
+					break
+				}
+				item := reflect.New(baseType.Elem()).Elem()
+				if err := setCell(item, value); err != nil {
+					return fmt.Errorf("csv: line %d, column %q: %w", line+2, header[i], err)
+				}
+				elem.SetMapIndex(reflect.ValueOf(header[i]).Convert(baseType.Key()), item)
+			}
+		default:
+			if len(record) > 0 {
+				if err := setCell(elem, record[0]); err != nil {
+					return fmt.Errorf("csv: line %d: %w", line+2, err)
+				}
+			}
+		}
+
+		if elemType.Kind() == reflect.Pointer {
+			ptr := reflect.New(baseType)
+			ptr.Elem().Set(elem)
+			elem = ptr
+		}
+		result = reflect.Append(result, elem)
+	}
+
+	list.Set(result)
+	return nil
+}
+
+// structColumns lists the CSV columns of a struct, flattening embedded structs like encoding/json
+func structColumns(t reflect.Type, prefix []int) []column {
+	var columns []column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		index := append(append([]int(nil), prefix...), i)
+
+		if field.Anonymous && name == "" {
+			if embedded := derefType(field.Type); embedded.Kind() == reflect.Struct {
+				columns = append(columns, structColumns(embedded, index)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == "$schema" {
+			// Huma's JSON schema link has no meaning in a spreadsheet
+			continue
+		}
+		columns = append(columns, column{name: name, index: index})
+	}
+	return columns
+}
+
+// cell formats a value for a CSV cell
+func cell(value reflect.Value) string {
+	value = derefValue(value)
+	if !value.IsValid() || (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.IsNil() {
+		return ""
+	}
+
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits())
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(value.Bytes())
+		}
+	}
+
+	encoded, err := json.Marshal(value.Interface())
+	if err != nil {
+		return fmt.Sprint(value.Interface())
+	}
+	return string(encoded)
+}
+
+// setCell parses a CSV cell into a value. Empty cells leave the value at its zero value
+func setCell(value reflect.Value, text string) error {
+	if text == "" {
+		return nil
+	}
+	if value.Kind() == reflect.Pointer {
+		ptr := reflect.New(value.Type().Elem())
+		if err := setCell(ptr.Elem(), text); err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+
+	if value.Type() == reflect.TypeFor[time.Time]() {
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Interface:
+		if value.NumMethod() != 0 {
+			return fmt.Errorf("cannot decode into %s", value.Type())
+		}
+		value.Set(reflect.ValueOf(text))
+	default:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := base64.StdEncoding.DecodeString(text)
+			if err != nil {
+				return err
+			}
+			value.SetBytes(b)
+			return nil
+		}
+		return json.Unmarshal([]byte(text), value.Addr().Interface())
+	}
+	return nil
+}
+
+// fieldByIndexAlloc returns a nested field, allocating nil embedded pointers on the way
+func fieldByIndexAlloc(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func derefValue(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
@@ -0,0 +1,189 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Media types of the built-in formats
+const (
+	JSON        = "application/json"
+	CBOR        = "application/cbor"
+	MessagePack = "application/msgpack"
+	CSV         = "text/csv"
+)
+
+// Encoder is an alternative body format selectable with the Accept and Content-Type headers
+type Encoder struct {
+	// MediaType is the canonical media type, listed in the OpenAPI document
+	MediaType string
+	// Aliases are further media types (and "+suffix" names) that select the encoder
+	Aliases []string
+	// Tabular encoders represent lists as rows and are only documented for array bodies
+	Tabular bool
+	// Marshal writes a response body
+	Marshal func(w io.Writer, v any) error
+	// Unmarshal decodes a request body into v
+	Unmarshal func(data []byte, v any) error
+}
+
+// Format returns the encoder as a Huma format
+func (e Encoder) Format() huma.Format {
+	return huma.Format{Marshal: e.Marshal, Unmarshal: e.Unmarshal}
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Encoder
+)
+
+func init() {
+	Register(Encoder{
+		MediaType: CBOR,
+		Aliases:   []string{"cbor"},
+		Marshal:   marshalCBOR,
+		Unmarshal: unmarshalCBOR,
+	})
+	Register(Encoder{
+		MediaType: MessagePack,
+		Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack", "msgpack"},
+		Marshal:   marshalMessagePack,
+		Unmarshal: unmarshalMessagePack,
+	})
+	Register(Encoder{
+		MediaType: CSV,
+		Aliases:   []string{"csv"},
+		Tabular:   true,
+		Marshal:   MarshalCSV,
+		Unmarshal: UnmarshalCSV,
+	})
+}
+
+// Register adds an encoder to the registry, replacing any encoder with the same media type
+func Register(encoder Encoder) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, existing := range registry {
+		if existing.MediaType == encoder.MediaType {
+			registry[i] = encoder
+			return
+		}
+	}
+	registry = append(registry, encoder)
+}
+
+// Lookup returns the registered encoder for a media type or alias
+func Lookup(mediaType string) (Encoder, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, encoder := range registry {
+		if encoder.MediaType == mediaType {
+			return encoder, true
+		}
+		for _, alias := range encoder.Aliases {
+			if alias == mediaType {
+				return encoder, true
+			}
+		}
+	}
+	return Encoder{}, false
+}
+
+// Encoders returns the registered encoders in registration order
+func Encoders() []Encoder {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Encoder(nil), registry...)
+}
+
+// Configure adds registered encoders to a Huma config so they can be negotiated for responses and
+// decoded from requests. With no media types every registered encoder is added. JSON stays the default
+//
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	formats.Configure(&config, formats.CBOR, formats.CSV)
+func Configure(config *huma.Config, mediaTypes ...string) error {
+	encoders := Encoders()
+	if len(mediaTypes) > 0 {
+		encoders = encoders[:0:0]
+		for _, mediaType := range mediaTypes {
+			encoder, ok := Lookup(mediaType)
+			if !ok {
+				return fmt.Errorf("formats: no encoder registered for %q", mediaType)
+			}
+			encoders = append(encoders, encoder)
+		}
+	}
+
+	// Copy the map: huma.DefaultConfig shares huma.DefaultFormats between configs
+	configured := make(map[string]huma.Format, len(config.Formats)+len(encoders)*2)
+	source := config.Formats
+	if source == nil {
+		source = huma.DefaultFormats
+	}
+	for name, format := range source {
+		configured[name] = format
+	}
+	for _, encoder := range encoders {
+		configured[encoder.MediaType] = encoder.Format()
+		for _, alias := range encoder.Aliases {
+			configured[alias] = encoder.Format()
+		}
+	}
+	config.Formats = configured
+	return nil
+}
+
+// Supported returns the registered encoders the API can negotiate
+func Supported(api huma.API) []Encoder {
+	var supported []Encoder
+	for _, encoder := range Encoders() {
+		// Negotiate falls back to the default format for unknown types, so check the result
+		if ct, err := api.Negotiate(encoder.MediaType); err == nil && ct == encoder.MediaType {
+			supported = append(supported, encoder)
+		}
+	}
+	return supported
+}
+
+// Annotate lists the API's alternative encoders next to the JSON request and response bodies
+// of an operation. Tabular encoders are only listed for array schemas
+func Annotate(operation *huma.Operation, api huma.API) {
+	supported := Supported(api)
+	if len(supported) == 0 {
+		return
+	}
+
+	if operation.RequestBody != nil {
+		addMediaTypes(operation.RequestBody.Content, supported)
+	}
+	for status, response := range operation.Responses {
+		if response == nil || !strings.HasPrefix(status, "2") {
+			continue
+		}
+		addMediaTypes(response.Content, supported)
+	}
+}
+
+// addMediaTypes copies the JSON schema of content to each supported encoder
+func addMediaTypes(content map[string]*huma.MediaType, supported []Encoder) {
+	jsonContent, ok := content[JSON]
+	if !ok || jsonContent == nil || jsonContent.Schema == nil {
+		return
+	}
+	for _, encoder := range supported {
+		if _, exists := content[encoder.MediaType]; exists {
+			continue
+		}
+		if encoder.Tabular && jsonContent.Schema.Type != huma.TypeArray {
+			continue
+		}
+		content[encoder.MediaType] = &huma.MediaType{Schema: jsonContent.Schema}
+	}
+}
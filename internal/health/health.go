@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Options configures a check
+type Options struct {
+	// Timeout bounds one run of the check (default 3 seconds)
+	Timeout time.Duration
+	// CacheTTL is how long a result is reused, so probes polled by several load balancers do not
+	// hammer the dependency (default 2 seconds)
+	CacheTTL time.Duration
+	// Critical checks take the instance out of rotation when they fail. Failures of other checks
+	// only degrade the report
+	Critical bool
+	// Liveness checks also run for the liveness probe. A failure there gets the process restarted,
+	// so only use it for conditions a restart fixes, such as a deadlock
+	Liveness bool
+}
+
+// CheckResult is the outcome of a check
+type CheckResult struct {
+	Name      string        `json:"name" doc:"Check name"`
+	Status    string        `json:"status" enum:"pass,fail" doc:"Outcome of the check"`
+	Critical  bool          `json:"critical" doc:"Whether a failure takes the instance out of rotation"`
+	Error     string        `json:"error,omitempty" doc:"Failure reason"`
+	Duration  time.Duration `json:"duration" doc:"Duration of the check in nanoseconds"`
+	CheckedAt time.Time     `json:"checkedAt" doc:"Time the check ran; results are cached briefly"`
+}
+
+// HealthReport combines the results of several checks
+type HealthReport struct {
+	Status string        `json:"status" enum:"pass,warn,fail" doc:"fail when a critical check failed or the service is not ready, warn when another check failed"`
+	Checks []CheckResult `json:"checks" doc:"Results by check name"`
+}
+
+type check struct {
+	name    string
+	fn      func(ctx context.Context) error
+	options Options
+
+	mu     sync.Mutex
+	result *CheckResult
+}
+
+// Registry holds the checks of a service
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{checks: map[string]*check{}}
+}
+
+// Default is the registry used by the probes and reports of the goflux package
+var Default = NewRegistry()
+
+// Register adds a check. Names must be unique
+func (r *Registry) Register(name string, fn func(ctx context.Context) error, options Options) error {
+	if options.Timeout <= 0 {
+		options.Timeout = 3 * time.Second
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = 2 * time.Second
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.checks[name]; exists {
+		return fmt.Errorf("health: check %q is already registered", name)
+	}
+	r.checks[name] = &check{name: name, fn: fn, options: options}
+	return nil
+}
+
+// Run runs the checks selected by include concurrently, reusing cached results.
+// A nil include runs every check
+func (r *Registry) Run(ctx context.Context, include func(options Options) bool) HealthReport {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if include == nil || include(c.options) {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	report := HealthReport{Status: StatusPass, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusFail {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusPass {
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+// run returns the cached result of a check or runs it. Concurrent callers share one run
+func (c *check) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result != nil && time.Since(c.result.CheckedAt) < c.options.CacheTTL {
+		return *c.result
+	}
+
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.options.Timeout)
+	defer cancel()
+
+	// Don't wait on checks that ignore their context
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- call(checkCtx, c.fn)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+	}
+	result := CheckResult{
+		Name:      c.name,
+		Status:    StatusPass,
+		Critical:  c.options.Critical,
+		Duration:  time.Since(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = fmt.Sprintf("timed out after %s", c.options.Timeout)
+		}
+	}
+	c.result = &result
+	return result
+}
+
+// call runs a check, turning panics into failures
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
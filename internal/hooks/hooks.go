@@ -0,0 +1,59 @@
+package hooks
+
+import (
+	"context"
+	"sync"
+)
+
+// Source tells what failed
+type Source string
+
+const (
+	// SourceRequest is an operation handler that failed with a server error or panicked
+	SourceRequest Source = "request"
+	// SourceTask is a scheduled task run that failed or panicked
+	SourceTask Source = "task"
+	// SourceFlags is a feature flag file that failed to reload
+	SourceFlags Source = "flags"
+)
+
+// ErrorEvent describes a failure reported to the error hooks
+type ErrorEvent struct {
+	Source Source
+	// Name is the operation ID of a request, the name of a task or the path of a flag file
+	Name string
+	// Method and Path identify the operation of a request
+	Method string
+	Path   string
+	// Status is the HTTP status answered to a request
+	Status int
+	// Panic is set when the failure was a recovered panic
+	Panic bool
+	Err   error
+}
+
+// ErrorHook receives failures, e.g. to log them or forward them to an error tracker. Hooks run
+// synchronously on the failing request or task, so slow work should be handed off
+type ErrorHook func(ctx context.Context, event ErrorEvent)
+
+var (
+	mu         sync.RWMutex
+	errorHooks []ErrorHook
+)
+
+// OnError adds a hook that is called for every reported failure
+func OnError(hook ErrorHook) {
+	mu.Lock()
+	defer mu.Unlock()
+	errorHooks = append(errorHooks, hook)
+}
+
+// ReportError calls the error hooks with event
+func ReportError(ctx context.Context, event ErrorEvent) {
+	mu.RLock()
+	hooks := errorHooks
+	mu.RUnlock()
+	for _, hook := range hooks {
+		hook(ctx, event)
+	}
+}
@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// DefaultHeader is the request header carrying the idempotency key
+const DefaultHeader = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Common idempotency errors
+var (
+	ErrMissingKey  = errors.New("idempotency key is required")
+	ErrInvalidKey  = errors.New("idempotency key must be 1-255 characters")
+	ErrInProgress  = errors.New("a request with this idempotency key is still being processed")
+	ErrKeyMismatch = errors.New("idempotency key was already used with a different request payload")
+)
+
+// maxRecordedBody limits how much of a response is kept; larger responses are not stored
+const maxRecordedBody = 1 << 20
+
+// Options configures idempotent request handling
+type Options struct {
+	// Store persists keys and responses (default in-memory store)
+	Store Store
+	// TTL is how long completed responses are replayed (default 24 hours)
+	TTL time.Duration
+	// LockTTL bounds how long an unfinished request blocks its key, e.g. after a crash (default 1 minute)
+	LockTTL time.Duration
+	// Header is the request header carrying the key (default "Idempotency-Key")
+	Header string
+	// Required rejects unsafe requests without a key with 400 Bad Request
+	Required bool
+	// ClientHeader identifies anonymous clients by a header set by a trusted reverse proxy, e.g.
+	// "X-Forwarded-For" (its last address is used) or "X-Real-IP", instead of the remote address,
+	// which is the proxy's for every client behind one. Only set it when the proxy overwrites the
+	// header, otherwise clients choose their own scope
+	ClientHeader string
+}
+
+// Manager coordinates idempotent requests
+type Manager struct {
+	options Options
+}
+
+// New creates a manager with defaults applied
+func New(opts Options) *Manager {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = time.Minute
+	}
+	if opts.Header == "" {
+		opts.Header = DefaultHeader
+	}
+	return &Manager{options: opts}
+}
+
+// Options returns the manager options with defaults applied
+func (m *Manager) Options() Options {
+	return m.options
+}
+
+// Fingerprint hashes the parts of a request that must match when a key is reused
+func Fingerprint(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		// Length prefixes keep ("ab", "c") and ("a", "bc") apart
+		hash.Write([]byte(strconv.Itoa(len(part)) + ":"))
+		hash.Write(part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Begin starts idempotent handling of a request. scope separates the keys of different
+// callers (e.g. the principal's subject) and fingerprint identifies the request payload.
+//
+// Requests with a safe method or without a key (unless required) pass through unchanged.
+// When a completed response is stored for the key it is replayed, and next is nil.
+// Otherwise next records the response, and finish must be called once the handler has
+// written it: responses below 500 are stored, anything else releases the key for a retry
+func (m *Manager) Begin(ctx huma.Context, scope, fingerprint string) (next huma.Context, finish func(), err error) {
+	switch ctx.Method() {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return ctx, func() {}, nil
+	}
+
+	key := ctx.Header(m.options.Header)
+	if key == "" {
+		if m.options.Required {
+			return nil, nil, ErrMissingKey
+		}
+		return ctx, func() {}, nil
+	}
+	if len(key) > 255 {
+		return nil, nil, ErrInvalidKey
+	}
+
+	storeKey := Fingerprint([]byte(scope), []byte(key))
+	existing, err := m.options.Store.Lock(ctx.Context(), storeKey, &Record{Fingerprint: fingerprint}, m.options.LockTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("idempotency: failed to lock key: %w", err)
+	}
+
+	if existing != nil {
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return nil, nil, ErrKeyMismatch
+		case !existing.Completed:
+			return nil, nil, ErrInProgress
+		}
+		replay(ctx, existing)
+		return nil, func() {}, nil
+	}
+
+	recorder := &recordingContext{humaContext: ctx, header: http.Header{}}
+	finish = func() {
+		// Use a fresh context: the request context may already be cancelled
+		storeCtx := context.WithoutCancel(ctx.Context())
+		if recorder.status == 0 || recorder.status >= 500 || recorder.overflow {
+			_ = m.options.Store.Delete(storeCtx, storeKey)
+			return
+		}
+		_ = m.options.Store.Save(storeCtx, storeKey, &Record{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      recorder.status,
+			Header:      recorder.header,
+			Body:        recorder.body.Bytes(),
+		}, m.options.TTL)
+	}
+	return recorder, finish, nil
+}
+
+// replay writes a stored response
+func replay(ctx huma.Context, record *Record) {
+	for name, values := range record.Header {
+		for i, value := range values {
+			if i == 0 {
+				ctx.SetHeader(name, value)
+			} else {
+				ctx.AppendHeader(name, value)
+			}
+		}
+	}
+	ctx.SetHeader(ReplayedHeader, "true")
+	ctx.SetStatus(record.Status)
+	if len(record.Body) > 0 {
+		_, _ = ctx.BodyWriter().Write(record.Body)
+	}
+}
+
+// humaContext lets the recorder embed huma.Context without the field name clashing with its Context method
+type humaContext = huma.Context
+
+// recordingContext captures the status, headers and body written through it
+type recordingContext struct {
+	humaContext
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+// SetStatus records and sets the status
+func (c *recordingContext) SetStatus(code int) {
+	c.status = code
+	c.humaContext.SetStatus(code)
+}
+
+// SetHeader records and sets a header
+func (c *recordingContext) SetHeader(name, value string) {
+	c.header.Set(name, value)
+	c.humaContext.SetHeader(name, value)
+}
+
+// AppendHeader records and appends a header
+func (c *recordingContext) AppendHeader(name, value string) {
+	c.header.Add(name, value)
+	c.humaContext.AppendHeader(name, value)
+}
+
+// BodyWriter returns a writer that copies the body into the recording
+func (c *recordingContext) BodyWriter() io.Writer {
+	return &teeWriter{context: c, writer: c.humaContext.BodyWriter()}
+}
+
+type teeWriter struct {
+	context *recordingContext
+	writer  io.Writer
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	if !w.context.overflow {
+		if w.context.body.Len()+len(p) > maxRecordedBody {
+			w.context.overflow = true
+			w.context.body.Reset()
+		} else {
+			w.context.body.Write(p)
+		}
+	}
+	return w.writer.Write(p)
+}
@@ -0,0 +1,104 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record is the stored state of an idempotency key
+type Record struct {
+	// Fingerprint identifies the request payload the key was first used with
+	Fingerprint string
+	// Completed is false while the first request is still being processed
+	Completed bool
+	// Status, Header and Body hold the stored response once Completed is true
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store persists idempotency records. Implementations must make Lock atomic
+type Store interface {
+	// Lock reserves key with an in-progress record if it is not in use.
+	// It returns the existing record, or nil if the key was reserved by this call
+	Lock(ctx context.Context, key string, record *Record, ttl time.Duration) (*Record, error)
+	// Save replaces the record for key, typically with the completed response
+	Save(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Delete releases key so the request can be retried
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore keeps records in process memory with per-record expiry
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[string]memoryRecord
+	lastCleanup time.Time
+}
+
+type memoryRecord struct {
+	record    Record
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+// Lock implements Store
+func (s *MemoryStore) Lock(ctx context.Context, key string, record *Record, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	// Expired records are swept at most once a minute so idle keys don't accumulate
+	if now.Sub(s.lastCleanup) > time.Minute {
+		s.cleanup(now)
+	}
+
+	if existing, ok := s.records[key]; ok && now.Before(existing.expiresAt) {
+		record := existing.record
+		return &record, nil
+	}
+
+	s.records[key] = memoryRecord{record: *record, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryRecord{record: *record, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Cleanup removes expired records and returns how many were removed
+func (s *MemoryStore) Cleanup(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cleanup(time.Now()), nil
+}
+
+// cleanup must be called with the lock held
+func (s *MemoryStore) cleanup(now time.Time) int {
+	removed := 0
+	for key, record := range s.records {
+		if !now.Before(record.expiresAt) {
+			delete(s.records, key)
+			removed++
+		}
+	}
+	s.lastCleanup = now
+	return removed
+}
@@ -0,0 +1,426 @@
+package jobs
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	// StatusPending jobs wait for their RunAt time, including jobs waiting to be retried
+	StatusPending Status = "pending"
+	// StatusRunning jobs have been claimed by a worker
+	StatusRunning Status = "running"
+	// StatusSucceeded jobs completed without error
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed jobs used up their attempts or failed permanently
+	StatusFailed Status = "failed"
+	// StatusCancelled jobs were cancelled before they ran
+	StatusCancelled Status = "cancelled"
+)
+
+var (
+	// ErrNotFound is returned when a job doesn't exist
+	ErrNotFound = errors.New("job: not found")
+	// ErrInvalidState is returned when a job cannot be retried or cancelled in its current state
+	ErrInvalidState = errors.New("job: invalid state for this operation")
+	// ErrClaimLost is returned when a job's lease expired and another claim took it over,
+	// so the outcome of the earlier attempt is discarded
+	ErrClaimLost = errors.New("job: claim lost to another worker")
+)
+
+// Job is a unit of background work with a JSON payload
+type Job struct {
+	ID          string          `json:"id" doc:"Job ID"`
+	Kind        string          `json:"kind" doc:"Job type, selects the worker"`
+	Payload     json.RawMessage `json:"payload" doc:"Job payload"`
+	Status      Status          `json:"status" enum:"pending,running,succeeded,failed,cancelled" doc:"Lifecycle state"`
+	Attempts    int             `json:"attempts" doc:"Number of times the job has been started"`
+	MaxAttempts int             `json:"maxAttempts" doc:"Number of attempts before the job fails"`
+	RunAt       time.Time       `json:"runAt" doc:"When the job is due to run (next)"`
+	LastError   string          `json:"lastError,omitempty" doc:"Error of the last failed attempt"`
+	CreatedAt   time.Time       `json:"createdAt" doc:"When the job was enqueued"`
+	UpdatedAt   time.Time       `json:"updatedAt" doc:"When the job last changed"`
+}
+
+// ListOptions filters and pages job listings, newest first
+type ListOptions struct {
+	Status Status
+	Kind   string
+	Limit  int
+	Offset int
+}
+
+// Store persists jobs. Implementations must let several processes claim jobs concurrently
+// without handing the same job to two of them
+type Store interface {
+	// Enqueue stores a new pending job
+	Enqueue(ctx context.Context, job *Job) error
+	// Claim marks up to limit due jobs of the given kinds as running, counts an attempt and locks them
+	// for lease. Running jobs whose lease expired (their worker died) are claimed again
+	Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*Job, error)
+	// Complete marks a running job as succeeded. attempt is the Attempts value returned by Claim; when the
+	// job has been claimed again since, nothing changes and ErrClaimLost is returned
+	Complete(ctx context.Context, id string, attempt int) error
+	// Fail records the error of a running job. It becomes pending again at retryAt, or failed when retryAt is nil.
+	// Like Complete, it returns ErrClaimLost unless attempt is still the job's current claim
+	Fail(ctx context.Context, id string, attempt int, message string, retryAt *time.Time) error
+	// Get returns a job or ErrNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns jobs matching the options, newest first
+	List(ctx context.Context, options ListOptions) ([]*Job, error)
+	// Retry makes a failed or cancelled job pending again with a fresh set of attempts
+	Retry(ctx context.Context, id string) (*Job, error)
+	// Cancel cancels a pending job
+	Cancel(ctx context.Context, id string) (*Job, error)
+}
+
+// Options configures a queue
+type Options struct {
+	// Concurrency is how many jobs are processed at the same time (default 4)
+	Concurrency int
+	// PollInterval is how often the store is checked for due jobs (default 1s)
+	PollInterval time.Duration
+	// Lease is how long a job may run before it times out and another worker may claim it (default 5m)
+	Lease time.Duration
+	// MaxAttempts is the default number of attempts of a job (default 5)
+	MaxAttempts int
+	// Backoff returns the delay before retrying after a failed attempt (default exponential from 1s to 1h with jitter)
+	Backoff func(attempt int) time.Duration
+	// OnError is called when an attempt fails or the store cannot be reached
+	OnError func(job *Job, err error)
+}
+
+// EnqueueOptions schedules a job
+type EnqueueOptions struct {
+	// Delay postpones the first attempt
+	Delay time.Duration
+	// RunAt schedules the first attempt at a point in time; it takes precedence over Delay
+	RunAt time.Time
+	// MaxAttempts overrides the queue's default number of attempts
+	MaxAttempts int
+}
+
+// HandlerFunc processes the JSON payload of a job
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+// Queue enqueues jobs and runs registered workers against a store
+type Queue struct {
+	store   Store
+	options Options
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	wake     chan struct{}
+}
+
+// New creates a queue backed by store
+func New(store Store, options Options) *Queue {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.Lease <= 0 {
+		options.Lease = 5 * time.Minute
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 5
+	}
+	if options.Backoff == nil {
+		options.Backoff = DefaultBackoff
+	}
+	return &Queue{
+		store:    store,
+		options:  options,
+		handlers: map[string]HandlerFunc{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// DefaultBackoff doubles the delay with every attempt, from about 1s up to 1h, with jitter so
+// jobs that failed together are not retried together
+func DefaultBackoff(attempt int) time.Duration {
+	delay := time.Hour
+	if attempt < 13 {
+		delay = min(time.Second<<max(attempt-1, 0), time.Hour)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Store returns the queue's store
+func (q *Queue) Store() Store {
+	return q.store
+}
+
+// Handle registers the worker of a kind of job. Registering a kind twice is an error
+func (q *Queue) Handle(kind string, handler HandlerFunc) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if kind == "" {
+		return fmt.Errorf("job: kind is required")
+	}
+	if _, exists := q.handlers[kind]; exists {
+		return fmt.Errorf("job: a worker for %q is already registered", kind)
+	}
+	q.handlers[kind] = handler
+	return nil
+}
+
+// Enqueue stores a job for kind with payload encoded as JSON
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, options EnqueueOptions) (*Job, error) {
+	if kind == "" {
+		return nil, fmt.Errorf("job: kind is required")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("job: failed to encode %q payload: %w", kind, err)
+	}
+
+	now := time.Now()
+	runAt := options.RunAt
+	if runAt.IsZero() {
+		runAt = now.Add(options.Delay)
+	}
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.options.MaxAttempts
+	}
+
+	job := &Job{
+		ID:          newID(),
+		Kind:        kind,
+		Payload:     data,
+		Status:      StatusPending,
+		MaxAttempts: maxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := q.store.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("job: failed to enqueue %q: %w", kind, err)
+	}
+	if !runAt.After(now) {
+		q.notify()
+	}
+	return job, nil
+}
+
+// Retry makes a failed or cancelled job pending again
+func (q *Queue) Retry(ctx context.Context, id string) (*Job, error) {
+	job, err := q.store.Retry(ctx, id)
+	if err == nil {
+		q.notify()
+	}
+	return job, err
+}
+
+// Cancel cancels a pending job
+func (q *Queue) Cancel(ctx context.Context, id string) (*Job, error) {
+	return q.store.Cancel(ctx, id)
+}
+
+// Get returns a job
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	return q.store.Get(ctx, id)
+}
+
+// List returns jobs, newest first
+func (q *Queue) List(ctx context.Context, options ListOptions) ([]*Job, error) {
+	return q.store.List(ctx, options)
+}
+
+// notify wakes the run loop of this process so a due job doesn't wait for the next poll
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes jobs of the registered kinds until ctx is cancelled, then waits for the jobs
+// in progress. Jobs are not interrupted by the shutdown; each one is bounded by the lease
+func (q *Queue) Run(ctx context.Context) error {
+	q.mu.RLock()
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	q.mu.RUnlock()
+	if len(kinds) == 0 {
+		return fmt.Errorf("job: no workers registered")
+	}
+
+	slots := make(chan struct{}, q.options.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		case <-q.wake:
+		}
+
+		free := q.options.Concurrency - len(slots)
+		claimed := 0
+		if free > 0 {
+			jobs, err := q.store.Claim(ctx, kinds, free, q.options.Lease)
+			if err != nil && ctx.Err() == nil {
+				q.report(nil, fmt.Errorf("job: failed to claim jobs: %w", err))
+			}
+			claimed = len(jobs)
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer func() {
+						<-slots
+						wg.Done()
+						q.notify() // A slot is free again
+					}()
+					q.process(context.WithoutCancel(ctx), job)
+				}()
+			}
+		}
+
+		// Keep going while there is work and room for it, otherwise wait for the next poll
+		if claimed == 0 || claimed < free {
+			timer.Reset(q.options.PollInterval)
+		}
+	}
+}
+
+// process runs one claimed job and records its outcome
+func (q *Queue) process(ctx context.Context, job *Job) {
+	q.mu.RLock()
+	handler := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	jobCtx, cancel := context.WithTimeout(WithJob(ctx, job), q.options.Lease)
+	err := run(jobCtx, handler, job.Payload)
+	cancel()
+
+	if err == nil {
+		if err := q.store.Complete(ctx, job.ID, job.Attempts); err != nil {
+			q.report(job, fmt.Errorf("job: failed to complete: %w", err))
+		}
+		return
+	}
+
+	q.report(job, err)
+	var retryAt *time.Time
+	if job.Attempts < job.MaxAttempts && !errors.Is(err, errPermanent) {
+		at := time.Now().Add(q.options.Backoff(job.Attempts))
+		retryAt = &at
+	}
+	if err := q.store.Fail(ctx, job.ID, job.Attempts, err.Error(), retryAt); err != nil {
+		q.report(job, fmt.Errorf("job: failed to record failure: %w", err))
+	}
+}
+
+// run calls a handler, turning panics into errors
+func run(ctx context.Context, handler HandlerFunc, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job: worker panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+func (q *Queue) report(job *Job, err error) {
+	if q.options.OnError != nil {
+		q.options.OnError(job, err)
+	}
+}
+
+var errPermanent = errors.New("job: permanent failure")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() []error {
+	return []error{e.err, errPermanent}
+}
+
+// Permanent wraps an error so the job fails right away instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type jobKey struct{}
+
+// WithJob stores the job being processed in ctx
+func WithJob(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// FromContext returns the job a worker is processing, e.g. to read its ID or attempt number
+func FromContext(ctx context.Context) (*Job, bool) {
+	job, ok := ctx.Value(jobKey{}).(*Job)
+	return job, ok
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = crand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Definition describes a kind of job and the Go type of its payload
+type Definition interface {
+	Kind() string
+	PayloadType() reflect.Type
+}
+
+// Type is a kind of job with a typed payload, shared by the code enqueuing jobs and their worker
+type Type[T any] struct {
+	kind string
+}
+
+// Define creates a job type; kind identifies the jobs in the store and must be unique
+func Define[T any](kind string) Type[T] {
+	return Type[T]{kind: kind}
+}
+
+// Kind returns the kind jobs of this type are stored with
+func (t Type[T]) Kind() string {
+	return t.kind
+}
+
+// PayloadType returns the Go type of the payload
+func (t Type[T]) PayloadType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// Enqueue stores a job of this type; without options it runs as soon as a worker is free
+func (t Type[T]) Enqueue(ctx context.Context, queue *Queue, payload T, options ...EnqueueOptions) (*Job, error) {
+	var opts EnqueueOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return queue.Enqueue(ctx, t.kind, payload, opts)
+}
@@ -0,0 +1,192 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps jobs in process memory. Jobs are lost on restart, so it suits development,
+// tests and single-process deployments that can afford that
+type MemoryStore struct {
+	mu     sync.Mutex
+	jobs   map[string]*memoryJob
+	nextID int64
+}
+
+type memoryJob struct {
+	job         Job
+	lockedUntil time.Time
+	seq         int64
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*memoryJob)}
+}
+
+// Enqueue implements Store
+func (s *MemoryStore) Enqueue(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.jobs[job.ID] = &memoryJob{job: *job, seq: s.nextID}
+	return nil
+}
+
+// Claim implements Store
+func (s *MemoryStore) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due []*memoryJob
+	for _, entry := range s.jobs {
+		if !slices.Contains(kinds, entry.job.Kind) {
+			continue
+		}
+		pending := entry.job.Status == StatusPending && !entry.job.RunAt.After(now)
+		abandoned := entry.job.Status == StatusRunning && entry.lockedUntil.Before(now)
+		if pending || abandoned {
+			due = append(due, entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].job.RunAt.Equal(due[j].job.RunAt) {
+			return due[i].job.RunAt.Before(due[j].job.RunAt)
+		}
+		return due[i].seq < due[j].seq
+	})
+
+	claimed := make([]*Job, 0, min(limit, len(due)))
+	for _, entry := range due[:min(limit, len(due))] {
+		entry.job.Status = StatusRunning
+		entry.job.Attempts++
+		entry.job.UpdatedAt = now
+		entry.lockedUntil = now.Add(lease)
+		job := entry.job
+		claimed = append(claimed, &job)
+	}
+	return claimed, nil
+}
+
+// Complete implements Store
+func (s *MemoryStore) Complete(ctx context.Context, id string, attempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if entry.job.Status != StatusRunning || entry.job.Attempts != attempt {
+		return ErrClaimLost
+	}
+	entry.job.Status = StatusSucceeded
+	entry.job.UpdatedAt = time.Now()
+	return nil
+}
+
+// Fail implements Store
+func (s *MemoryStore) Fail(ctx context.Context, id string, attempt int, message string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if entry.job.Status != StatusRunning || entry.job.Attempts != attempt {
+		return ErrClaimLost
+	}
+	entry.job.LastError = message
+	entry.job.UpdatedAt = time.Now()
+	if retryAt != nil {
+		entry.job.Status = StatusPending
+		entry.job.RunAt = *retryAt
+	} else {
+		entry.job.Status = StatusFailed
+	}
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	job := entry.job
+	return &job, nil
+}
+
+// List implements Store
+func (s *MemoryStore) List(ctx context.Context, options ListOptions) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []*memoryJob
+	for _, entry := range s.jobs {
+		if options.Status != "" && entry.job.Status != options.Status {
+			continue
+		}
+		if options.Kind != "" && entry.job.Kind != options.Kind {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].seq > matches[j].seq
+	})
+
+	start := min(max(options.Offset, 0), len(matches))
+	end := len(matches)
+	if options.Limit > 0 {
+		end = min(start+options.Limit, end)
+	}
+	jobs := make([]*Job, 0, end-start)
+	for _, entry := range matches[start:end] {
+		job := entry.job
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// Retry implements Store
+func (s *MemoryStore) Retry(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if entry.job.Status != StatusFailed && entry.job.Status != StatusCancelled {
+		return nil, ErrInvalidState
+	}
+	now := time.Now()
+	entry.job.Status = StatusPending
+	entry.job.Attempts = 0
+	entry.job.RunAt = now
+	entry.job.UpdatedAt = now
+	job := entry.job
+	return &job, nil
+}
+
+// Cancel implements Store
+func (s *MemoryStore) Cancel(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if entry.job.Status != StatusPending {
+		return nil, ErrInvalidState
+	}
+	entry.job.Status = StatusCancelled
+	entry.job.UpdatedAt = time.Now()
+	job := entry.job
+	return &job, nil
+}
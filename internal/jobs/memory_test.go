@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreClaimOwnership(t *testing.T) {
+	retryAt := time.Now().Add(time.Minute)
+	tests := []struct {
+		name   string
+		finish func(store *MemoryStore, attempt int) error
+		status Status
+	}{
+		{"complete", func(store *MemoryStore, attempt int) error {
+			return store.Complete(context.Background(), "job-1", attempt)
+		}, StatusSucceeded},
+		{"fail with retry", func(store *MemoryStore, attempt int) error {
+			return store.Fail(context.Background(), "job-1", attempt, "boom", &retryAt)
+		}, StatusPending},
+		{"fail permanently", func(store *MemoryStore, attempt int) error {
+			return store.Fail(context.Background(), "job-1", attempt, "boom", nil)
+		}, StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			if err := store.Enqueue(context.Background(), &Job{ID: "job-1", Kind: "email", Status: StatusPending, MaxAttempts: 5}); err != nil {
+				t.Fatal(err)
+			}
+
+			// The first claim's lease runs out, so a second worker takes the job over
+			first, err := store.Claim(context.Background(), []string{"email"}, 1, -time.Second)
+			if err != nil || len(first) != 1 {
+				t.Fatalf("Claim() = %d jobs, %v", len(first), err)
+			}
+			second, err := store.Claim(context.Background(), []string{"email"}, 1, time.Minute)
+			if err != nil || len(second) != 1 {
+				t.Fatalf("Claim() of the abandoned job = %d jobs, %v", len(second), err)
+			}
+
+			if err := tt.finish(store, first[0].Attempts); !errors.Is(err, ErrClaimLost) {
+				t.Fatalf("stale claim error = %v, want %v", err, ErrClaimLost)
+			}
+			if job, _ := store.Get(context.Background(), "job-1"); job.Status != StatusRunning {
+				t.Fatalf("stale claim changed the status to %s", job.Status)
+			}
+
+			if err := tt.finish(store, second[0].Attempts); err != nil {
+				t.Fatalf("current claim error = %v", err)
+			}
+			if job, _ := store.Get(context.Background(), "job-1"); job.Status != tt.status {
+				t.Fatalf("status = %s, want %s", job.Status, tt.status)
+			}
+			if err := tt.finish(store, second[0].Attempts); !errors.Is(err, ErrClaimLost) {
+				t.Fatalf("finishing twice error = %v, want %v", err, ErrClaimLost)
+			}
+		})
+	}
+}
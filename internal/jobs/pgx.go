@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PgxExecutor is satisfied by *pgx.Conn, *pgxpool.Pool and pgx.Tx. A pool is recommended so
+// workers and enqueuers do not wait on each other
+type PgxExecutor interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, last_error, created_at, updated_at`
+
+// PgxStore keeps jobs in a PostgreSQL table. Workers in any number of processes claim jobs
+// with SELECT ... FOR UPDATE SKIP LOCKED, so a job is handed to one of them at a time
+type PgxStore struct {
+	db    PgxExecutor
+	table string
+}
+
+// NewPgxStore creates a store backed by the given table (default "goflux_jobs")
+func NewPgxStore(db PgxExecutor, table string) (*PgxStore, error) {
+	if table == "" {
+		table = "goflux_jobs"
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("job: invalid table name %q", table)
+	}
+	return &PgxStore{db: db, table: table}, nil
+}
+
+// CreateTable creates the jobs table and its index of due jobs if they don't exist
+func (s *PgxStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL,
+	run_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, s.table))
+	if err != nil {
+		return fmt.Errorf("job: failed to create table: %w", err)
+	}
+
+	index := strings.ReplaceAll(s.table, ".", "_") + "_due_idx"
+	_, err = s.db.Exec(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (run_at) WHERE status IN ('pending', 'running')`, index, s.table))
+	if err != nil {
+		return fmt.Errorf("job: failed to create index: %w", err)
+	}
+	return nil
+}
+
+// Enqueue implements Store
+func (s *PgxStore) Enqueue(ctx context.Context, job *Job) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (id, kind, payload, status, attempts, max_attempts, run_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $7)`, s.table),
+		job.ID, job.Kind, []byte(job.Payload), string(job.Status), job.MaxAttempts, job.RunAt, job.CreatedAt)
+	return err
+}
+
+// Claim implements Store
+func (s *PgxStore) Claim(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*Job, error) {
+	rows, err := s.db.Query(ctx, fmt.Sprintf(`UPDATE %[1]s SET status = 'running', attempts = attempts + 1,
+	locked_until = now() + make_interval(secs => $3), updated_at = now()
+WHERE id IN (
+	SELECT id FROM %[1]s
+	WHERE kind = ANY($1) AND (
+		(status = 'pending' AND run_at <= now()) OR
+		(status = 'running' AND locked_until < now())
+	)
+	ORDER BY run_at, created_at
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING %[2]s`, s.table, jobColumns), kinds, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+// Complete implements Store
+func (s *PgxStore) Complete(ctx context.Context, id string, attempt int) error {
+	tag, err := s.db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET status = 'succeeded', locked_until = NULL, updated_at = now()
+WHERE id = $1 AND status = 'running' AND attempts = $2`, s.table), id, attempt)
+	if err != nil {
+		return err
+	}
+	return s.checkUpdated(ctx, tag, id)
+}
+
+// Fail implements Store
+func (s *PgxStore) Fail(ctx context.Context, id string, attempt int, message string, retryAt *time.Time) error {
+	var tag pgconn.CommandTag
+	var err error
+	if retryAt != nil {
+		tag, err = s.db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET status = 'pending', run_at = $3, last_error = $4, locked_until = NULL, updated_at = now()
+WHERE id = $1 AND status = 'running' AND attempts = $2`, s.table), id, attempt, *retryAt, message)
+	} else {
+		tag, err = s.db.Exec(ctx, fmt.Sprintf(`UPDATE %s SET status = 'failed', last_error = $3, locked_until = NULL, updated_at = now()
+WHERE id = $1 AND status = 'running' AND attempts = $2`, s.table), id, attempt, message)
+	}
+	if err != nil {
+		return err
+	}
+	return s.checkUpdated(ctx, tag, id)
+}
+
+// Get implements Store
+func (s *PgxStore) Get(ctx context.Context, id string) (*Job, error) {
+	job, err := scanJob(s.db.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, jobColumns, s.table), id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return job, err
+}
+
+// List implements Store
+func (s *PgxStore) List(ctx context.Context, options ListOptions) ([]*Job, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+ORDER BY created_at DESC, id DESC OFFSET $3`, jobColumns, s.table)
+	args := []any{string(options.Status), options.Kind, max(options.Offset, 0)}
+	if options.Limit > 0 {
+		query += " LIMIT $4"
+		args = append(args, options.Limit)
+	}
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+// Retry implements Store
+func (s *PgxStore) Retry(ctx context.Context, id string) (*Job, error) {
+	return s.transition(ctx, id, fmt.Sprintf(`UPDATE %s SET status = 'pending', attempts = 0, run_at = now(), updated_at = now()
+WHERE id = $1 AND status IN ('failed', 'cancelled')
+RETURNING %s`, s.table, jobColumns))
+}
+
+// Cancel implements Store
+func (s *PgxStore) Cancel(ctx context.Context, id string) (*Job, error) {
+	return s.transition(ctx, id, fmt.Sprintf(`UPDATE %s SET status = 'cancelled', updated_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING %s`, s.table, jobColumns))
+}
+
+// transition runs a conditional update, telling a missing job apart from one in the wrong state
+func (s *PgxStore) transition(ctx context.Context, id, query string) (*Job, error) {
+	job, err := scanJob(s.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidState
+	}
+	return job, err
+}
+
+// checkUpdated tells a missing job apart from one that is no longer held by the claim that ran it
+func (s *PgxStore) checkUpdated(ctx context.Context, tag pgconn.CommandTag, id string) error {
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrClaimLost
+}
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+	var payload []byte
+	var status string
+	err := row.Scan(&job.ID, &job.Kind, &payload, &status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LastError, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	job.Status = Status(status)
+	return &job, nil
+}
+
+func collectJobs(rows pgx.Rows) ([]*Job, error) {
+	defer rows.Close()
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,260 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minRefreshInterval limits how often a stale cache or an unknown "kid" can trigger a refetch
+const minRefreshInterval = 30 * time.Second
+
+// jsonWebKey is a single entry of a JSON Web Key Set (RFC 7517)
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	K         string `json:"k"`
+}
+
+// jsonWebKeySet is the document served at a JWKS endpoint
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// verificationKey is a parsed key ready for signature checks
+type verificationKey struct {
+	kid       string
+	algorithm string
+	key       any // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// keySet caches keys loaded from a JWKS file or URL
+type keySet struct {
+	file   string
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	// refreshes collapses concurrent refetches into one request to the key source
+	refreshes singleflight.Group
+
+	mu          sync.RWMutex
+	keys        []verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func newKeySet(opts Options) *keySet {
+	ttl := opts.JWKSCacheTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &keySet{file: opts.JWKSFile, url: opts.JWKSURL, ttl: ttl, client: client}
+}
+
+// lookup finds a key by ID and algorithm, refreshing the cache when it is stale or the key is unknown.
+// Stale keys are served immediately while the cache refreshes in the background, so an unavailable
+// key source never delays requests signed with known keys
+func (s *keySet) lookup(ctx context.Context, kid, alg string) (any, error) {
+	s.mu.RLock()
+	stale := time.Since(s.fetchedAt) > s.ttl
+	key, found := s.find(kid, alg)
+	canRetry := time.Since(s.lastAttempt) > minRefreshInterval
+	s.mu.RUnlock()
+
+	if found {
+		if stale && canRetry {
+			go s.refreshShared(context.WithoutCancel(ctx))
+		}
+		return key, nil
+	}
+
+	// A key rotation may have introduced a new kid; wait for the refetch, at most once per interval
+	if canRetry {
+		if err := s.refreshShared(ctx); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+		key, found = s.find(kid, alg)
+		s.mu.RUnlock()
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+	}
+	return key, nil
+}
+
+// refreshShared reloads the key set, joining a refetch already in flight.
+// The refetch outlives callers that give up waiting so it can still update the cache for the others
+func (s *keySet) refreshShared(ctx context.Context) error {
+	result := s.refreshes.DoChan("keys", func() (any, error) {
+		return nil, s.refresh(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// find must be called with the lock held
+func (s *keySet) find(kid, alg string) (any, bool) {
+	for _, k := range s.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.algorithm != "" && k.algorithm != alg {
+			continue
+		}
+		if !keyMatchesAlgorithm(k.key, alg) {
+			continue
+		}
+		return k.key, true
+	}
+	return nil, false
+}
+
+// refresh reloads the key set from its source
+func (s *keySet) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	data, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("jwt: failed to parse JWKS: %w", err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Skip encryption keys and key types we can't use
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.parse()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, verificationKey{kid: jwk.KeyID, algorithm: jwk.Algorithm, key: key})
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("jwt: JWKS contains no usable signing keys")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// load reads the raw JWKS document
+func (s *keySet) load(ctx context.Context) ([]byte, error) {
+	if s.file != "" {
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: failed to read JWKS file: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: invalid JWKS URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	// Key sets are small; cap the read to avoid unbounded memory use
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parse converts a JWK into a verification key
+func (k jsonWebKey) parse() (any, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// keyMatchesAlgorithm reports whether a parsed key can verify the given algorithm
+func keyMatchesAlgorithm(key any, alg string) bool {
+	switch key.(type) {
+	case []byte:
+		return alg == HS256 || alg == HS384 || alg == HS512
+	case *rsa.PublicKey:
+		return alg == RS256 || alg == RS384 || alg == RS512
+	case ed25519.PublicKey:
+		return alg == EdDSA
+	}
+	return false
+}
@@ -0,0 +1,427 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/barisgit/goflux/internal/auth"
+)
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	EdDSA = "EdDSA"
+)
+
+// Common verification errors
+var (
+	ErrMissingToken         = errors.New("missing bearer token")
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported or disallowed signing algorithm")
+	ErrKeyNotFound          = errors.New("no key found to verify token")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrTokenNotYetValid     = errors.New("token is not valid yet")
+	ErrMissingExpiry        = errors.New("token has no expiry")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+)
+
+// Options configures token verification.
+// At least one key source (Secret, PublicKey, JWKSFile or JWKSURL) is required
+type Options struct {
+	// Algorithms restricts accepted "alg" values. Defaults to every algorithm the configured keys support
+	Algorithms []string
+	// Secret is the shared key for HS256/HS384/HS512
+	Secret []byte
+	// PublicKey is an *rsa.PublicKey or ed25519.PublicKey used when no JWKS is configured
+	PublicKey crypto.PublicKey
+	// JWKSFile loads keys from a JSON Web Key Set on disk
+	JWKSFile string
+	// JWKSURL loads keys from a remote JSON Web Key Set
+	JWKSURL string
+	// JWKSCacheTTL controls how long a loaded key set is reused (default 15 minutes)
+	JWKSCacheTTL time.Duration
+	// HTTPClient is used to fetch JWKSURL (default client with a 10 second timeout)
+	HTTPClient *http.Client
+	// Issuer, if set, must match the "iss" claim exactly
+	Issuer string
+	// Audience, if set, must intersect the "aud" claim
+	Audience []string
+	// Leeway tolerates clock skew when checking "exp", "nbf" and "iat"
+	Leeway time.Duration
+	// AllowMissingExpiry accepts tokens without an "exp" claim
+	AllowMissingExpiry bool
+	// Now overrides the clock, mainly for tests
+	Now func() time.Time
+}
+
+// Header is the decoded JOSE header of a token
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// Claims is the decoded claim set of a token
+type Claims map[string]any
+
+// Token is a verified JSON Web Token
+type Token struct {
+	Raw    string
+	Header Header
+	Claims Claims
+	// payload keeps the raw claim JSON for typed decoding
+	payload []byte
+}
+
+// Decode unmarshals the token claims into dst, e.g. a struct with json tags
+func (t *Token) Decode(dst any) error {
+	return json.Unmarshal(t.payload, dst)
+}
+
+// Principal converts the token into a generic authenticated principal
+func (t *Token) Principal() *auth.Principal {
+	return &auth.Principal{
+		Subject:     t.Claims.Subject(),
+		Scheme:      "bearer",
+		Scopes:      t.Claims.Scopes(),
+		Roles:       t.Claims.Strings("roles"),
+		Permissions: t.Claims.Strings("permissions"),
+		Claims:      t.Claims,
+	}
+}
+
+// Subject returns the "sub" claim
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the "iss" claim
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the "aud" claim, which may be a string or a list in the token
+func (c Claims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// Scopes returns the space separated "scope" claim, falling back to an "scp" list
+func (c Claims) Scopes() []string {
+	if s, ok := c["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	return c.Strings("scp")
+}
+
+// Strings returns a claim that may be encoded as a single string or a list of strings
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// numericDate returns a NumericDate claim as a time
+func (c Claims) numericDate(name string) (time.Time, bool) {
+	v, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := v.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// Verifier verifies tokens against configured keys and claim requirements
+type Verifier struct {
+	options    Options
+	algorithms []string
+	keys       *keySet
+}
+
+// New creates a verifier from the given options
+func New(opts Options) (*Verifier, error) {
+	if len(opts.Secret) == 0 && opts.PublicKey == nil && opts.JWKSFile == "" && opts.JWKSURL == "" {
+		return nil, fmt.Errorf("jwt: no verification key configured (set Secret, PublicKey, JWKSFile or JWKSURL)")
+	}
+	if opts.JWKSFile != "" && opts.JWKSURL != "" {
+		return nil, fmt.Errorf("jwt: JWKSFile and JWKSURL are mutually exclusive")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	v := &Verifier{options: opts}
+
+	switch key := opts.PublicKey.(type) {
+	case nil, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("jwt: unsupported public key type %T", key)
+	}
+
+	if opts.JWKSFile != "" || opts.JWKSURL != "" {
+		v.keys = newKeySet(opts)
+		// Fail fast on a broken key file; remote sets are fetched lazily
+		if opts.JWKSFile != "" {
+			if err := v.keys.refresh(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	v.algorithms = opts.Algorithms
+	if len(v.algorithms) == 0 {
+		if len(opts.Secret) > 0 {
+			v.algorithms = append(v.algorithms, HS256, HS384, HS512)
+		}
+		if opts.PublicKey != nil || v.keys != nil {
+			v.algorithms = append(v.algorithms, RS256, RS384, RS512, EdDSA)
+		}
+	}
+	for _, alg := range v.algorithms {
+		if !isSupported(alg) {
+			return nil, fmt.Errorf("jwt: unsupported algorithm %q", alg)
+		}
+	}
+
+	return v, nil
+}
+
+// VerifyRequest extracts the bearer token from an Authorization header value and verifies it
+func (v *Verifier) VerifyRequest(ctx context.Context, authorization string) (*Token, error) {
+	scheme, raw, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(raw) == "" {
+		return nil, ErrMissingToken
+	}
+	return v.Verify(ctx, strings.TrimSpace(raw))
+}
+
+// Verify parses the compact token, checks its signature and validates the registered claims
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	token := &Token{Raw: raw}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+	if err := json.Unmarshal(headerJSON, &token.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+
+	if !slices.Contains(v.algorithms, token.Header.Algorithm) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, token.Header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformedToken, err)
+	}
+
+	key, err := v.key(ctx, token.Header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(token.Header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	token.payload, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrMalformedToken, err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(token.payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&token.Claims); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrMalformedToken, err)
+	}
+
+	if err := v.validateClaims(token.Claims); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// key selects the verification key for a token header
+func (v *Verifier) key(ctx context.Context, header Header) (any, error) {
+	if strings.HasPrefix(header.Algorithm, "HS") {
+		if len(v.options.Secret) > 0 {
+			return v.options.Secret, nil
+		}
+		if v.keys != nil {
+			return v.keys.lookup(ctx, header.KeyID, header.Algorithm)
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	if v.keys != nil {
+		return v.keys.lookup(ctx, header.KeyID, header.Algorithm)
+	}
+	if v.options.PublicKey != nil {
+		return v.options.PublicKey, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// validateClaims checks expiry, not-before, issuer and audience
+func (v *Verifier) validateClaims(claims Claims) error {
+	now := v.options.Now()
+	leeway := v.options.Leeway
+
+	if exp, ok := claims.numericDate("exp"); ok {
+		if !now.Before(exp.Add(leeway)) {
+			return ErrTokenExpired
+		}
+	} else if !v.options.AllowMissingExpiry {
+		return ErrMissingExpiry
+	}
+
+	if nbf, ok := claims.numericDate("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return ErrTokenNotYetValid
+	}
+	if iat, ok := claims.numericDate("iat"); ok && now.Add(leeway).Before(iat) {
+		return ErrTokenNotYetValid
+	}
+
+	if v.options.Issuer != "" && claims.Issuer() != v.options.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if len(v.options.Audience) > 0 {
+		matched := false
+		for _, aud := range claims.Audience() {
+			if slices.Contains(v.options.Audience, aud) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// verifySignature checks the signature of the signing input with the given key
+func verifySignature(alg string, key any, signingInput, signature []byte) error {
+	switch alg {
+	case HS256, HS384, HS512:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		mac := hmac.New(hashFunc(alg), secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case RS256, RS384, RS512:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		h, cryptoHash := hashFunc(alg)(), rsaHash(alg)
+		h.Write(signingInput)
+		if err := rsa.VerifyPKCS1v15(publicKey, cryptoHash, h.Sum(nil), signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case EdDSA:
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		if !ed25519.Verify(publicKey, signingInput, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
+
+func hashFunc(alg string) func() hash.Hash {
+	switch alg[2:] {
+	case "384":
+		return sha512.New384
+	case "512":
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func rsaHash(alg string) crypto.Hash {
+	switch alg {
+	case RS384:
+		return crypto.SHA384
+	case RS512:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func isSupported(alg string) bool {
+	switch alg {
+	case HS256, HS384, HS512, RS256, RS384, RS512, EdDSA:
+		return true
+	}
+	return false
+}
+
+type tokenKey struct{}
+
+// WithToken returns a copy of ctx carrying the verified token
+func WithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext returns the verified token stored by the JWT middleware
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(tokenKey{}).(*Token)
+	return token, ok && token != nil
+}
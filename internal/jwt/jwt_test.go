@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("test-secret-of-at-least-32-bytes!")
+	testNow    = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// encodeSegment base64url-encodes a JSON header or claim set
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signHS256 creates a compact HS256 token
+func signHS256(t *testing.T, secret []byte, header map[string]any, claims Claims) string {
+	t.Helper()
+	input := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signEdDSA creates a compact EdDSA token
+func signEdDSA(t *testing.T, key ed25519.PrivateKey, header map[string]any, claims Claims) string {
+	t.Helper()
+	input := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(input)))
+}
+
+func validClaims() Claims {
+	return Claims{
+		"sub": "user-1",
+		"iss": "https://issuer.example.com",
+		"aud": "api",
+		"exp": testNow.Add(time.Hour).Unix(),
+		"iat": testNow.Add(-time.Minute).Unix(),
+	}
+}
+
+func withClaim(name string, value any) Claims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerifyHS256(t *testing.T) {
+	verifier, err := New(Options{
+		Secret:   testSecret,
+		Issuer:   "https://issuer.example.com",
+		Audience: []string{"api"},
+		Leeway:   30 * time.Second,
+		Now:      func() time.Time { return testNow },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs256 := map[string]any{"alg": HS256, "typ": "JWT"}
+	valid := signHS256(t, testSecret, hs256, validClaims())
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", valid, nil},
+		{"tampered payload", strings.Split(valid, ".")[0] + "." + encodeSegment(t, withClaim("sub", "admin")) + "." + strings.Split(valid, ".")[2], ErrInvalidSignature},
+		{"tampered signature", valid[:len(valid)-2] + "AA", ErrInvalidSignature},
+		{"wrong secret", signHS256(t, []byte("another-secret-of-32-bytes-long!!"), hs256, validClaims()), ErrInvalidSignature},
+		{"alg none", encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, validClaims()) + ".", ErrUnsupportedAlgorithm},
+		{"alg RS256 without public key", signHS256(t, testSecret, map[string]any{"alg": RS256}, validClaims()), ErrUnsupportedAlgorithm},
+		{"expired", signHS256(t, testSecret, hs256, withClaim("exp", testNow.Add(-time.Minute).Unix())), ErrTokenExpired},
+		{"expired within leeway", signHS256(t, testSecret, hs256, withClaim("exp", testNow.Add(-10*time.Second).Unix())), nil},
+		{"missing expiry", signHS256(t, testSecret, hs256, withClaim("exp", nil)), ErrMissingExpiry},
+		{"not yet valid", signHS256(t, testSecret, hs256, withClaim("nbf", testNow.Add(time.Hour).Unix())), ErrTokenNotYetValid},
+		{"issued in the future", signHS256(t, testSecret, hs256, withClaim("iat", testNow.Add(time.Hour).Unix())), ErrTokenNotYetValid},
+		{"wrong issuer", signHS256(t, testSecret, hs256, withClaim("iss", "https://evil.example.com")), ErrInvalidIssuer},
+		{"wrong audience", signHS256(t, testSecret, hs256, withClaim("aud", []string{"other"})), ErrInvalidAudience},
+		{"two segments", "a.b", ErrMalformedToken},
+		{"invalid header", "!!!." + encodeSegment(t, validClaims()) + ".sig", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && token.Claims.Subject() != "user-1" {
+				t.Fatalf("Verify() subject = %q, want user-1", token.Claims.Subject())
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	verifier, err := New(Options{Secret: testSecret, Now: func() time.Time { return testNow }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signHS256(t, testSecret, map[string]any{"alg": HS256}, validClaims())
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          error
+	}{
+		{"bearer", "Bearer " + token, nil},
+		{"lowercase scheme", "bearer " + token, nil},
+		{"missing", "", ErrMissingToken},
+		{"basic scheme", "Basic dXNlcjpwYXNz", ErrMissingToken},
+		{"empty token", "Bearer ", ErrMissingToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.VerifyRequest(context.Background(), tt.authorization)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("VerifyRequest() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := jsonWebKeySet{Keys: []jsonWebKey{
+		{KeyType: "OKP", Curve: "Ed25519", KeyID: "current", Algorithm: EdDSA, Use: "sig", X: base64.RawURLEncoding.EncodeToString(publicKey)},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := New(Options{JWKSFile: file, Now: func() time.Time { return testNow }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"known kid", signEdDSA(t, privateKey, map[string]any{"alg": EdDSA, "kid": "current"}, validClaims()), nil},
+		{"no kid", signEdDSA(t, privateKey, map[string]any{"alg": EdDSA}, validClaims()), nil},
+		{"unknown kid", signEdDSA(t, privateKey, map[string]any{"alg": EdDSA, "kid": "retired"}, validClaims()), ErrKeyNotFound},
+		{"signed by another key", signEdDSA(t, otherKey, map[string]any{"alg": EdDSA, "kid": "current"}, validClaims()), ErrInvalidSignature},
+		// A verifier without a secret must not accept HMAC tokens keyed with the public key
+		{"HS256 with public key", signHS256(t, publicKey, map[string]any{"alg": HS256, "kid": "current"}, validClaims()), ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeoutEnv overrides the default drain deadline, e.g. so `flux dev` can restart
+// the backend quickly
+const ShutdownTimeoutEnv = "FLUX_SHUTDOWN_TIMEOUT"
+
+var ready atomic.Bool
+
+func init() {
+	ready.Store(true)
+}
+
+// Ready reports whether the process accepts traffic. Serve keeps it false until startup has
+// finished and turns it false again once shutdown begins
+func Ready() bool {
+	return ready.Load()
+}
+
+// SetReady changes the readiness reported by Ready
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   []hook
+)
+
+// OnShutdown registers a teardown hook. Hooks run once the server has drained, in the reverse
+// order of registration, so resources are released after everything that was set up later
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook{name: name, fn: fn})
+}
+
+// Teardown runs and removes the registered hooks, last registered first. Every hook runs even
+// when an earlier one fails or ctx expires; their errors are joined
+func Teardown(ctx context.Context) error {
+	hooksMu.Lock()
+	pending := hooks
+	hooks = nil
+	hooksMu.Unlock()
+
+	var errs []error
+	for i := len(pending) - 1; i >= 0; i-- {
+		if err := pending[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("lifecycle: teardown of %s failed: %w", pending[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closer returns a teardown function for values with a Close or Shutdown method, nil for others
+func Closer(value any) func(ctx context.Context) error {
+	switch v := value.(type) {
+	case interface{ Shutdown(context.Context) error }:
+		return v.Shutdown
+	case interface{ Close(context.Context) error }:
+		return v.Close
+	case io.Closer:
+		return func(context.Context) error { return v.Close() }
+	case interface{ Close() }:
+		return func(context.Context) error {
+			v.Close()
+			return nil
+		}
+	}
+	return nil
+}
+
+// Server is a server that can be started on a listener and shut down gracefully.
+// *http.Server implements it; adapters wrap servers of other routers
+type Server interface {
+	Serve(listener net.Listener) error
+	Shutdown(ctx context.Context) error
+}
+
+// Options configures Serve
+type Options struct {
+	// ShutdownTimeout is how long in-flight requests, background services and teardown hooks get
+	// once shutdown begins (default 15 seconds, or the FLUX_SHUTDOWN_TIMEOUT environment variable)
+	ShutdownTimeout time.Duration
+	// DrainDelay keeps accepting requests for a while after readiness turns false, so load
+	// balancers polling the readiness endpoint stop routing traffic before the listener closes
+	DrainDelay time.Duration
+	// OnListen is called once the listener is open
+	OnListen func(addr net.Addr)
+	// Startup runs once the listener is open, e.g. to warm up connection pools. Readiness stays
+	// false until it succeeds, so load balancers hold traffic back; when it fails Serve shuts down
+	Startup func(ctx context.Context) error
+	// Services run next to the server, once Startup has succeeded, until shutdown begins. Serve
+	// waits for them to return before running the teardown hooks
+	Services []func(ctx context.Context) error
+}
+
+// Serve listens on addr and serves until ctx is cancelled or the process receives SIGINT or SIGTERM.
+// Readiness turns on once Startup has succeeded. On shutdown Serve turns readiness off, waits
+// DrainDelay, lets in-flight requests finish, stops the services and runs the teardown hooks, all
+// within ShutdownTimeout. A second signal exits immediately
+func Serve(ctx context.Context, server Server, addr string, options Options) error {
+	if options.ShutdownTimeout <= 0 {
+		options.ShutdownTimeout = 15 * time.Second
+		if value := os.Getenv(ShutdownTimeoutEnv); value != "" {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("lifecycle: invalid %s %q: %w", ShutdownTimeoutEnv, value, err)
+			}
+			options.ShutdownTimeout = timeout
+		}
+	}
+
+	SetReady(false)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("lifecycle: failed to listen on %s: %w", addr, err)
+	}
+	if options.OnListen != nil {
+		options.OnListen(listener.Addr())
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	servicesCtx, stopServices := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopServices()
+	var services sync.WaitGroup
+	serviceErrs := make([]error, len(options.Services))
+	startServices := func() {
+		SetReady(true)
+		for i, service := range options.Services {
+			services.Add(1)
+			go func() {
+				defer services.Done()
+				serviceErrs[i] = service(servicesCtx)
+			}()
+		}
+	}
+
+	startupErr := make(chan error, 1)
+	if options.Startup != nil {
+		go func() {
+			startupErr <- options.Startup(servicesCtx)
+		}()
+	} else {
+		startServices()
+	}
+
+	var errs []error
+wait:
+	for {
+		select {
+		case <-signalCtx.Done():
+			break wait
+		case err := <-serveErr:
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs = append(errs, fmt.Errorf("lifecycle: server failed: %w", err))
+			}
+			break wait
+		case err := <-startupErr:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("lifecycle: startup failed: %w", err))
+				break wait
+			}
+			startServices()
+		}
+	}
+	// Restore the default signal behaviour so a second Ctrl+C kills the process
+	stopSignals()
+
+	SetReady(false)
+	if options.DrainDelay > 0 && len(errs) == 0 {
+		time.Sleep(options.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), options.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("lifecycle: failed to drain requests: %w", err))
+	}
+
+	stopServices()
+	stopped := make(chan struct{})
+	go func() {
+		services.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		for _, err := range serviceErrs {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case <-shutdownCtx.Done():
+		errs = append(errs, fmt.Errorf("lifecycle: background services did not stop in time"))
+	}
+
+	if err := Teardown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
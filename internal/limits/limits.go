@@ -0,0 +1,238 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension is the OpenAPI operation extension describing the limits of an operation
+const Extension = "x-goflux-limits"
+
+// TimeoutMetadataKey is the operation metadata key of a per-operation timeout override
+const TimeoutMetadataKey = "goflux.timeout"
+
+// StreamingMetadataKey marks long-lived operations, such as event streams and WebSockets, whose
+// timeout only bounds reading the request: the handler runs for as long as the stream is open
+const StreamingMetadataKey = "goflux.streaming"
+
+// MultipartMaxMemory is the part of a multipart form kept in memory, the rest is stored in temporary
+// files. It matches huma's adapters
+const MultipartMaxMemory = 8 * 1024
+
+// Limit errors, mapped to their responses by Status
+var (
+	ErrBodyTooLarge = errors.New("request body too large")
+	ErrReadTimeout  = errors.New("request body read timed out")
+)
+
+// Limits bounds the requests of an operation. Zero values mean no limit
+type Limits struct {
+	// MaxBodyBytes is the largest accepted request body, uploads included
+	MaxBodyBytes int64
+	// Timeout bounds reading the request and running the handler
+	Timeout time.Duration
+	// Streaming limits the timeout to reading the request, set for operations marked with
+	// StreamingMetadataKey
+	Streaming bool
+}
+
+// IsZero reports whether no limit is set
+func (l Limits) IsZero() bool {
+	return l.MaxBodyBytes <= 0 && l.Timeout <= 0
+}
+
+// ForOperation applies the per-operation overrides: huma's Operation.MaxBodyBytes and the timeout
+// stored under TimeoutMetadataKey. Positive values replace the limit, negative values remove it
+func (l Limits) ForOperation(operation *huma.Operation) Limits {
+	if operation.MaxBodyBytes != 0 {
+		l.MaxBodyBytes = max(operation.MaxBodyBytes, 0)
+	}
+	if timeout, ok := operation.Metadata[TimeoutMetadataKey].(time.Duration); ok && timeout != 0 {
+		l.Timeout = max(timeout, 0)
+	}
+	if streaming, _ := operation.Metadata[StreamingMetadataKey].(bool); streaming {
+		l.Streaming = true
+	}
+	return l
+}
+
+// Spec is the extension describing the limits of an operation
+type Spec struct {
+	MaxBodyBytes int64 `json:"maxBodyBytes,omitempty"`
+	TimeoutMs    int64 `json:"timeoutMs,omitempty"`
+}
+
+// Annotate documents the limits of an operation and the responses they cause
+func (l Limits) Annotate(operation *huma.Operation) {
+	if l.IsZero() {
+		return
+	}
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = Spec{MaxBodyBytes: l.MaxBodyBytes, TimeoutMs: l.Timeout.Milliseconds()}
+
+	var statuses []int
+	if l.MaxBodyBytes > 0 {
+		statuses = append(statuses, http.StatusRequestEntityTooLarge)
+	}
+	if l.Timeout > 0 {
+		statuses = append(statuses, http.StatusRequestTimeout)
+		if !l.Streaming {
+			statuses = append(statuses, http.StatusServiceUnavailable)
+		}
+	}
+	for _, status := range statuses {
+		if !slices.Contains(operation.Errors, status) {
+			operation.Errors = append(operation.Errors, status)
+		}
+	}
+}
+
+// Wrap applies the limits to a request: the body is read through a limited reader and the request
+// context gets the deadline. Streaming operations keep their request context and only the body
+// reader gets the deadline. The cancel function must be called once the request is done. Requests
+// announcing a body larger than the limit fail right away with ErrBodyTooLarge
+func (l Limits) Wrap(ctx huma.Context) (huma.Context, context.CancelFunc, error) {
+	if l.MaxBodyBytes > 0 {
+		if length, err := strconv.ParseInt(ctx.Header("Content-Length"), 10, 64); err == nil && length > l.MaxBodyBytes {
+			return ctx, func() {}, ErrBodyTooLarge
+		}
+	}
+
+	requestCtx, cancel := ctx.Context(), context.CancelFunc(func() {})
+	readCtx := requestCtx
+	if l.Timeout > 0 {
+		deadline := time.Now().Add(l.Timeout)
+		readCtx, cancel = context.WithDeadline(requestCtx, deadline)
+		if !l.Streaming {
+			requestCtx = readCtx
+			// Not every response writer supports read deadlines; the reader checks the context as well.
+			// Streams skip it, a hijacked WebSocket connection would keep the deadline
+			_ = ctx.SetReadDeadline(deadline)
+		}
+	}
+
+	wrapped := &limitedContext{
+		humaContext: ctx,
+		body:        &reader{ctx: readCtx, remaining: l.MaxBodyBytes, limited: l.MaxBodyBytes > 0},
+	}
+	if body := ctx.BodyReader(); body != nil {
+		wrapped.body.r = body
+	}
+	return huma.WithContext(wrapped, requestCtx), cancel, nil
+}
+
+// Status returns the response status of a limit error: 413 for too large bodies and 408 for bodies
+// that were not received in time
+func Status(err error) (int, bool) {
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge, true
+	case errors.Is(err, ErrReadTimeout), errors.Is(err, os.ErrDeadlineExceeded):
+		return http.StatusRequestTimeout, true
+	}
+	return 0, false
+}
+
+// TimedOut reports whether the deadline of the request context passed
+func TimedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// humaContext names the embedded context, whose field would otherwise hide its Context method
+type humaContext = huma.Context
+
+// limitedContext reads the body, multipart forms included, through the limited reader
+type limitedContext struct {
+	humaContext
+	body *reader
+	form *multipart.Form
+}
+
+// BodyReader returns the limited body
+func (c *limitedContext) BodyReader() io.Reader {
+	if c.body.r == nil {
+		return nil
+	}
+	return c.body
+}
+
+// GetMultipartForm parses the multipart form from the limited body, so uploads are bounded too
+func (c *limitedContext) GetMultipartForm() (*multipart.Form, error) {
+	if c.form != nil {
+		return c.form, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(c.Header("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, http.ErrNotMultipart
+	}
+	boundary, ok := params["boundary"]
+	if !ok || c.body.r == nil {
+		return nil, http.ErrMissingBoundary
+	}
+	form, err := multipart.NewReader(c.body, boundary).ReadForm(MultipartMaxMemory)
+	if err != nil {
+		return nil, err
+	}
+	c.form = form
+	return form, nil
+}
+
+// Unwrap returns the wrapped context
+func (c *limitedContext) Unwrap() huma.Context {
+	return c.humaContext
+}
+
+// reader fails with ErrBodyTooLarge past the limit and with ErrReadTimeout past the deadline
+type reader struct {
+	r         io.Reader
+	ctx       context.Context
+	remaining int64
+	limited   bool
+	err       error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if TimedOut(r.ctx) {
+		r.err = ErrReadTimeout
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Read one byte more than allowed to tell a body of exactly the limit from a larger one
+	if r.limited && int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.r.Read(p)
+	if r.limited {
+		if int64(n) > r.remaining {
+			n = int(r.remaining)
+			r.remaining = 0
+			r.err = ErrBodyTooLarge
+			return n, r.err
+		}
+		r.remaining -= int64(n)
+	}
+	if err != nil && err != io.EOF && (errors.Is(err, os.ErrDeadlineExceeded) || TimedOut(r.ctx)) {
+		r.err = ErrReadTimeout
+		return n, r.err
+	}
+	return n, err
+}
@@ -0,0 +1,406 @@
+package oidc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barisgit/goflux/internal/jwt"
+)
+
+// MockUser is an account offered by the mock provider
+type MockUser struct {
+	Subject string
+	Email   string
+	Name    string
+	Roles   []string
+	// Claims are added to the ID and access tokens as-is
+	Claims map[string]any
+}
+
+// MockOptions configures the mock provider
+type MockOptions struct {
+	// Issuer is the absolute URL the provider is served at, e.g. "http://localhost:3000/api/_oidc" (required)
+	Issuer string
+	// Users that can sign in. Defaults to a single "dev-user" account
+	Users []MockUser
+	// ClientID, if set, is the only client allowed to sign in
+	ClientID string
+	// TokenTTL controls ID and access token lifetimes (default 1 hour)
+	TokenTTL time.Duration
+}
+
+// MockProvider is a minimal in-process OpenID provider for development and tests.
+// It implements discovery, the authorization code flow with PKCE, JWKS and userinfo.
+// Users are signed in without a password: with a single user the authorize step
+// redirects immediately, otherwise a user picker is shown (or use the login_hint parameter)
+type MockProvider struct {
+	options MockOptions
+	prefix  string
+	keyID   string
+	private ed25519.PrivateKey
+	// access verifies the access tokens presented to the userinfo endpoint
+	access *jwt.Verifier
+
+	mu    sync.Mutex
+	codes map[string]*mockGrant
+}
+
+// mockGrant is an issued authorization code waiting to be exchanged
+type mockGrant struct {
+	user        MockUser
+	clientID    string
+	redirectURI string
+	nonce       string
+	scope       string
+	challenge   string
+	expiresAt   time.Time
+}
+
+// NewMockProvider creates a mock provider with a fresh signing key
+func NewMockProvider(opts MockOptions) (*MockProvider, error) {
+	issuer, err := url.Parse(opts.Issuer)
+	if err != nil || issuer.Scheme == "" || issuer.Host == "" {
+		return nil, fmt.Errorf("oidc: mock provider needs an absolute Issuer URL")
+	}
+	opts.Issuer = strings.TrimSuffix(opts.Issuer, "/")
+	if len(opts.Users) == 0 {
+		opts.Users = []MockUser{{Subject: "dev-user", Email: "dev@example.com", Name: "Dev User"}}
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to generate mock signing key: %w", err)
+	}
+
+	access, err := jwt.New(jwt.Options{
+		PublicKey: private.Public(),
+		Issuer:    opts.Issuer,
+		Audience:  []string{opts.Issuer + "/userinfo"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MockProvider{
+		options: opts,
+		prefix:  strings.TrimSuffix(issuer.Path, "/"),
+		keyID:   randomString()[:16],
+		private: private,
+		access:  access,
+		codes:   make(map[string]*mockGrant),
+	}, nil
+}
+
+// Issuer returns the provider's issuer URL
+func (m *MockProvider) Issuer() string {
+	return m.options.Issuer
+}
+
+// ServeHTTP routes provider requests. Mount the provider at its issuer path, e.g.
+// mux.Handle("/api/_oidc/", provider) for the issuer "http://localhost:3000/api/_oidc"
+func (m *MockProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, m.prefix) {
+	case "/.well-known/openid-configuration":
+		m.discovery(w)
+	case "/authorize":
+		m.authorize(w, r)
+	case "/token":
+		m.token(w, r)
+	case "/jwks":
+		m.jwks(w)
+	case "/userinfo":
+		m.userinfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// IssueIDToken signs an ID token for the user, e.g. to call protected endpoints in tests
+// with an Authorization: Bearer header
+func (m *MockProvider) IssueIDToken(user MockUser, audience, nonce string) (string, error) {
+	claims := m.userClaims(user)
+	claims["aud"] = audience
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return m.sign(claims)
+}
+
+func (m *MockProvider) discovery(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                m.options.Issuer,
+		"authorization_endpoint":                m.options.Issuer + "/authorize",
+		"token_endpoint":                        m.options.Issuer + "/token",
+		"jwks_uri":                              m.options.Issuer + "/jwks",
+		"userinfo_endpoint":                     m.options.Issuer + "/userinfo",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.EdDSA},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_basic", "client_secret_post"},
+	})
+}
+
+var pickerTemplate = template.Must(template.New("picker").Parse(`<!doctype html>
+<html><head><title>Mock sign in</title></head>
+<body style="font-family: sans-serif; max-width: 28rem; margin: 4rem auto">
+<h1>Mock identity provider</h1>
+<p>Choose an account to sign in as:</p>
+<ul>{{range .}}<li><a href="{{.URL}}">{{.Name}}</a> &lt;{{.Email}}&gt; <small>{{.Subject}}</small></li>{{end}}</ul>
+</body></html>`))
+
+func (m *MockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || !target.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	// From here on errors are reported to the client via its redirect URI
+	fail := func(code, description string) {
+		values := target.Query()
+		values.Set("error", code)
+		values.Set("error_description", description)
+		values.Set("state", query.Get("state"))
+		target.RawQuery = values.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+
+	if query.Get("response_type") != "code" {
+		fail("unsupported_response_type", "only the authorization code flow is supported")
+		return
+	}
+	if m.options.ClientID != "" && query.Get("client_id") != m.options.ClientID {
+		fail("unauthorized_client", "unknown client_id")
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		fail("invalid_request", "PKCE with code_challenge_method S256 is required")
+		return
+	}
+
+	user, ok := m.findUser(query.Get("login_hint"))
+	if !ok {
+		type choice struct {
+			MockUser
+			URL string
+		}
+		choices := make([]choice, 0, len(m.options.Users))
+		for _, u := range m.options.Users {
+			values := url.Values{}
+			for key, v := range query {
+				values[key] = v
+			}
+			values.Set("login_hint", u.Subject)
+			choices = append(choices, choice{MockUser: u, URL: "?" + values.Encode()})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = pickerTemplate.Execute(w, choices)
+		return
+	}
+
+	code := randomString()
+	m.mu.Lock()
+	now := time.Now()
+	for c, grant := range m.codes {
+		if now.After(grant.expiresAt) {
+			delete(m.codes, c)
+		}
+	}
+	m.codes[code] = &mockGrant{
+		user:        user,
+		clientID:    query.Get("client_id"),
+		redirectURI: redirectURI,
+		nonce:       query.Get("nonce"),
+		scope:       query.Get("scope"),
+		challenge:   query.Get("code_challenge"),
+		expiresAt:   now.Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	values := target.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	target.RawQuery = values.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (m *MockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", "malformed form body")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	grant, ok := m.codes[code]
+	delete(m.codes, code) // codes are single use
+	m.mu.Unlock()
+	if !ok || time.Now().After(grant.expiresAt) {
+		writeTokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if basicID, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(basicID)
+	}
+	if clientID != grant.clientID || r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		writeTokenError(w, "invalid_grant", "client_id or redirect_uri does not match the authorization request")
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(grant.challenge)) != 1 {
+		writeTokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	idToken, err := m.IssueIDToken(grant.user, grant.clientID, grant.nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accessClaims := m.userClaims(grant.user)
+	accessClaims["aud"] = m.options.Issuer + "/userinfo"
+	accessClaims["scope"] = grant.scope
+	accessToken, err := m.sign(accessClaims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(m.options.TokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       grant.scope,
+	})
+}
+
+func (m *MockProvider) jwks(w http.ResponseWriter) {
+	public := m.private.Public().(ed25519.PublicKey)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"alg": jwt.EdDSA,
+			"use": "sig",
+			"kid": m.keyID,
+			"x":   base64.RawURLEncoding.EncodeToString(public),
+		}},
+	})
+}
+
+func (m *MockProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	token, err := m.access.VerifyRequest(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := m.findUser(token.Claims.Subject())
+	if !ok {
+		http.Error(w, "unknown user", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, m.profile(user))
+}
+
+// findUser looks a user up by subject or email. With a single user, an empty hint selects it
+func (m *MockProvider) findUser(hint string) (MockUser, bool) {
+	if hint == "" && len(m.options.Users) == 1 {
+		return m.options.Users[0], true
+	}
+	for _, user := range m.options.Users {
+		if hint != "" && (user.Subject == hint || strings.EqualFold(user.Email, hint)) {
+			return user, true
+		}
+	}
+	return MockUser{}, false
+}
+
+// profile returns the user's standard and custom claims
+func (m *MockProvider) profile(user MockUser) map[string]any {
+	claims := map[string]any{"sub": user.Subject}
+	for key, value := range user.Claims {
+		claims[key] = value
+	}
+	if user.Email != "" {
+		claims["email"] = user.Email
+		claims["email_verified"] = true
+	}
+	if user.Name != "" {
+		claims["name"] = user.Name
+	}
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
+	return claims
+}
+
+// userClaims returns the profile plus the registered token claims
+func (m *MockProvider) userClaims(user MockUser) map[string]any {
+	now := time.Now()
+	claims := m.profile(user)
+	claims["iss"] = m.options.Issuer
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(m.options.TokenTTL).Unix()
+	return claims
+}
+
+func (m *MockProvider) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(jwt.Header{Algorithm: jwt.EdDSA, KeyID: m.keyID, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("oidc: failed to encode claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(m.private, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusBadRequest, ProviderError{Code: code, Description: description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
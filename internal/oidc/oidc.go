@@ -0,0 +1,389 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barisgit/goflux/internal/jwt"
+	"github.com/barisgit/goflux/internal/session"
+)
+
+// Common OIDC errors
+var (
+	ErrInvalidState   = errors.New("oidc: invalid or expired login state")
+	ErrMissingCode    = errors.New("oidc: missing authorization code")
+	ErrInvalidNonce   = errors.New("oidc: ID token nonce does not match")
+	ErrMissingIDToken = errors.New("oidc: token response has no id_token")
+)
+
+// Session keys used to remember an in-flight login
+const (
+	stateKey    = "oidc_state"
+	nonceKey    = "oidc_nonce"
+	verifierKey = "oidc_verifier"
+	returnToKey = "oidc_return_to"
+)
+
+// Options configures the relying party
+type Options struct {
+	// Issuer is the provider's issuer URL, used for discovery (required)
+	Issuer string
+	// ClientID registered with the provider (required)
+	ClientID string
+	// ClientSecret for confidential clients; public clients rely on PKCE alone
+	ClientSecret string
+	// RedirectURL is the absolute URL of the callback endpoint (required)
+	RedirectURL string
+	// Scopes requested during login (default "openid", "profile", "email")
+	Scopes []string
+	// DefaultReturnTo is where users land after login when no return path was given (default "/")
+	DefaultReturnTo string
+	// HTTPClient is used for discovery, token and JWKS requests (default client with a 10 second timeout)
+	HTTPClient *http.Client
+}
+
+// Provider holds the endpoints published in the provider's discovery document
+type Provider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint,omitempty"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint,omitempty"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// TokenResponse is the provider's answer to an authorization code exchange
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	IDToken      string `json:"id_token"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// ProviderError is an OAuth2 error returned by the provider, either on the callback or the token endpoint
+type ProviderError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *ProviderError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oidc: provider error %s: %s", e.Code, e.Description)
+	}
+	return "oidc: provider error " + e.Code
+}
+
+// LoginResult describes a completed login
+type LoginResult struct {
+	// IDToken is the verified ID token
+	IDToken *jwt.Token
+	// Tokens is the raw token response, including the access and refresh tokens
+	Tokens *TokenResponse
+	// ReturnTo is the local path the user should be redirected to
+	ReturnTo string
+}
+
+// RelyingParty implements the authorization code flow with PKCE against an OpenID provider.
+// Discovery happens on first use, so the provider may be served by the same process
+type RelyingParty struct {
+	options Options
+
+	mu       sync.Mutex
+	provider *Provider
+	verifier *jwt.Verifier
+}
+
+// New creates a relying party
+func New(opts Options) (*RelyingParty, error) {
+	if opts.Issuer == "" || opts.ClientID == "" || opts.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: Issuer, ClientID and RedirectURL are required")
+	}
+	if _, err := url.Parse(opts.RedirectURL); err != nil {
+		return nil, fmt.Errorf("oidc: invalid RedirectURL: %w", err)
+	}
+	opts.Issuer = strings.TrimSuffix(opts.Issuer, "/")
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{"openid", "profile", "email"}
+	}
+	if opts.DefaultReturnTo == "" {
+		opts.DefaultReturnTo = "/"
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RelyingParty{options: opts}, nil
+}
+
+// Options returns the relying party options with defaults applied
+func (rp *RelyingParty) Options() Options {
+	return rp.options
+}
+
+// DiscoveryURL returns the provider's OpenID configuration URL
+func (rp *RelyingParty) DiscoveryURL() string {
+	return rp.options.Issuer + "/.well-known/openid-configuration"
+}
+
+// Discover fetches and caches the provider's discovery document
+func (rp *RelyingParty) Discover(ctx context.Context) (*Provider, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.provider != nil {
+		return rp.provider, nil
+	}
+
+	var provider Provider
+	if err := rp.getJSON(ctx, rp.DiscoveryURL(), &provider); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != rp.options.Issuer {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", provider.Issuer, rp.options.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery document is missing required endpoints")
+	}
+
+	verifier, err := jwt.New(jwt.Options{
+		Algorithms: supportedAlgorithms(provider.SigningAlgorithms),
+		JWKSURL:    provider.JWKSURI,
+		HTTPClient: rp.options.HTTPClient,
+		Issuer:     provider.Issuer,
+		Audience:   []string{rp.options.ClientID},
+		Leeway:     time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rp.provider = &provider
+	rp.verifier = verifier
+	return rp.provider, nil
+}
+
+// Verifier returns a JWT verifier for tokens issued to this client by the provider
+func (rp *RelyingParty) Verifier(ctx context.Context) (*jwt.Verifier, error) {
+	if _, err := rp.Discover(ctx); err != nil {
+		return nil, err
+	}
+	return rp.verifier, nil
+}
+
+// BeginLogin starts an authorization code flow. The state, nonce and PKCE verifier are
+// remembered in the session, and the URL to redirect the user to is returned
+func (rp *RelyingParty) BeginLogin(ctx context.Context, sess *session.Session, returnTo string) (string, error) {
+	provider, err := rp.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, nonce, verifier := randomString(), randomString(), randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	sess.Set(stateKey, state)
+	sess.Set(nonceKey, nonce)
+	sess.Set(verifierKey, verifier)
+	sess.Set(returnToKey, rp.safeReturnTo(returnTo))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.options.ClientID},
+		"redirect_uri":          {rp.options.RedirectURL},
+		"scope":                 {strings.Join(rp.options.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// CompleteLogin handles the provider's callback: it checks the state, exchanges the code,
+// verifies the ID token and logs the session in as the token's subject.
+// The "email", "name", "roles" and "permissions" claims are copied into the session
+func (rp *RelyingParty) CompleteLogin(ctx context.Context, sess *session.Session, callback url.Values) (*LoginResult, error) {
+	state, nonce, verifier := sess.GetString(stateKey), sess.GetString(nonceKey), sess.GetString(verifierKey)
+	returnTo := sess.GetString(returnToKey)
+	for _, key := range []string{stateKey, nonceKey, verifierKey, returnToKey} {
+		sess.Delete(key)
+	}
+
+	if code := callback.Get("error"); code != "" {
+		return nil, &ProviderError{Code: code, Description: callback.Get("error_description")}
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(callback.Get("state"))) != 1 {
+		return nil, ErrInvalidState
+	}
+	code := callback.Get("code")
+	if code == "" {
+		return nil, ErrMissingCode
+	}
+
+	tokens, err := rp.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := rp.VerifyIDToken(ctx, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.Login(idToken.Claims.Subject())
+	for _, claim := range []string{"email", "name"} {
+		if value, ok := idToken.Claims[claim].(string); ok {
+			sess.Set(claim, value)
+		}
+	}
+	for _, claim := range []string{"roles", "permissions"} {
+		if values := idToken.Claims.Strings(claim); len(values) > 0 {
+			sess.Set(claim, values)
+		}
+	}
+
+	if returnTo == "" {
+		returnTo = rp.options.DefaultReturnTo
+	}
+	return &LoginResult{IDToken: idToken, Tokens: tokens, ReturnTo: returnTo}, nil
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens
+func (rp *RelyingParty) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	provider, err := rp.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.options.RedirectURL},
+		"client_id":     {rp.options.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if rp.options.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(rp.options.ClientID), url.QueryEscape(rp.options.ClientSecret))
+	}
+
+	resp, err := rp.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		providerErr := &ProviderError{}
+		if json.Unmarshal(body, providerErr) == nil && providerErr.Code != "" {
+			return nil, providerErr
+		}
+		return nil, fmt.Errorf("oidc: token endpoint returned %s", resp.Status)
+	}
+
+	var tokens TokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("oidc: invalid token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken verifies an ID token's signature, issuer, audience and expiry, and
+// checks its nonce when one is given
+func (rp *RelyingParty) VerifyIDToken(ctx context.Context, raw, nonce string) (*jwt.Token, error) {
+	verifier, err := rp.Verifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != "" {
+		claimed, _ := token.Claims["nonce"].(string)
+		if subtle.ConstantTimeCompare([]byte(claimed), []byte(nonce)) != 1 {
+			return nil, ErrInvalidNonce
+		}
+	}
+	return token, nil
+}
+
+// safeReturnTo only allows local absolute paths, preventing open redirects
+func (rp *RelyingParty) safeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return rp.options.DefaultReturnTo
+	}
+	return returnTo
+}
+
+func (rp *RelyingParty) getJSON(ctx context.Context, target string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := rp.options.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+// supportedAlgorithms keeps the advertised signing algorithms the JWT verifier understands
+func supportedAlgorithms(advertised []string) []string {
+	var algorithms []string
+	for _, alg := range advertised {
+		switch alg {
+		case jwt.RS256, jwt.RS384, jwt.RS512, jwt.EdDSA:
+			algorithms = append(algorithms, alg)
+		}
+	}
+	if len(algorithms) == 0 {
+		// RS256 is mandatory for OpenID providers
+		return []string{jwt.RS256}
+	}
+	return algorithms
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("oidc: failed to read random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,178 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/barisgit/goflux/internal/session"
+)
+
+// newTestProvider serves a mock provider and returns a relying party using it
+func newTestProvider(t *testing.T) *RelyingParty {
+	t.Helper()
+	var provider *MockProvider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	var err error
+	provider, err = NewMockProvider(MockOptions{Issuer: server.URL + "/oidc", ClientID: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	rp, err := New(Options{
+		Issuer:      provider.Issuer(),
+		ClientID:    "app",
+		RedirectURL: "http://app.example.com/auth/callback",
+		HTTPClient:  client,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rp
+}
+
+// authorize begins a login and follows the provider's redirect, returning the callback parameters
+func authorize(t *testing.T, rp *RelyingParty, sess *session.Session, returnTo string) url.Values {
+	t.Helper()
+	target, err := rp.BeginLogin(context.Background(), sess, returnTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rp.Options().HTTPClient.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
+
+func TestCompleteLogin(t *testing.T) {
+	manager, err := session.New(session.Options{Secret: []byte("test-secret-of-at-least-32-bytes!")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp := newTestProvider(t)
+
+	tests := []struct {
+		name       string
+		returnTo   string
+		tamper     func(sess *session.Session, callback url.Values)
+		want       error
+		wantCode   string
+		wantReturn string
+	}{
+		{name: "valid", returnTo: "/dashboard", wantReturn: "/dashboard"},
+		{name: "external return path", returnTo: "//evil.example.com", wantReturn: "/"},
+		{
+			name:   "state mismatch",
+			tamper: func(sess *session.Session, callback url.Values) { callback.Set("state", "forged") },
+			want:   ErrInvalidState,
+		},
+		{
+			name:   "state missing from callback",
+			tamper: func(sess *session.Session, callback url.Values) { callback.Del("state") },
+			want:   ErrInvalidState,
+		},
+		{
+			name:   "no login in progress",
+			tamper: func(sess *session.Session, callback url.Values) { sess.Delete(stateKey) },
+			want:   ErrInvalidState,
+		},
+		{
+			name:   "missing code",
+			tamper: func(sess *session.Session, callback url.Values) { callback.Del("code") },
+			want:   ErrMissingCode,
+		},
+		{
+			name:     "PKCE verifier mismatch",
+			tamper:   func(sess *session.Session, callback url.Values) { sess.Set(verifierKey, "another-verifier") },
+			wantCode: "invalid_grant",
+		},
+		{
+			name:     "PKCE verifier missing",
+			tamper:   func(sess *session.Session, callback url.Values) { sess.Delete(verifierKey) },
+			wantCode: "invalid_grant",
+		},
+		{
+			name:   "nonce mismatch",
+			tamper: func(sess *session.Session, callback url.Values) { sess.Set(nonceKey, "another-nonce") },
+			want:   ErrInvalidNonce,
+		},
+		{
+			name: "provider error",
+			tamper: func(sess *session.Session, callback url.Values) {
+				callback.Set("error", "access_denied")
+				callback.Del("code")
+			},
+			wantCode: "access_denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := manager.Load(context.Background(), "")
+			callback := authorize(t, rp, sess, tt.returnTo)
+			if tt.tamper != nil {
+				tt.tamper(sess, callback)
+			}
+
+			result, err := rp.CompleteLogin(context.Background(), sess, callback)
+
+			var providerErr *ProviderError
+			switch {
+			case tt.wantCode != "":
+				if !errors.As(err, &providerErr) || providerErr.Code != tt.wantCode {
+					t.Fatalf("CompleteLogin() error = %v, want provider error %s", err, tt.wantCode)
+				}
+			case tt.want != nil:
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("CompleteLogin() error = %v, want %v", err, tt.want)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("CompleteLogin() error = %v", err)
+				}
+				if sess.Subject() != "dev-user" || result.ReturnTo != tt.wantReturn {
+					t.Fatalf("CompleteLogin() subject %q, return to %q; want dev-user, %q", sess.Subject(), result.ReturnTo, tt.wantReturn)
+				}
+				return
+			}
+			if sess.IsAuthenticated() {
+				t.Fatal("CompleteLogin() logged in a rejected callback")
+			}
+		})
+	}
+}
+
+func TestCompleteLoginReplay(t *testing.T) {
+	manager, err := session.New(session.Options{Secret: []byte("test-secret-of-at-least-32-bytes!")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp := newTestProvider(t)
+
+	sess := manager.Load(context.Background(), "")
+	callback := authorize(t, rp, sess, "/")
+	if _, err := rp.CompleteLogin(context.Background(), sess, callback); err != nil {
+		t.Fatal(err)
+	}
+	// The login state is consumed, so the same callback can't complete a second login
+	if _, err := rp.CompleteLogin(context.Background(), sess, callback); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("replayed CompleteLogin() error = %v, want %v", err, ErrInvalidState)
+	}
+}
@@ -258,6 +258,10 @@ func (p *SchemaProcessor) processOutputType(operation *huma.Operation, registry
 			// Status field doesn't affect OpenAPI schema directly
 			continue
 		case "Body":
+			// Streaming bodies are written by a callback and documented by their caller
+			if field.Type.Kind() == reflect.Func {
+				continue
+			}
 			// Process body field for response schema
 			if err := p.processOutputBodyField(response, registry, field, outputType, operation.OperationID); err != nil {
 				return err
@@ -379,6 +383,9 @@ func (p *SchemaProcessor) setupErrorResponses(operation *huma.Operation, registr
 		errorCodes = append([]int{http.StatusUnauthorized, http.StatusForbidden}, errorCodes...)
 	}
 
+	// Add errors declared on the operation (like huma.Register does)
+	errorCodes = append(errorCodes, operation.Errors...)
+
 	for _, code := range errorCodes {
 		codeStr := fmt.Sprintf("%d", code)
 		if operation.Responses[codeStr] == nil {
@@ -0,0 +1,289 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension documents the pagination mode of an operation, so clients can page through it
+const Extension = "x-goflux-pagination"
+
+// Pagination modes
+const (
+	ModeOffset = "offset"
+	ModeCursor = "cursor"
+)
+
+// Options configures a paginator
+type Options struct {
+	// DefaultLimit is the page size when the request has no limit (default 20)
+	DefaultLimit int
+	// MaxLimit caps the limit a request can ask for (default 100)
+	MaxLimit int
+	// Secret signs cursors so clients cannot forge positions. Without it a random secret is
+	// generated at startup, which invalidates cursors on restart and across instances
+	Secret []byte
+}
+
+func (o Options) withDefaults() Options {
+	if o.DefaultLimit <= 0 {
+		o.DefaultLimit = 20
+	}
+	if o.MaxLimit <= 0 {
+		o.MaxLimit = 100
+	}
+	if o.DefaultLimit > o.MaxLimit {
+		o.DefaultLimit = o.MaxLimit
+	}
+	if len(o.Secret) == 0 {
+		o.Secret = processSecret
+	}
+	return o
+}
+
+var processSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("pagination: failed to generate cursor secret: %v", err))
+	}
+	return secret
+}()
+
+// Spec is the content of the pagination extension
+type Spec struct {
+	Mode         string `json:"mode"`
+	DefaultLimit int    `json:"defaultLimit"`
+	MaxLimit     int    `json:"maxLimit"`
+}
+
+// OffsetParams are the query parameters of offset pagination
+type OffsetParams struct {
+	Limit  int `query:"limit" minimum:"1" doc:"Maximum number of items to return"`
+	Offset int `query:"offset" minimum:"0" doc:"Number of items to skip"`
+}
+
+// CursorParams are the query parameters of cursor pagination
+type CursorParams struct {
+	Limit  int    `query:"limit" minimum:"1" doc:"Maximum number of items to return"`
+	Cursor string `query:"cursor" doc:"Opaque cursor from the nextCursor of the previous page"`
+}
+
+// Offset is the position requested with offset pagination
+type Offset struct {
+	Limit  int
+	Offset int
+}
+
+// NewOffset validates the parameters of a request
+func NewOffset(params *OffsetParams, options Options) *Offset {
+	options = options.withDefaults()
+	offset := &Offset{Limit: options.DefaultLimit}
+	if params != nil {
+		offset.Offset = max(params.Offset, 0)
+		if params.Limit > 0 {
+			offset.Limit = min(params.Limit, options.MaxLimit)
+		}
+	}
+	return offset
+}
+
+// Cursor is the position requested with cursor pagination
+type Cursor struct {
+	Limit   int
+	payload []byte
+	secret  []byte
+}
+
+// ErrInvalidCursor is returned for cursors that were tampered with or signed with another secret
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// NewCursor validates the parameters of a request, including the cursor's signature
+func NewCursor(params *CursorParams, options Options) (*Cursor, error) {
+	options = options.withDefaults()
+	cursor := &Cursor{Limit: options.DefaultLimit, secret: options.Secret}
+	if params == nil {
+		return cursor, nil
+	}
+	if params.Limit > 0 {
+		cursor.Limit = min(params.Limit, options.MaxLimit)
+	}
+	if params.Cursor != "" {
+		payload, err := verify(params.Cursor, options.Secret)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid cursor", err)
+		}
+		cursor.payload = payload
+	}
+	return cursor, nil
+}
+
+// Scan decodes the position stored in the cursor into dest. It returns false for the first page,
+// which has no cursor
+func (c *Cursor) Scan(dest any) (bool, error) {
+	if len(c.payload) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(c.payload, dest); err != nil {
+		return false, fmt.Errorf("pagination: failed to decode cursor: %w", err)
+	}
+	return true, nil
+}
+
+// Encode signs a position, e.g. the sort key and ID of the last item of a page
+func (c *Cursor) Encode(position any) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", fmt.Errorf("pagination: failed to encode cursor: %w", err)
+	}
+	return sign(payload, c.secret), nil
+}
+
+func sign(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func verify(cursor string, secret []byte) ([]byte, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)[:16]) {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+// Page is the standard envelope of paginated responses
+type Page[T any] struct {
+	Items      []T    `json:"items" doc:"Items of the page"`
+	Limit      int    `json:"limit" doc:"Maximum number of items per page"`
+	Offset     int    `json:"offset,omitempty" doc:"Number of items before this page (offset pagination)"`
+	Total      int    `json:"total,omitempty" doc:"Total number of items (offset pagination)"`
+	NextCursor string `json:"nextCursor,omitempty" doc:"Cursor of the next page (cursor pagination)"`
+	HasMore    bool   `json:"hasMore" doc:"Whether another page follows"`
+
+	mode string
+}
+
+// NewOffsetPage builds the page of an offset request from its items and the total number of items
+func NewOffsetPage[T any](offset *Offset, items []T, total int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{
+		Items:   items,
+		Limit:   offset.Limit,
+		Offset:  offset.Offset,
+		Total:   total,
+		HasMore: offset.Offset+len(items) < total,
+		mode:    ModeOffset,
+	}
+}
+
+// NewCursorPage builds the page of a cursor request from its items and the position after the last
+// item. A nil next position marks the last page
+func NewCursorPage[T any](cursor *Cursor, items []T, next any) (Page[T], error) {
+	if items == nil {
+		items = []T{}
+	}
+	page := Page[T]{Items: items, Limit: cursor.Limit, mode: ModeCursor}
+	if next != nil {
+		encoded, err := cursor.Encode(next)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		page.NextCursor = encoded
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+// Links returns the RFC 8288 links of the page, relative to the request URL u
+func (p Page[T]) Links(u *url.URL) []string {
+	link := func(rel string, set map[string]string) string {
+		query := u.Query()
+		for key, value := range set {
+			if value == "" {
+				query.Del(key)
+			} else {
+				query.Set(key, value)
+			}
+		}
+		target := url.URL{Path: u.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=%q", target.String(), rel)
+	}
+	limit := strconv.Itoa(p.Limit)
+
+	var links []string
+	switch p.mode {
+	case ModeOffset:
+		links = append(links, link("first", map[string]string{"offset": "", "limit": limit}))
+		if p.Offset > 0 {
+			links = append(links, link("prev", map[string]string{"offset": strconv.Itoa(max(p.Offset-p.Limit, 0)), "limit": limit}))
+		}
+		if p.HasMore {
+			links = append(links, link("next", map[string]string{"offset": strconv.Itoa(p.Offset + len(p.Items)), "limit": limit}))
+		}
+		if p.Total > 0 && p.Limit > 0 {
+			last := (p.Total - 1) / p.Limit * p.Limit
+			links = append(links, link("last", map[string]string{"offset": strconv.Itoa(last), "limit": limit}))
+		}
+	case ModeCursor:
+		if p.HasMore {
+			links = append(links, link("next", map[string]string{"cursor": p.NextCursor, "limit": limit}))
+		}
+	}
+	return links
+}
+
+// Linker is implemented by pages, whose links are sent in the Link header
+type Linker interface {
+	Links(u *url.URL) []string
+}
+
+// Annotate documents the pagination of an operation: the extension read by clients and the default
+// and maximum of the limit parameter
+func Annotate(operation *huma.Operation, spec Spec) {
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = spec
+	for _, param := range operation.Parameters {
+		if param.In != "query" || param.Name != "limit" || param.Schema == nil {
+			continue
+		}
+		schema := *param.Schema
+		schema.Default = spec.DefaultLimit
+		maxLimit := float64(spec.MaxLimit)
+		schema.Maximum = &maxLimit
+		param.Schema = &schema
+	}
+}
+
+// SpecFor returns the extension of a paginator's options
+func SpecFor(mode string, options Options) Spec {
+	options = options.withDefaults()
+	return Spec{Mode: mode, DefaultLimit: options.DefaultLimit, MaxLimit: options.MaxLimit}
+}
@@ -1,16 +1,24 @@
 package parsing
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/barisgit/goflux/internal/etag"
 	"github.com/danielgtaylor/huma/v2"
 )
 
 // ResponseWriter handles writing HTTP responses
-type ResponseWriter struct{}
+type ResponseWriter struct {
+	// ETags enables automatic ETag and Last-Modified headers and conditional GET handling
+	ETags *etag.Options
+	// VaryAccept adds Vary: Accept to negotiated responses, for APIs that offer several formats
+	VaryAccept bool
+}
 
 // NewResponseWriter creates a new response writer
 func NewResponseWriter() *ResponseWriter {
@@ -44,9 +52,10 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 
 	// Handle response headers (like Huma does)
 	ct := ""
+	explicitETag := ""
 	for i := 0; i < outputValue.NumField(); i++ {
 		field := outputType.Field(i)
-		if !field.IsExported() {
+		if !field.IsExported() || field.Tag.Get(etag.TagName) != "" {
 			continue
 		}
 
@@ -57,6 +66,9 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 					// Track custom content type (like Huma does)
 					ct = headerValue.String()
 				}
+				if strings.EqualFold(headerName, "ETag") {
+					explicitETag = fmt.Sprintf("%v", headerValue.Interface())
+				}
 				ctx.SetHeader(headerName, fmt.Sprintf("%v", headerValue.Interface()))
 			}
 		}
@@ -66,8 +78,19 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 	if bodyField := outputValue.FieldByName("Body"); bodyField.IsValid() {
 		body := bodyField.Interface()
 
+		// Streaming bodies such as huma.StreamResponse write the response themselves
+		if stream, ok := body.(func(huma.Context)); ok {
+			if stream != nil {
+				stream(ctx)
+			}
+			return nil
+		}
+
 		// Handle byte slice special case (like Huma does)
 		if b, ok := body.([]byte); ok {
+			if w.ETags != nil && w.writeValidators(ctx, outputValue, status, explicitETag, b) {
+				return nil
+			}
 			ctx.SetStatus(status)
 			if _, err := ctx.BodyWriter().Write(b); err != nil {
 				return fmt.Errorf("error writing byte response: %w", err)
@@ -89,6 +112,9 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 			}
 
 			ctx.SetHeader("Content-Type", ct)
+			if w.VaryAccept {
+				ctx.AppendHeader("Vary", "Accept")
+			}
 		}
 
 		// Transform the response body (like Huma does)
@@ -97,6 +123,22 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 			return fmt.Errorf("error transforming response: %w", terr)
 		}
 
+		// With ETags enabled the body is marshaled first so it can be hashed
+		if w.ETags != nil && status != http.StatusNoContent && status != http.StatusNotModified {
+			var buf bytes.Buffer
+			if merr := api.Marshal(&buf, ct, tval); merr != nil {
+				return fmt.Errorf("error marshaling response: %w", merr)
+			}
+			if w.writeValidators(ctx, outputValue, status, explicitETag, buf.Bytes()) {
+				return nil
+			}
+			ctx.SetStatus(status)
+			if _, err := ctx.BodyWriter().Write(buf.Bytes()); err != nil {
+				return fmt.Errorf("error writing response: %w", err)
+			}
+			return nil
+		}
+
 		ctx.SetStatus(status)
 
 		// Marshal and write the response (like Huma does)
@@ -113,6 +155,39 @@ func (w *ResponseWriter) WriteOutput(api huma.API, ctx huma.Context, output inte
 	return nil
 }
 
+// writeValidators sets the ETag and Last-Modified headers of a successful response and
+// answers conditional GET and HEAD requests. It returns true when 304 Not Modified was written
+func (w *ResponseWriter) writeValidators(ctx huma.Context, outputValue reflect.Value, status int, explicitETag string, body []byte) bool {
+	if status < 200 || status >= 300 {
+		return false
+	}
+
+	version, modified := etag.Validators(outputValue)
+	tag := explicitETag
+	if tag == "" && version.IsValid() && !version.IsZero() {
+		tag = etag.FromVersion(version.Interface(), w.ETags.Weak)
+	}
+
+	method := ctx.Method()
+	conditional := method == http.MethodGet || method == http.MethodHead
+	if tag == "" && conditional && status == http.StatusOK {
+		tag = etag.Compute(body, w.ETags.Weak)
+	}
+
+	if tag != "" && explicitETag == "" {
+		ctx.SetHeader("ETag", tag)
+	}
+	if !modified.IsZero() {
+		ctx.SetHeader("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if conditional && status == http.StatusOK && etag.NotModified(ctx.Header("If-None-Match"), ctx.Header("If-Modified-Since"), tag, modified) {
+		ctx.SetStatus(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
 // getHeaderName extracts header name from struct field
 func (w *ResponseWriter) getHeaderName(field reflect.StructField) string {
 	if header := field.Tag.Get("header"); header != "" {
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/barisgit/goflux/internal/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension is the OpenAPI operation extension describing authorization requirements
+const Extension = "x-goflux-authorization"
+
+// Common authorization errors
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("insufficient permissions")
+)
+
+// Requirements lists what a principal needs to call an operation.
+// Every listed scope, role and permission is required
+type Requirements struct {
+	Scopes      []string `json:"scopes,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// IsEmpty reports whether there is nothing to check
+func (r Requirements) IsEmpty() bool {
+	return len(r.Scopes) == 0 && len(r.Roles) == 0 && len(r.Permissions) == 0
+}
+
+// Merge returns the union of both requirement sets
+func (r Requirements) Merge(other Requirements) Requirements {
+	return Requirements{
+		Scopes:      union(r.Scopes, other.Scopes),
+		Roles:       union(r.Roles, other.Roles),
+		Permissions: union(r.Permissions, other.Permissions),
+	}
+}
+
+// Check verifies that the principal satisfies all requirements
+func (r Requirements) Check(principal *auth.Principal) error {
+	if r.IsEmpty() {
+		return nil
+	}
+	if principal == nil {
+		return huma.Error401Unauthorized("Authentication required", ErrUnauthenticated)
+	}
+
+	var missing []string
+	for _, scope := range r.Scopes {
+		if !principal.HasScope(scope) {
+			missing = append(missing, "scope "+scope)
+		}
+	}
+	for _, role := range r.Roles {
+		if !principal.HasRole(role) {
+			missing = append(missing, "role "+role)
+		}
+	}
+	for _, permission := range r.Permissions {
+		if !principal.HasPermission(permission) {
+			missing = append(missing, "permission "+permission)
+		}
+	}
+
+	if len(missing) > 0 {
+		return huma.Error403Forbidden("Insufficient permissions",
+			fmt.Errorf("%w: missing %s", ErrForbidden, strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// Annotate documents the requirements on an operation: scopes are added to every
+// security requirement, and the full set is stored in the x-goflux-authorization extension
+func (r Requirements) Annotate(operation *huma.Operation) {
+	if r.IsEmpty() {
+		return
+	}
+
+	if len(r.Scopes) > 0 {
+		security := make([]map[string][]string, len(operation.Security))
+		for i, requirement := range operation.Security {
+			security[i] = make(map[string][]string, len(requirement))
+			for scheme, scopes := range requirement {
+				security[i][scheme] = union(scopes, r.Scopes)
+			}
+		}
+		operation.Security = security
+	}
+
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	if existing, ok := operation.Extensions[Extension].(Requirements); ok {
+		r = existing.Merge(r)
+	}
+	operation.Extensions[Extension] = r
+}
+
+// union returns a followed by the items of b that are not in a
+func union(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	result := append([]string{}, a...)
+	for _, item := range b {
+		if !slices.Contains(result, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Locker elects the instance that runs an occurrence of a task. TryLock succeeds for exactly one
+// caller per task and occurrence, and only once the previous run has released its lock or the
+// lock has been held for ttl
+type Locker interface {
+	TryLock(ctx context.Context, task string, occurrence time.Time, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// MemoryLocker elects among the schedulers of one process, which is mostly useful in tests
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*lease
+}
+
+type lease struct {
+	occurrence time.Time
+	expires    time.Time
+}
+
+// NewMemoryLocker creates a locker that keeps its locks in process memory
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: map[string]*lease{}}
+}
+
+// TryLock implements Locker
+func (l *MemoryLocker) TryLock(ctx context.Context, task string, occurrence time.Time, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	current, ok := l.locks[task]
+	if ok && (!current.occurrence.Before(occurrence) || current.expires.After(now)) {
+		return nil, false, nil
+	}
+	held := &lease{occurrence: occurrence, expires: now.Add(ttl)}
+	l.locks[task] = held
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.locks[task] == held {
+			held.expires = time.Time{}
+		}
+	}, true, nil
+}
+
+// PgxExecutor is satisfied by *pgx.Conn, *pgxpool.Pool and pgx.Tx
+type PgxExecutor interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PgxLocker elects among instances sharing a PostgreSQL database. It keeps one row per task
+// holding the latest claimed occurrence, so a lock never depends on a particular connection
+type PgxLocker struct {
+	db    PgxExecutor
+	table string
+}
+
+// NewPgxLocker creates a locker backed by the given table (default "goflux_schedule_locks")
+func NewPgxLocker(db PgxExecutor, table string) (*PgxLocker, error) {
+	if table == "" {
+		table = "goflux_schedule_locks"
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("schedule: invalid table name %q", table)
+	}
+	return &PgxLocker{db: db, table: table}, nil
+}
+
+// CreateTable creates the locks table if it doesn't exist
+func (l *PgxLocker) CreateTable(ctx context.Context) error {
+	_, err := l.db.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	task TEXT PRIMARY KEY,
+	occurrence TIMESTAMPTZ NOT NULL,
+	holder TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+)`, l.table))
+	if err != nil {
+		return fmt.Errorf("schedule: failed to create table: %w", err)
+	}
+	return nil
+}
+
+// TryLock implements Locker
+func (l *PgxLocker) TryLock(ctx context.Context, task string, occurrence time.Time, ttl time.Duration) (func(), bool, error) {
+	var id [16]byte
+	if _, err := crand.Read(id[:]); err != nil {
+		return nil, false, err
+	}
+	holder := hex.EncodeToString(id[:])
+
+	var claimed string
+	err := l.db.QueryRow(ctx, fmt.Sprintf(`INSERT INTO %[1]s (task, occurrence, holder, expires_at)
+VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+ON CONFLICT (task) DO UPDATE SET occurrence = EXCLUDED.occurrence, holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+WHERE %[1]s.occurrence < EXCLUDED.occurrence AND %[1]s.expires_at <= now()
+RETURNING holder`, l.table), task, occurrence, holder, ttl.Seconds()).Scan(&claimed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return func() {
+		// The run is over, but the occurrence stays claimed so no other instance repeats it
+		_, _ = l.db.Exec(context.Background(), fmt.Sprintf(`UPDATE %s SET expires_at = now() WHERE task = $1 AND holder = $2`, l.table), task, holder)
+	}, true, nil
+}
@@ -0,0 +1,268 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Func is the work of a scheduled task
+type Func func(ctx context.Context) error
+
+// Options configures a scheduler
+type Options struct {
+	// Locker elects one instance to run each occurrence of a task when the application runs on
+	// several instances. Without it every instance runs every task
+	Locker Locker
+	// LockTTL is how long a run holds its lock at most, in case the instance dies without
+	// releasing it (default 10 minutes). Keep it above the longest run of any task
+	LockTTL time.Duration
+	// OnError is called when a run fails or panics, and when the locker is unavailable
+	OnError func(ctx context.Context, task *Info, err error)
+}
+
+// TaskOptions configures one task
+type TaskOptions struct {
+	// Name identifies the task in listings, error reports and leader locks. It must be unique and
+	// should stay stable across deployments (default: the handler's function name)
+	Name string
+	// Timeout cancels the context of a run that takes longer (default: none)
+	Timeout time.Duration
+}
+
+// Info describes a task and its latest run
+type Info struct {
+	Name         string        `json:"name" doc:"Task name"`
+	Spec         string        `json:"spec" doc:"Cron expression or interval of the task"`
+	Running      bool          `json:"running" doc:"Whether a run is in progress on this instance"`
+	NextRun      time.Time     `json:"nextRun,omitzero" doc:"Time of the next run"`
+	LastRun      time.Time     `json:"lastRun,omitzero" doc:"Start of the latest run on this instance"`
+	LastDuration time.Duration `json:"lastDuration,omitempty" doc:"Duration of the latest run in nanoseconds"`
+	LastError    string        `json:"lastError,omitempty" doc:"Error of the latest run, empty if it succeeded"`
+	Runs         int           `json:"runs" doc:"Number of runs on this instance"`
+	Failures     int           `json:"failures" doc:"Number of failed runs on this instance"`
+	Skipped      int           `json:"skipped" doc:"Occurrences skipped because the previous run was still going or another instance held the lock"`
+}
+
+type task struct {
+	spec    Spec
+	fn      Func
+	options TaskOptions
+
+	mu   sync.Mutex
+	info Info
+}
+
+// Scheduler runs tasks on their schedules. A task never overlaps with itself: an occurrence that
+// comes up while the previous run is still going is skipped
+type Scheduler struct {
+	mu      sync.RWMutex
+	options Options
+	tasks   map[string]*task
+	running bool
+}
+
+// New creates a scheduler
+func New(options Options) *Scheduler {
+	s := &Scheduler{tasks: map[string]*task{}}
+	s.Configure(options)
+	return s
+}
+
+// Configure replaces the options of the scheduler. It must be called before Run
+func (s *Scheduler) Configure(options Options) {
+	if options.LockTTL <= 0 {
+		options.LockTTL = 10 * time.Minute
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = options
+}
+
+// Add registers a task. It must be called before Run
+func (s *Scheduler) Add(spec string, fn Func, options TaskOptions) error {
+	parsed, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	if options.Name == "" {
+		return fmt.Errorf("schedule: task name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return fmt.Errorf("schedule: cannot add task %q to a running scheduler", options.Name)
+	}
+	if _, exists := s.tasks[options.Name]; exists {
+		return fmt.Errorf("schedule: task %q is already scheduled", options.Name)
+	}
+	s.tasks[options.Name] = &task{
+		spec:    parsed,
+		fn:      fn,
+		options: options,
+		info:    Info{Name: options.Name, Spec: spec, NextRun: parsed.Next(time.Now())},
+	}
+	return nil
+}
+
+// Tasks describes the scheduled tasks, sorted by name
+func (s *Scheduler) Tasks() []Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]Info, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		t.mu.Lock()
+		infos = append(infos, t.info)
+		t.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Run runs the tasks until ctx is cancelled, then waits for the runs in progress to finish
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("schedule: scheduler is already running")
+	}
+	s.running = true
+	tasks := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	options := s.options
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, &wg, options, t)
+		}()
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// loop waits for each occurrence of a task and starts a run unless one is in progress
+func (s *Scheduler) loop(ctx context.Context, wg *sync.WaitGroup, options Options, t *task) {
+	for {
+		next := t.spec.Next(time.Now())
+		t.mu.Lock()
+		t.info.NextRun = next
+		t.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		t.mu.Lock()
+		if t.info.Running {
+			t.info.Skipped++
+			t.mu.Unlock()
+			continue
+		}
+		t.info.Running = true
+		t.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.execute(context.WithoutCancel(ctx), options, t, next)
+		}()
+	}
+}
+
+// execute runs one occurrence of a task, taking the leader lock first when a locker is configured
+func (s *Scheduler) execute(ctx context.Context, options Options, t *task, occurrence time.Time) {
+	defer func() {
+		t.mu.Lock()
+		t.info.Running = false
+		t.mu.Unlock()
+	}()
+
+	if options.Locker != nil {
+		unlock, ok, err := options.Locker.TryLock(ctx, t.options.Name, occurrence, options.LockTTL)
+		if err != nil {
+			s.report(ctx, options, t, fmt.Errorf("schedule: failed to lock task %q: %w", t.options.Name, err))
+			return
+		}
+		if !ok {
+			t.mu.Lock()
+			t.info.Skipped++
+			t.mu.Unlock()
+			return
+		}
+		defer unlock()
+	}
+
+	runCtx := ctx
+	if t.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, t.options.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := call(runCtx, t.fn)
+
+	t.mu.Lock()
+	t.info.LastRun = start
+	t.info.LastDuration = time.Since(start)
+	t.info.Runs++
+	t.info.LastError = ""
+	if err != nil {
+		t.info.Failures++
+		t.info.LastError = err.Error()
+	}
+	t.mu.Unlock()
+
+	if err != nil {
+		s.report(ctx, options, t, err)
+	}
+}
+
+// ErrPanicked is wrapped by the error of a run that panicked
+var ErrPanicked = errors.New("schedule: task panicked")
+
+// call runs a task, turning panics into errors
+func call(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanicked, r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Scheduler) report(ctx context.Context, options Options, t *task, err error) {
+	if options.OnError == nil {
+		return
+	}
+	t.mu.Lock()
+	info := t.info
+	t.mu.Unlock()
+	options.OnError(ctx, &info, err)
+}
@@ -0,0 +1,232 @@
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec computes the run times of a task
+type Spec interface {
+	// Next returns the first run time strictly after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// macros are the shorthands accepted in place of the five cron fields
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a schedule. It accepts the standard five cron fields (minute, hour, day of month,
+// month, day of week) with lists, ranges, steps and month/weekday names, the @hourly, @daily,
+// @weekly, @monthly and @yearly macros, and intervals such as "@every 10m". Cron schedules run in
+// local time unless prefixed with "CRON_TZ=<zone> " (or "TZ=<zone> ")
+func Parse(spec string) (Spec, error) {
+	spec = strings.TrimSpace(spec)
+	location := time.Local
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		zone, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(zone, "=")
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: invalid time zone %q: %w", name, err)
+		}
+		location = loc
+		spec = strings.TrimSpace(rest)
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("schedule: invalid interval %q: %w", rest, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("schedule: interval %s is shorter than a second", interval)
+		}
+		return Every(interval), nil
+	}
+	if expanded, ok := macros[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule: expected 5 fields in %q, got %d", spec, len(fields))
+	}
+	c := &cron{location: location}
+	var err error
+	if c.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], daysOfMonth); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], daysOfWeek); err != nil {
+		return nil, err
+	}
+	// Sunday may be written as 7
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domAny = fields[2] == "*" || fields[2] == "?"
+	c.dowAny = fields[4] == "*" || fields[4] == "?"
+	return c, nil
+}
+
+// Every returns a spec that runs every interval. Run times are aligned to multiples of the interval
+// since the Unix epoch, so every instance of an application agrees on them
+func Every(interval time.Duration) Spec {
+	return every(interval)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+type bounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes     = bounds{name: "minute", min: 0, max: 59}
+	hours       = bounds{name: "hour", min: 0, max: 23}
+	daysOfMonth = bounds{name: "day of month", min: 1, max: 31}
+	months      = bounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	daysOfWeek = bounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// parseField turns a comma-separated list of values, ranges and steps into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("schedule: invalid step %q in %s field", stepPart, b.name)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			lo, hi = b.min, b.max
+			if b.max == 7 {
+				hi = 6 // Avoid running Sunday twice through 0 and 7
+			}
+		case strings.Contains(rangePart, "-"):
+			first, last, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(first, b); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(last, b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("schedule: invalid range %q in %s field", rangePart, b.name)
+			}
+		default:
+			value, err := parseValue(rangePart, b)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = value, value
+			if hasStep {
+				hi = b.max // "5/15" means "5-59/15"
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	if n, ok := b.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < b.min || n > b.max {
+		return 0, fmt.Errorf("schedule: invalid %s %q", b.name, value)
+	}
+	return n, nil
+}
+
+// cron is a parsed five-field schedule, one bit per allowed value
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+	location                      *time.Location
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+	original := t.Location()
+	t = t.In(c.location).Truncate(time.Minute).Add(time.Minute)
+
+	// A valid schedule matches within a few years (Feb 29 on a given weekday takes the longest)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<t.Month()) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			next := bits.TrailingZeros64(c.minute >> (t.Minute() + 1))
+			if next == 64 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			} else {
+				t = t.Add(time.Duration(next+1) * time.Minute)
+			}
+			continue
+		}
+		return t.In(original)
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron's rule: when both day fields are restricted, either one matching is enough
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
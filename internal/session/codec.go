@@ -0,0 +1,88 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errInvalidCookie is returned for cookies that fail signature or decryption checks
+var errInvalidCookie = errors.New("session: invalid cookie")
+
+// codec signs and optionally encrypts cookie values
+type codec struct {
+	secret []byte
+	aead   cipher.AEAD
+}
+
+func newCodec(secret, encryptionKey []byte) (*codec, error) {
+	c := &codec{secret: secret}
+	if len(encryptionKey) > 0 {
+		block, err := aes.NewCipher(encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("session: invalid EncryptionKey: %w", err)
+		}
+		c.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("session: invalid EncryptionKey: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// encode produces "<payload>.<signature>", binding the value to the cookie name
+func (c *codec) encode(name string, payload []byte) (string, error) {
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("session: failed to generate nonce: %w", err)
+		}
+		payload = c.aead.Seal(nonce, nonce, payload, []byte(name))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + c.sign(name, encoded), nil
+}
+
+// decode verifies and decodes a value produced by encode
+func (c *codec) decode(name, value string) ([]byte, error) {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return nil, errInvalidCookie
+	}
+	if !hmac.Equal([]byte(signature), []byte(c.sign(name, encoded))) {
+		return nil, errInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+
+	if c.aead != nil {
+		nonceSize := c.aead.NonceSize()
+		if len(payload) < nonceSize {
+			return nil, errInvalidCookie
+		}
+		payload, err = c.aead.Open(nil, payload[:nonceSize], payload[nonceSize:], []byte(name))
+		if err != nil {
+			return nil, errInvalidCookie
+		}
+	}
+
+	return payload, nil
+}
+
+func (c *codec) sign(name, value string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'|'})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"io"
+	"sync"
+
+	"github.com/barisgit/goflux/internal/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// humaContext lets the wrapper embed huma.Context without the field name clashing with its Context method
+type humaContext = huma.Context
+
+// committingContext commits the session right before the response headers are written,
+// so handlers can modify the session until they return their output
+type committingContext struct {
+	humaContext
+	once   sync.Once
+	commit func()
+}
+
+// SetStatus commits the session, then sets the status
+func (c *committingContext) SetStatus(code int) {
+	c.once.Do(c.commit)
+	c.humaContext.SetStatus(code)
+}
+
+// BodyWriter commits the session, then returns the body writer
+func (c *committingContext) BodyWriter() io.Writer {
+	c.once.Do(c.commit)
+	return c.humaContext.BodyWriter()
+}
+
+// Attach stores the session in the request context and arranges for it to be committed
+// before the response is written. The returned finish function commits sessions for
+// handlers that never write a response and must be called after the handler returns
+func (m *Manager) Attach(ctx huma.Context, s *Session) (huma.Context, func()) {
+	wrapped := &committingContext{humaContext: ctx}
+	wrapped.commit = func() {
+		cookies, err := m.Commit(ctx.Context(), s)
+		if err != nil {
+			// Headers haven't been sent yet but the handler already produced its output,
+			// so the session change is dropped rather than replacing the response
+			return
+		}
+		for _, cookie := range cookies {
+			ctx.AppendHeader("Set-Cookie", cookie)
+		}
+	}
+
+	requestCtx := WithSession(ctx.Context(), s)
+	if principal := s.Principal(); principal != nil {
+		requestCtx = auth.WithPrincipal(requestCtx, principal)
+	}
+
+	return huma.WithContext(wrapped, requestCtx), func() { wrapped.once.Do(wrapped.commit) }
+}
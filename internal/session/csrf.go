@@ -0,0 +1,86 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CSRFExtension documents the CSRF cookie and header names on security schemes,
+// so generated clients echo the token under the names the server was configured with
+const CSRFExtension = "x-goflux-csrf"
+
+// NewCSRFToken creates a random token for the double-submit cookie, signed together with the
+// session it belongs to. Pass "" for clients without a session
+func (m *Manager) NewCSRFToken(sessionID string) string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("session: failed to generate CSRF token: %v", err))
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token + "." + m.signCSRF(sessionID, token)
+}
+
+// ValidCSRFToken reports whether token was issued by this manager for the given session
+func (m *Manager) ValidCSRFToken(token, sessionID string) bool {
+	value, signature, found := strings.Cut(token, ".")
+	return found && hmac.Equal([]byte(signature), []byte(m.signCSRF(sessionID, value)))
+}
+
+// signCSRF binds a token to a session, so a token planted by another site or taken from
+// another session never validates
+func (m *Manager) signCSRF(sessionID, token string) string {
+	return m.codec.sign(m.options.CSRFCookieName, sessionID+"|"+token)
+}
+
+// CSRFCookie returns the Set-Cookie value for a CSRF token. It is readable by
+// JavaScript so clients can echo it back in the CSRF header
+func (m *Manager) CSRFCookie(token string) string {
+	return m.cookie(m.options.CSRFCookieName, token, false).String()
+}
+
+// CSRFNames returns the value of the CSRF extension: the cookie the token is issued in
+// and the header unsafe requests must echo it in
+func (m *Manager) CSRFNames() map[string]string {
+	return map[string]string{"cookie": m.options.CSRFCookieName, "header": m.options.CSRFHeaderName}
+}
+
+// CSRFResult describes the outcome of a double-submit check
+type CSRFResult struct {
+	// Allowed is false when the request must be rejected
+	Allowed bool
+	// SetCookie is a Set-Cookie value to send when a new token was issued
+	SetCookie string
+}
+
+// CheckCSRF performs the double-submit check for a request of the given session.
+// Safe methods always pass and receive a token cookie if they don't have a valid one yet.
+// Unsafe methods must echo the CSRF cookie in the CSRF header, and the token must have been
+// issued to the request's session, or to a client without one when the session is new
+func (m *Manager) CheckCSRF(method, cookieHeader, headerToken string, s *Session) CSRFResult {
+	sessionID := ""
+	if !s.IsNew() {
+		sessionID = s.ID
+	}
+
+	cookieToken := findCookie(cookieHeader, m.options.CSRFCookieName)
+	hasValidCookie := cookieToken != "" && m.ValidCSRFToken(cookieToken, sessionID)
+
+	result := CSRFResult{Allowed: true}
+	if !hasValidCookie {
+		result.SetCookie = m.CSRFCookie(m.NewCSRFToken(sessionID))
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return result
+	}
+
+	if !hasValidCookie || !hmac.Equal([]byte(cookieToken), []byte(headerToken)) {
+		result.Allowed = false
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PgxExecutor is satisfied by *pgx.Conn, *pgxpool.Pool and pgx.Tx
+type PgxExecutor interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PgxStore keeps sessions in a PostgreSQL table
+type PgxStore struct {
+	db    PgxExecutor
+	table string
+}
+
+// NewPgxStore creates a store backed by the given table (default "goflux_sessions")
+func NewPgxStore(db PgxExecutor, table string) (*PgxStore, error) {
+	if table == "" {
+		table = "goflux_sessions"
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("session: invalid table name %q", table)
+	}
+	return &PgxStore{db: db, table: table}, nil
+}
+
+// CreateTable creates the sessions table if it doesn't exist
+func (s *PgxStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	data BYTEA NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+)`, s.table))
+	if err != nil {
+		return fmt.Errorf("session: failed to create table: %w", err)
+	}
+	return nil
+}
+
+// Load implements Store
+func (s *PgxStore) Load(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(ctx,
+		fmt.Sprintf(`SELECT data FROM %s WHERE id = $1 AND expires_at > now()`, s.table), id,
+	).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Save implements Store
+func (s *PgxStore) Save(ctx context.Context, id string, data []byte, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (id, data, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`, s.table),
+		id, data, expiresAt)
+	return err
+}
+
+// Delete implements Store
+func (s *PgxStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, s.table), id)
+	return err
+}
+
+// Cleanup removes expired sessions and returns how many were removed
+func (s *PgxStore) Cleanup(ctx context.Context) (int, error) {
+	tag, err := s.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= now()`, s.table))
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,417 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/barisgit/goflux/internal/auth"
+)
+
+// subjectKey is the session value holding the logged-in subject
+const subjectKey = "_subject"
+
+// Default cookie and header names, shared with the generated TypeScript clients
+const (
+	DefaultCookieName     = "goflux_session"
+	DefaultCSRFCookieName = "goflux_csrf"
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+)
+
+// ErrNotFound is returned by stores when a session does not exist or has expired
+var ErrNotFound = errors.New("session not found")
+
+// Options configures cookie sessions
+type Options struct {
+	// Secret signs session and CSRF cookies. Must be at least 32 bytes
+	Secret []byte
+	// EncryptionKey optionally encrypts cookie contents with AES-GCM (16, 24 or 32 bytes)
+	EncryptionKey []byte
+	// Store keeps session data server-side. When nil, the data is stored in the cookie itself
+	Store Store
+	// CookieName defaults to "goflux_session"
+	CookieName string
+	// MaxAge is the session lifetime (default 24 hours)
+	MaxAge time.Duration
+	// Path defaults to "/"
+	Path string
+	// Domain is left empty (host-only cookie) by default
+	Domain string
+	// Secure marks cookies as HTTPS-only
+	Secure bool
+	// SameSite defaults to http.SameSiteLaxMode
+	SameSite http.SameSite
+	// CSRFCookieName defaults to "goflux_csrf"
+	CSRFCookieName string
+	// CSRFHeaderName defaults to "X-CSRF-Token"
+	CSRFHeaderName string
+}
+
+// Session is a per-request view of the caller's session data
+type Session struct {
+	ID        string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	mu          sync.RWMutex
+	values      map[string]any
+	isNew       bool
+	modified    bool
+	destroyed   bool
+	regenerated bool
+	previousID  string
+}
+
+// record is the serialized form of a session
+type record struct {
+	ID        string         `json:"i,omitempty"`
+	Values    map[string]any `json:"v"`
+	CreatedAt time.Time      `json:"c"`
+	ExpiresAt time.Time      `json:"e"`
+}
+
+// Get returns a session value
+func (s *Session) Get(key string) any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[key]
+}
+
+// GetString returns a session value as a string, or "" if missing or not a string
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key).(string)
+	return v
+}
+
+// Set stores a session value. Values must be JSON serializable
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete removes a session value
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.modified = true
+}
+
+// Values returns a copy of all session values
+func (s *Session) Values() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make(map[string]any, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+// IsNew reports whether the session was created during this request
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Subject returns the logged-in subject, or "" for anonymous sessions
+func (s *Session) Subject() string {
+	return s.GetString(subjectKey)
+}
+
+// IsAuthenticated reports whether a subject is logged in
+func (s *Session) IsAuthenticated() bool {
+	return s.Subject() != ""
+}
+
+// Login marks the session as authenticated for the given subject.
+// The session ID and CSRF token are rotated to prevent session fixation
+func (s *Session) Login(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[subjectKey] = subject
+	s.modified = true
+	s.regenerate()
+}
+
+// Logout destroys the session and expires its cookie
+func (s *Session) Logout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = map[string]any{}
+	s.destroyed = true
+	s.modified = true
+}
+
+// Regenerate assigns a new session ID while keeping the values
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modified = true
+	s.regenerate()
+}
+
+// regenerate must be called with the lock held
+func (s *Session) regenerate() {
+	if !s.regenerated && !s.isNew {
+		s.previousID = s.ID
+	}
+	s.ID = newID()
+	s.regenerated = true
+}
+
+// Principal converts an authenticated session into a generic principal.
+// Roles and permissions are read from the "roles" and "permissions" session values
+func (s *Session) Principal() *auth.Principal {
+	subject := s.Subject()
+	if subject == "" {
+		return nil
+	}
+	return &auth.Principal{
+		Subject:     subject,
+		Scheme:      "session",
+		Roles:       s.strings("roles"),
+		Permissions: s.strings("permissions"),
+		Claims:      s.Values(),
+	}
+}
+
+// strings returns a list value, which is []any after a round trip through JSON
+func (s *Session) strings(key string) []string {
+	switch v := s.Get(key).(type) {
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Manager loads and persists sessions for requests
+type Manager struct {
+	options Options
+	codec   *codec
+}
+
+// New creates a session manager
+func New(opts Options) (*Manager, error) {
+	if len(opts.Secret) < 32 {
+		return nil, fmt.Errorf("session: Secret must be at least 32 bytes")
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = DefaultCookieName
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 24 * time.Hour
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	if opts.CSRFCookieName == "" {
+		opts.CSRFCookieName = DefaultCSRFCookieName
+	}
+	if opts.CSRFHeaderName == "" {
+		opts.CSRFHeaderName = DefaultCSRFHeaderName
+	}
+
+	c, err := newCodec(opts.Secret, opts.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{options: opts, codec: c}, nil
+}
+
+// Options returns the manager options with defaults applied
+func (m *Manager) Options() Options {
+	return m.options
+}
+
+// Load restores the session referenced by the request cookies, or starts a new one
+func (m *Manager) Load(ctx context.Context, cookieHeader string) *Session {
+	if cookie := findCookie(cookieHeader, m.options.CookieName); cookie != "" {
+		if session, err := m.load(ctx, cookie); err == nil {
+			return session
+		}
+	}
+
+	now := time.Now()
+	return &Session{
+		ID:        newID(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.options.MaxAge),
+		values:    map[string]any{},
+		isNew:     true,
+	}
+}
+
+func (m *Manager) load(ctx context.Context, cookie string) (*Session, error) {
+	payload, err := m.codec.decode(m.options.CookieName, cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ""
+	data := payload
+	if m.options.Store != nil {
+		id = string(payload)
+		data, err = m.options.Store.Load(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	if rec.Values == nil {
+		rec.Values = map[string]any{}
+	}
+	if id == "" {
+		// Cookie-only sessions carry their ID inside the cookie
+		id = rec.ID
+	}
+
+	return &Session{ID: id, CreatedAt: rec.CreatedAt, ExpiresAt: rec.ExpiresAt, values: rec.Values}, nil
+}
+
+// Commit persists a modified session and returns the Set-Cookie header values to send.
+// Unmodified sessions produce no cookies
+func (m *Manager) Commit(ctx context.Context, s *Session) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.modified {
+		return nil, nil
+	}
+	s.modified = false
+
+	store := m.options.Store
+	if s.destroyed {
+		if store != nil {
+			for _, id := range []string{s.ID, s.previousID} {
+				if id == "" {
+					continue
+				}
+				if err := store.Delete(ctx, id); err != nil {
+					return nil, err
+				}
+			}
+		}
+		// Tokens of the destroyed session are void, issue one for the now anonymous client
+		return []string{
+			m.expiredCookie(m.options.CookieName, true).String(),
+			m.CSRFCookie(m.NewCSRFToken("")),
+		}, nil
+	}
+
+	if store != nil && s.previousID != "" {
+		if err := store.Delete(ctx, s.previousID); err != nil {
+			return nil, err
+		}
+		s.previousID = ""
+	}
+
+	s.ExpiresAt = time.Now().Add(m.options.MaxAge)
+	data, err := json.Marshal(record{ID: s.ID, Values: s.values, CreatedAt: s.CreatedAt, ExpiresAt: s.ExpiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("session: failed to encode values: %w", err)
+	}
+
+	payload := data
+	if store != nil {
+		if err := store.Save(ctx, s.ID, data, s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		payload = []byte(s.ID)
+	}
+
+	value, err := m.codec.encode(m.options.CookieName, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := []string{m.cookie(m.options.CookieName, value, true).String()}
+	if s.regenerated || s.isNew {
+		// CSRF tokens are bound to the session ID, so issue one whenever the ID is new or rotated
+		cookies = append(cookies, m.CSRFCookie(m.NewCSRFToken(s.ID)))
+		s.regenerated = false
+	}
+	s.isNew = false
+
+	return cookies, nil
+}
+
+// cookie builds a cookie with the manager's attributes
+func (m *Manager) cookie(name, value string, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     m.options.Path,
+		Domain:   m.options.Domain,
+		MaxAge:   int(m.options.MaxAge.Seconds()),
+		Secure:   m.options.Secure,
+		HttpOnly: httpOnly,
+		SameSite: m.options.SameSite,
+	}
+}
+
+// expiredCookie builds a cookie that removes name from the browser
+func (m *Manager) expiredCookie(name string, httpOnly bool) *http.Cookie {
+	c := m.cookie(name, "", httpOnly)
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+	return c
+}
+
+// findCookie returns the value of a cookie from a Cookie header
+func findCookie(header, name string) string {
+	request := &http.Request{Header: http.Header{"Cookie": {header}}}
+	if c, err := request.Cookie(name); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// newID returns a random, URL-safe session identifier
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("session: failed to generate ID: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx carrying the session
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session stored by the session middleware
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok && s != nil
+}
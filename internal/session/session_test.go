@@ -0,0 +1,197 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret-of-at-least-32-bytes!")
+
+func newTestManager(t *testing.T, opts Options) *Manager {
+	t.Helper()
+	opts.Secret = testSecret
+	m, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// cookieHeader turns Set-Cookie values into the Cookie header a browser would send back
+func cookieHeader(t *testing.T, setCookies []string) string {
+	t.Helper()
+	var pairs []string
+	for _, value := range setCookies {
+		c, err := http.ParseSetCookie(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.MaxAge >= 0 {
+			pairs = append(pairs, c.Name+"="+c.Value)
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// establish commits a new session holding a value and returns it with the browser's cookies
+func establish(t *testing.T, m *Manager) (*Session, string) {
+	t.Helper()
+	s := m.Load(context.Background(), "")
+	s.Set("theme", "dark")
+	setCookies, err := m.Commit(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, cookieHeader(t, setCookies)
+}
+
+func TestLoad(t *testing.T) {
+	stores := map[string]Options{
+		"cookie":    {},
+		"encrypted": {EncryptionKey: []byte("0123456789abcdef0123456789abcdef")},
+		"memory":    {Store: NewMemoryStore()},
+	}
+	for storeName, opts := range stores {
+		t.Run(storeName, func(t *testing.T) {
+			m := newTestManager(t, opts)
+			s, header := establish(t, m)
+			value := findCookie(header, DefaultCookieName)
+			payload, signature, _ := strings.Cut(value, ".")
+
+			expired, err := json.Marshal(record{ID: s.ID, Values: map[string]any{"theme": "dark"}, ExpiresAt: time.Now().Add(-time.Minute)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expiredCookie, err := m.codec.encode(DefaultCookieName, expired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.options.Store != nil {
+				if err := m.options.Store.Save(context.Background(), "expired", expired, time.Now().Add(-time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+				expiredCookie, _ = m.codec.encode(DefaultCookieName, []byte("expired"))
+			}
+			renamed, _ := m.codec.encode("other_cookie", []byte(s.ID))
+			otherManager, err := New(Options{Secret: []byte("another-secret-of-at-least-32-bytes")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			foreign, _ := otherManager.codec.encode(DefaultCookieName, []byte(s.ID))
+
+			tests := []struct {
+				name   string
+				cookie string
+				valid  bool
+			}{
+				{"valid", value, true},
+				{"tampered payload", "A" + payload[1:] + "." + signature, false},
+				{"tampered signature", payload + "." + strings.ToUpper(signature), false},
+				{"missing signature", payload, false},
+				{"signed for another cookie", renamed, false},
+				{"signed with another secret", foreign, false},
+				{"expired", expiredCookie, false},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					loaded := m.Load(context.Background(), DefaultCookieName+"="+tt.cookie)
+					if tt.valid {
+						if loaded.IsNew() || loaded.ID != s.ID || loaded.GetString("theme") != "dark" {
+							t.Fatalf("Load() = new %v, ID %q, theme %q; want the committed session", loaded.IsNew(), loaded.ID, loaded.GetString("theme"))
+						}
+						return
+					}
+					if !loaded.IsNew() || loaded.ID == s.ID || len(loaded.Values()) != 0 {
+						t.Fatalf("Load() restored a rejected cookie: new %v, values %v", loaded.IsNew(), loaded.Values())
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestLoginRotatesSession(t *testing.T) {
+	store := NewMemoryStore()
+	m := newTestManager(t, Options{Store: store})
+	s, header := establish(t, m)
+	oldID, oldToken := s.ID, findCookie(header, DefaultCSRFCookieName)
+
+	s = m.Load(context.Background(), header)
+	s.Login("user-1")
+	setCookies, err := m.Commit(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header = cookieHeader(t, setCookies)
+
+	if s.ID == oldID {
+		t.Fatal("Login() kept the session ID")
+	}
+	if _, err := store.Load(context.Background(), oldID); err != ErrNotFound {
+		t.Fatalf("previous session still stored: %v", err)
+	}
+	if m.ValidCSRFToken(oldToken, s.ID) {
+		t.Fatal("CSRF token of the previous session is valid for the rotated one")
+	}
+	if token := findCookie(header, DefaultCSRFCookieName); !m.ValidCSRFToken(token, s.ID) {
+		t.Fatalf("Commit() issued CSRF token %q not bound to the rotated session", token)
+	}
+	if loaded := m.Load(context.Background(), header); loaded.Subject() != "user-1" {
+		t.Fatalf("Load() subject = %q, want user-1", loaded.Subject())
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	m := newTestManager(t, Options{})
+	s, header := establish(t, m)
+	s = m.Load(context.Background(), header)
+	fresh := m.Load(context.Background(), "")
+
+	token := findCookie(header, DefaultCSRFCookieName)
+	otherSession := m.NewCSRFToken("other-session")
+	anonymous := m.NewCSRFToken("")
+	unsigned := "forged-token-value"
+
+	tests := []struct {
+		name      string
+		method    string
+		session   *Session
+		cookie    string
+		header    string
+		allowed   bool
+		newCookie bool
+	}{
+		{"GET without token", http.MethodGet, s, "", "", true, true},
+		{"GET with token", http.MethodGet, s, token, "", true, false},
+		{"HEAD with foreign token", http.MethodHead, s, otherSession, "", true, true},
+		{"POST matching", http.MethodPost, s, token, token, true, false},
+		{"DELETE matching", http.MethodDelete, s, token, token, true, false},
+		{"POST without header", http.MethodPost, s, token, "", false, false},
+		{"POST without cookie", http.MethodPost, s, "", token, false, true},
+		{"POST header mismatch", http.MethodPost, s, token, anonymous, false, false},
+		{"POST token of another session", http.MethodPost, s, otherSession, otherSession, false, true},
+		{"POST anonymous token with session", http.MethodPost, s, anonymous, anonymous, false, true},
+		{"POST unsigned token", http.MethodPost, s, unsigned, unsigned, false, true},
+		{"POST anonymous token without session", http.MethodPost, fresh, anonymous, anonymous, true, false},
+		{"POST session token without session", http.MethodPost, fresh, token, token, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookies := ""
+			if tt.cookie != "" {
+				cookies = DefaultCSRFCookieName + "=" + tt.cookie
+			}
+			result := m.CheckCSRF(tt.method, cookies, tt.header, tt.session)
+			if result.Allowed != tt.allowed {
+				t.Fatalf("CheckCSRF() allowed = %v, want %v", result.Allowed, tt.allowed)
+			}
+			if (result.SetCookie != "") != tt.newCookie {
+				t.Fatalf("CheckCSRF() SetCookie = %q, want new cookie %v", result.SetCookie, tt.newCookie)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store persists session data server-side
+type Store interface {
+	// Load returns the data for a session, or ErrNotFound if it doesn't exist or has expired
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save creates or replaces the data for a session
+	Save(ctx context.Context, id string, data []byte, expiresAt time.Time) error
+	// Delete removes a session. Deleting a missing session is not an error
+	Delete(ctx context.Context, id string) error
+}
+
+// ============================================================================
+// MEMORY STORE
+// ============================================================================
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on restart
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+// Load implements Store
+func (s *MemoryStore) Load(ctx context.Context, id string) ([]byte, error) {
+	s.mu.RLock()
+	entry, ok := s.sessions[id]
+	s.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, ErrNotFound
+	}
+	return entry.data, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(ctx context.Context, id string, data []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = memoryEntry{data: data, expiresAt: expiresAt}
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Cleanup removes expired sessions and returns how many were removed
+func (s *MemoryStore) Cleanup(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	now := time.Now()
+	for id, entry := range s.sessions {
+		if now.After(entry.expiresAt) {
+			delete(s.sessions, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ============================================================================
+// FILE STORE
+// ============================================================================
+
+// FileStore keeps one file per session in a directory
+type FileStore struct {
+	dir string
+}
+
+type fileEntry struct {
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("session: failed to create store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path hashes the ID so it can never escape the store directory
+func (s *FileStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load implements Store
+func (s *FileStore) Load(ctx context.Context, id string) ([]byte, error) {
+	raw, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry fileEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return entry.Data, nil
+}
+
+// Save implements Store
+func (s *FileStore) Save(ctx context.Context, id string, data []byte, expiresAt time.Time) error {
+	raw, err := json.Marshal(fileEntry{Data: data, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so readers never see partial data
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// Delete implements Store
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Cleanup removes expired session files and returns how many were removed
+func (s *FileStore) Cleanup(ctx context.Context) (int, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var entry fileEntry
+		if err := json.Unmarshal(raw, &entry); err != nil || now.After(entry.ExpiresAt) {
+			if os.Remove(file) == nil {
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,342 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// ContentType is the media type of server-sent event streams
+const ContentType = "text/event-stream"
+
+// DefaultEvent is the event name browsers deliver to EventSource.onmessage
+const DefaultEvent = "message"
+
+// Message is a server-sent event. The event name is looked up from the type of Data
+type Message struct {
+	// ID is sent as the event id; browsers send the last one back in Last-Event-ID when they reconnect
+	ID string
+	// Data is the event payload, encoded as JSON
+	Data any
+	// Retry tells the client how many milliseconds to wait before reconnecting (0 leaves it unchanged)
+	Retry int
+}
+
+// Options configures event streams
+type Options struct {
+	// Heartbeat is the interval of keep-alive comments that stop proxies from closing idle
+	// streams (default 15s, negative disables them)
+	Heartbeat time.Duration
+	// Retry is the reconnection delay in milliseconds announced when the stream opens (0 keeps the client default)
+	Retry int
+}
+
+// Events maps the Go types of event payloads to event names
+type Events struct {
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+// NewEvents creates an event set from event names and example payloads, e.g.
+//
+//	sse.NewEvents(map[string]any{"message": ChatMessage{}, "typing": TypingEvent{}})
+func NewEvents(eventTypes map[string]any) (*Events, error) {
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("sse: at least one event type is required")
+	}
+
+	events := &Events{
+		names: make(map[reflect.Type]string, len(eventTypes)),
+		types: make(map[string]reflect.Type, len(eventTypes)),
+	}
+	for name, example := range eventTypes {
+		if name == "" || strings.ContainsAny(name, "\r\n") {
+			return nil, fmt.Errorf("sse: invalid event name %q", name)
+		}
+		if example == nil {
+			return nil, fmt.Errorf("sse: event %q needs an example payload to derive its type", name)
+		}
+		t := deref(reflect.TypeOf(example))
+		if existing, ok := events.names[t]; ok {
+			return nil, fmt.Errorf("sse: events %q and %q share the payload type %s", existing, name, t)
+		}
+		events.names[t] = name
+		events.types[name] = t
+	}
+	return events, nil
+}
+
+// Names returns the event names, sorted
+func (e *Events) Names() []string {
+	names := make([]string, 0, len(e.types))
+	for name := range e.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the event name of a payload
+func (e *Events) Name(data any) (string, bool) {
+	if data == nil {
+		return "", false
+	}
+	name, ok := e.names[deref(reflect.TypeOf(data))]
+	return name, ok
+}
+
+// Schema documents the stream as an array of messages, one oneOf entry per event type,
+// the same shape Huma uses for its own SSE operations
+func (e *Events) Schema(registry huma.Registry) *huma.Schema {
+	names := e.Names()
+	messages := make([]*huma.Schema, 0, len(names))
+	for _, name := range names {
+		required := []string{"data"}
+		if name != DefaultEvent {
+			required = append(required, "event")
+		}
+		messages = append(messages, &huma.Schema{
+			Title: "Event " + name,
+			Type:  huma.TypeObject,
+			Properties: map[string]*huma.Schema{
+				"id": {
+					Type:        huma.TypeString,
+					Description: "The event ID, sent back in Last-Event-ID when the client reconnects.",
+				},
+				"event": {
+					Type:        huma.TypeString,
+					Description: "The event name.",
+					Extensions:  map[string]any{"const": name},
+				},
+				"data": registry.Schema(e.types[name], true, name),
+				"retry": {
+					Type:        huma.TypeInteger,
+					Description: "The reconnection delay in milliseconds.",
+				},
+			},
+			Required: required,
+		})
+	}
+
+	return &huma.Schema{
+		Title:       "Server-Sent Events",
+		Description: "Each oneOf object in the array is one possible server-sent event, serialized as UTF-8 text according to the SSE specification.",
+		Type:        huma.TypeArray,
+		Items: &huma.Schema{
+			Extensions: map[string]any{"oneOf": messages},
+		},
+	}
+}
+
+// Sender writes typed events to a huma.Context. The stream is opened lazily by the first
+// event or heartbeat, so a handler can still fail with a regular error response before that.
+// Close must be called once the handler returns
+type Sender struct {
+	ctx         huma.Context
+	events      *Events
+	options     Options
+	lastEventID string
+
+	mu      sync.Mutex
+	writer  io.Writer
+	flusher http.Flusher
+	started bool
+	closed  bool
+	err     error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSender prepares an event stream on ctx and starts its heartbeat
+func NewSender(ctx huma.Context, events *Events, options Options) *Sender {
+	if options.Heartbeat == 0 {
+		options.Heartbeat = 15 * time.Second
+	}
+
+	s := &Sender{
+		ctx:         ctx,
+		events:      events,
+		options:     options,
+		lastEventID: ctx.Header("Last-Event-ID"),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if options.Heartbeat > 0 {
+		go s.heartbeat()
+	} else {
+		close(s.done)
+	}
+	return s
+}
+
+// LastEventID returns the id of the last event the client received before reconnecting,
+// empty for a fresh subscription. Handlers use it to resume the stream
+func (s *Sender) LastEventID() string {
+	return s.lastEventID
+}
+
+// Data sends a payload as the event its type is registered for
+func (s *Sender) Data(data any) error {
+	return s.Send(Message{Data: data})
+}
+
+// Send writes an event and flushes it to the client. It fails once the client has disconnected
+func (s *Sender) Send(msg Message) error {
+	name, ok := s.events.Name(msg.Data)
+	if !ok {
+		return fmt.Errorf("sse: no event registered for payload type %T", msg.Data)
+	}
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return fmt.Errorf("sse: failed to encode %q event: %w", name, err)
+	}
+
+	var buf strings.Builder
+	if msg.ID != "" {
+		buf.WriteString("id: " + sanitize(msg.ID) + "\n")
+	}
+	if msg.Retry > 0 {
+		buf.WriteString("retry: " + strconv.Itoa(msg.Retry) + "\n")
+	}
+	if name != DefaultEvent {
+		buf.WriteString("event: " + name + "\n")
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+
+	return s.write(buf.String())
+}
+
+// Open sends the response headers without waiting for the first event, so clients see the
+// connection as established right away
+func (s *Sender) Open() error {
+	return s.write("")
+}
+
+// Started reports whether the response headers have been sent
+func (s *Sender) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// Close stops the heartbeat. Events sent afterwards fail
+func (s *Sender) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+}
+
+// write opens the stream if needed, then writes and flushes a chunk
+func (s *Sender) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("sse: stream is closed")
+	}
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Context().Err(); err != nil {
+		s.err = fmt.Errorf("sse: client disconnected: %w", err)
+		return s.err
+	}
+
+	if !s.started {
+		s.started = true
+		s.ctx.SetHeader("Content-Type", ContentType)
+		s.ctx.SetHeader("Cache-Control", "no-cache")
+		// Stop nginx and similar proxies from buffering the stream
+		s.ctx.SetHeader("X-Accel-Buffering", "no")
+		s.ctx.SetStatus(http.StatusOK)
+		s.writer = s.ctx.BodyWriter()
+		s.flusher = findFlusher(s.writer)
+		if s.options.Retry > 0 {
+			chunk = "retry: " + strconv.Itoa(s.options.Retry) + "\n\n" + chunk
+		}
+		if chunk == "" {
+			// A comment makes sure the headers actually go out when the writer buffers
+			chunk = ": open\n\n"
+		}
+	}
+
+	if _, err := io.WriteString(s.writer, chunk); err != nil {
+		s.err = fmt.Errorf("sse: failed to write event: %w", err)
+		return s.err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// heartbeat writes keep-alive comments until the sender is closed or the client goes away
+func (s *Sender) heartbeat() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.options.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Context().Done():
+			return
+		case <-ticker.C:
+			if err := s.write(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// findFlusher unwraps the body writer until it finds one that can flush
+func findFlusher(w io.Writer) http.Flusher {
+	for w != nil {
+		if flusher, ok := w.(http.Flusher); ok {
+			return flusher
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// sanitize strips characters that would end an SSE field early
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == 0 {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
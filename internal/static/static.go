@@ -5,6 +5,9 @@ import (
 	"io/fs"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/barisgit/goflux/internal/compress"
 )
 
 // StaticConfig configures static file serving behavior
@@ -17,6 +20,15 @@ type StaticConfig struct {
 	DevMode bool
 	// APIPrefix excludes paths starting with this prefix from static serving
 	APIPrefix string
+	// Compression compresses compressible files for clients that accept it (nil disables compression).
+	// Compressed files are cached, so each file is only encoded once per encoding
+	Compression *compress.Compressor
+}
+
+// StaticRequest contains the parts of an incoming request relevant to static serving, router-agnostic
+type StaticRequest struct {
+	Path           string
+	AcceptEncoding string
 }
 
 // StaticResponse contains the result of static file processing
@@ -26,10 +38,22 @@ type StaticResponse struct {
 	CacheControl string
 	Body         []byte
 	NotFound     bool
+	// ContentEncoding is set when Body is compressed
+	ContentEncoding string
+	// Vary is set when the response depends on request headers
+	Vary string
 }
 
 // ServeStaticFile is the core logic for serving static files, router-agnostic
 func ServeStaticFile(assets embed.FS, config StaticConfig, path string) StaticResponse {
+	return ServeStaticRequest(assets, config, StaticRequest{Path: path})
+}
+
+// ServeStaticRequest serves a static file like ServeStaticFile, compressing it when
+// config.Compression is set and the request's Accept-Encoding allows it
+func ServeStaticRequest(assets embed.FS, config StaticConfig, req StaticRequest) StaticResponse {
+	path := req.Path
+
 	// Set defaults
 	if config.APIPrefix == "" {
 		config.APIPrefix = "/api/"
@@ -106,13 +130,67 @@ func ServeStaticFile(assets embed.FS, config StaticConfig, path string) StaticRe
 		return StaticResponse{StatusCode: 500, NotFound: true}
 	}
 
-	return StaticResponse{
+	response := StaticResponse{
 		StatusCode:   200,
 		ContentType:  getContentType(cleanPath),
 		CacheControl: getCacheControl(cleanPath),
 		Body:         body,
 		NotFound:     false,
 	}
+
+	if config.Compression != nil {
+		compressResponse(&response, assets, config, cleanPath, req.AcceptEncoding)
+	}
+
+	return response
+}
+
+// compressedKey identifies a cached compressed file
+type compressedKey struct {
+	compressor *compress.Compressor
+	assets     embed.FS
+	assetsDir  string
+	path       string
+	encoding   string
+}
+
+// compressedFiles caches compressed bodies; embedded files never change at runtime
+var compressedFiles sync.Map
+
+// compressResponse replaces the response body with its negotiated compressed form
+func compressResponse(response *StaticResponse, assets embed.FS, config StaticConfig, path, acceptEncoding string) {
+	encoding, vary := config.Compression.Encoding(compress.Request{
+		Method:         "GET",
+		AcceptEncoding: acceptEncoding,
+		Status:         response.StatusCode,
+		ContentType:    response.ContentType,
+		Size:           len(response.Body),
+	})
+	if vary {
+		response.Vary = "Accept-Encoding"
+	}
+	if encoding == "" {
+		return
+	}
+
+	key := compressedKey{config.Compression, assets, config.AssetsDir, path, encoding}
+	if cached, ok := compressedFiles.Load(key); ok {
+		if body := cached.([]byte); body != nil {
+			response.Body = body
+			response.ContentEncoding = encoding
+		}
+		return
+	}
+
+	body, err := config.Compression.Compress(response.Body, encoding)
+	if err != nil || len(body) >= len(response.Body) {
+		// Remember incompressible files so they are not encoded again
+		compressedFiles.Store(key, []byte(nil))
+		return
+	}
+	compressedFiles.Store(key, body)
+	response.Body = body
+	response.ContentEncoding = encoding
 }
 
 // Helper functions for content type and cache control
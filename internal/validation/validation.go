@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Validator is implemented by inputs whose rules span several fields or need services, e.g. checking
+// that a slug is unique. It runs after the input was parsed and its struct tags were validated.
+// Problems are reported with AddError; a returned error aborts the request
+type Validator interface {
+	ValidateInput(ctx context.Context, v *Validation) error
+}
+
+// ErrAborted is returned by Resolve when a dependency failed to load and its error was already written
+var ErrAborted = errors.New("validation: request aborted")
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// Implements reports whether pointers to t are validators
+func Implements(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(validatorType)
+}
+
+// Resolver loads the dependency of a type for the current request
+type Resolver func(t reflect.Type) (reflect.Value, error)
+
+// Validation collects the problems found by the validators of a request and gives them access to
+// the request's dependencies
+type Validation struct {
+	resolve Resolver
+	details []*huma.ErrorDetail
+}
+
+// New creates a validation resolving dependencies with resolve
+func New(resolve Resolver) *Validation {
+	return &Validation{resolve: resolve}
+}
+
+// AddError reports a problem with the value at location, e.g. "body.slug" or "query.from"
+func (v *Validation) AddError(location, message string, value any) {
+	v.details = append(v.details, &huma.ErrorDetail{Location: location, Message: message, Value: value})
+}
+
+// Add reports a problem from an error. Errors with details, like *huma.ErrorDetail, keep their
+// location
+func (v *Validation) Add(err error) {
+	var detailer huma.ErrorDetailer
+	if errors.As(err, &detailer) {
+		v.details = append(v.details, detailer.ErrorDetail())
+		return
+	}
+	v.details = append(v.details, &huma.ErrorDetail{Message: err.Error()})
+}
+
+// HasErrors reports whether a problem was reported
+func (v *Validation) HasErrors() bool {
+	return len(v.details) > 0
+}
+
+// Errors returns the reported problems
+func (v *Validation) Errors() []error {
+	errs := make([]error, len(v.details))
+	for i, detail := range v.details {
+		errs[i] = detail
+	}
+	return errs
+}
+
+// Resolve returns the dependency of type t. Dependencies are loaded once per request and shared with
+// the handler
+func (v *Validation) Resolve(t reflect.Type) (reflect.Value, error) {
+	if v.resolve == nil {
+		return reflect.Value{}, fmt.Errorf("validation: no dependency of type %v", t)
+	}
+	return v.resolve(t)
+}
+
+// Run calls the validator of input, a pointer to a parsed input, if it has one. Returned errors with
+// details are reported as problems, other errors are returned
+func Run(ctx context.Context, v *Validation, input any) error {
+	validator, ok := input.(Validator)
+	if !ok {
+		return nil
+	}
+	err := validator.ValidateInput(ctx, v)
+	if err == nil {
+		return nil
+	}
+	var detailer huma.ErrorDetailer
+	if errors.As(err, &detailer) {
+		v.Add(err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package versioning
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension is the OpenAPI operation extension describing the version and deprecation of an
+// operation
+const Extension = "x-goflux-version"
+
+// VersionsExtension is the root OpenAPI extension listing the versions of an API. Versioned documents
+// carry their own version under Extension instead
+const VersionsExtension = "x-goflux-versions"
+
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Validate checks that a version can be used as a path segment, e.g. "v1" or "2024-06"
+func Validate(version string) error {
+	if !versionPattern.MatchString(version) {
+		return fmt.Errorf("versioning: invalid version %q, use letters, digits, '.', '_' and '-'", version)
+	}
+	return nil
+}
+
+// Path returns the path of an operation in a version. The version follows the /api prefix of paths
+// that have one, e.g. /api/users becomes /api/v1/users, and prefixes other paths
+func Path(version, path string) string {
+	if path == "/api" || strings.HasPrefix(path, "/api/") {
+		return "/api/" + version + strings.TrimPrefix(path, "/api")
+	}
+	return "/" + version + path
+}
+
+// Compare orders versions by their numbers, so v2 comes before v10 and 2024-06 before 2025-01.
+// Digit runs are compared numerically, other runs lexically
+func Compare(a, b string) int {
+	for a != "" && b != "" {
+		aRun, aRest := nextRun(a)
+		bRun, bRest := nextRun(b)
+		if isDigit(aRun[0]) && isDigit(bRun[0]) {
+			aNum, bNum := strings.TrimLeft(aRun, "0"), strings.TrimLeft(bRun, "0")
+			if c := cmp.Compare(len(aNum), len(bNum)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(aNum, bNum); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(aRun, bRun); c != 0 {
+			return c
+		}
+		a, b = aRest, bRest
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// nextRun splits the leading run of digits or non-digits off a version
+func nextRun(s string) (string, string) {
+	i := 1
+	for i < len(s) && isDigit(s[i]) == isDigit(s[0]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Deprecation marks operations as deprecated
+type Deprecation struct {
+	// Since is when the operation was deprecated. Zero announces the deprecation without a date
+	Since time.Time
+	// Sunset is when the operation stops working, zero if not planned yet
+	Sunset time.Time
+	// Successor links to the replacement of the operation, e.g. the path of its next version
+	Successor string
+}
+
+// WriteHeaders announces the deprecation with the Deprecation (RFC 9745), Sunset (RFC 8594) and
+// successor-version Link headers
+func (d *Deprecation) WriteHeaders(ctx huma.Context) {
+	if d.Since.IsZero() {
+		ctx.SetHeader("Deprecation", "true")
+	} else {
+		ctx.SetHeader("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		ctx.SetHeader("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		ctx.AppendHeader("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor))
+	}
+}
+
+// Spec is the extension describing the version and deprecation of an operation
+type Spec struct {
+	Version   string `json:"version,omitempty"`
+	Since     string `json:"deprecatedSince,omitempty"`
+	Sunset    string `json:"sunset,omitempty"`
+	Successor string `json:"successor,omitempty"`
+}
+
+// Annotate documents the version and deprecation of an operation, and lists its version in the
+// root extension
+func Annotate(operation *huma.Operation, oapi *huma.OpenAPI, version string, deprecation *Deprecation) {
+	if version == "" && deprecation == nil {
+		return
+	}
+	spec := Spec{Version: version}
+	if deprecation != nil {
+		operation.Deprecated = true
+		if !deprecation.Since.IsZero() {
+			spec.Since = deprecation.Since.UTC().Format(time.RFC3339)
+		}
+		if !deprecation.Sunset.IsZero() {
+			spec.Sunset = deprecation.Sunset.UTC().Format(time.RFC3339)
+		}
+		spec.Successor = deprecation.Successor
+	}
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = spec
+
+	if version == "" {
+		return
+	}
+	if oapi.Extensions == nil {
+		oapi.Extensions = map[string]any{}
+	}
+	versions, _ := oapi.Extensions[VersionsExtension].([]string)
+	if !slices.Contains(versions, version) {
+		versions = append(versions, version)
+		slices.SortFunc(versions, Compare)
+		oapi.Extensions[VersionsExtension] = versions
+	}
+}
+
+// Versions returns the versions of an API
+func Versions(oapi *huma.OpenAPI) []string {
+	versions, _ := oapi.Extensions[VersionsExtension].([]string)
+	return slices.Clone(versions)
+}
+
+// Document returns the OpenAPI document of a version: its operations and the unversioned ones. The
+// components are shared with the full document
+func Document(oapi *huma.OpenAPI, version string) *huma.OpenAPI {
+	document := *oapi
+	document.Extensions = maps.Clone(oapi.Extensions)
+	if document.Extensions == nil {
+		document.Extensions = map[string]any{}
+	}
+	delete(document.Extensions, VersionsExtension)
+	document.Extensions[Extension] = version
+
+	document.Paths = map[string]*huma.PathItem{}
+	for path, item := range oapi.Paths {
+		filtered := *item
+		keep := false
+		for _, operation := range []**huma.Operation{
+			&filtered.Get, &filtered.Put, &filtered.Post, &filtered.Delete,
+			&filtered.Options, &filtered.Head, &filtered.Patch, &filtered.Trace,
+		} {
+			if *operation == nil {
+				continue
+			}
+			if spec, ok := (*operation).Extensions[Extension].(Spec); ok && spec.Version != "" && spec.Version != version {
+				*operation = nil
+				continue
+			}
+			keep = true
+		}
+		if keep {
+			document.Paths[path] = &filtered
+		}
+	}
+	return &document
+}
@@ -0,0 +1,376 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Extension is the OpenAPI operation extension describing the messages of a WebSocket endpoint
+const Extension = "x-goflux-websocket"
+
+// ErrClosed is returned by Receive and Send once the connection has been closed
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Options configures WebSocket endpoints
+type Options struct {
+	// OriginPatterns lists the hosts allowed to connect from other origins, e.g. "app.example.com"
+	// or "*.example.com". Same-origin connections are always accepted
+	OriginPatterns []string
+	// Subprotocols lists the supported subprotocols in preference order
+	Subprotocols []string
+	// PingInterval is how often idle connections are checked with a ping (default 30s, negative disables)
+	PingInterval time.Duration
+	// ReadLimit is the largest inbound message in bytes (default 32KB)
+	ReadLimit int64
+}
+
+// MessageError reports a message that did not match its schema
+type MessageError struct {
+	// Direction is "inbound" for messages from the client and "outbound" for messages to it
+	Direction string
+	Errors    []error
+}
+
+func (e *MessageError) Error() string {
+	details := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		details[i] = err.Error()
+	}
+	return fmt.Sprintf("websocket: invalid %s message: %s", e.Direction, strings.Join(details, "; "))
+}
+
+// Conn is a WebSocket connection exchanging In messages from the client for Out messages to it.
+// Both are JSON documents validated against the schemas of their Go types
+type Conn[In, Out any] struct {
+	session *Session
+}
+
+// binder is implemented by every Conn instantiation so endpoints can be built from a handler's parameter type
+type binder interface {
+	bind(session *Session)
+	messageTypes() (in, out reflect.Type)
+}
+
+func (c *Conn[In, Out]) bind(session *Session) {
+	c.session = session
+}
+
+func (c *Conn[In, Out]) messageTypes() (reflect.Type, reflect.Type) {
+	return reflect.TypeFor[In](), reflect.TypeFor[Out]()
+}
+
+// Receive waits for the next message from the client. A *MessageError leaves the connection open;
+// ErrClosed means the client went away
+func (c *Conn[In, Out]) Receive(ctx context.Context) (In, error) {
+	var msg In
+	select {
+	case data, ok := <-c.session.messages:
+		if !ok {
+			return msg, c.session.closedErr()
+		}
+		if err := c.session.endpoint.decode(data, &msg); err != nil {
+			return msg, err
+		}
+		return msg, nil
+	case <-ctx.Done():
+		if c.session.ctx.Err() != nil {
+			return msg, c.session.closedErr()
+		}
+		return msg, ctx.Err()
+	}
+}
+
+// Send validates and writes a message to the client
+func (c *Conn[In, Out]) Send(ctx context.Context, msg Out) error {
+	data, err := c.session.endpoint.encode(msg)
+	if err != nil {
+		return err
+	}
+	if err := c.session.conn.Write(ctx, websocket.MessageText, data); err != nil {
+		if c.session.ctx.Err() != nil {
+			return c.session.closedErr()
+		}
+		return fmt.Errorf("websocket: failed to send message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection with a status code (1000 for a normal closure, 4000-4999 for application codes)
+func (c *Conn[In, Out]) Close(code int, reason string) error {
+	return c.session.close(websocket.StatusCode(code), reason)
+}
+
+// Subprotocol returns the negotiated subprotocol, empty when none was selected
+func (c *Conn[In, Out]) Subprotocol() string {
+	return c.session.conn.Subprotocol()
+}
+
+// Endpoint upgrades requests to connections of one Conn type
+type Endpoint struct {
+	connType  reflect.Type
+	inSchema  *huma.Schema
+	outSchema *huma.Schema
+	registry  huma.Registry
+	options   Options
+}
+
+// IsConn reports whether t is a *Conn[In, Out]
+func IsConn(t reflect.Type) bool {
+	if t == nil || t.Kind() != reflect.Pointer {
+		return false
+	}
+	_, ok := reflect.Zero(t).Interface().(binder)
+	return ok
+}
+
+// NewEndpoint creates an endpoint for connections of type connType (a *Conn[In, Out]),
+// registering the message schemas with registry
+func NewEndpoint(registry huma.Registry, connType reflect.Type, options Options) (*Endpoint, error) {
+	if !IsConn(connType) {
+		return nil, fmt.Errorf("websocket: %s is not a *Conn[In, Out]", connType)
+	}
+	if options.PingInterval == 0 {
+		options.PingInterval = 30 * time.Second
+	}
+	if options.ReadLimit <= 0 {
+		options.ReadLimit = 32 << 10
+	}
+
+	in, out := reflect.Zero(connType).Interface().(binder).messageTypes()
+	return &Endpoint{
+		connType:  connType,
+		inSchema:  registry.Schema(in, true, "WebSocketInbound"),
+		outSchema: registry.Schema(out, true, "WebSocketOutbound"),
+		registry:  registry,
+		options:   options,
+	}, nil
+}
+
+// Annotate documents the upgrade response and the message schemas of an operation. The
+// x-goflux-websocket extension lists the schema of the messages the client sends ("inbound")
+// and of those it receives ("outbound")
+func (e *Endpoint) Annotate(operation *huma.Operation) {
+	if operation.Extensions == nil {
+		operation.Extensions = map[string]any{}
+	}
+	operation.Extensions[Extension] = map[string]any{
+		"inbound":  e.inSchema,
+		"outbound": e.outSchema,
+	}
+	if operation.Responses == nil {
+		operation.Responses = map[string]*huma.Response{}
+	}
+	operation.Responses["101"] = &huma.Response{Description: "Switching Protocols to a WebSocket connection"}
+}
+
+// Accept upgrades the request to a WebSocket connection. On failure a response has already
+// been written, except for routers that do not expose net/http (ErrUnsupported)
+func (e *Endpoint) Accept(ctx huma.Context) (*Session, error) {
+	w, r, err := httpFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols:   e.options.Subprotocols,
+		OriginPatterns: e.options.OriginPatterns,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	conn.SetReadLimit(e.options.ReadLimit)
+
+	sessionCtx, cancel := context.WithCancel(ctx.Context())
+	session := &Session{
+		endpoint: e,
+		conn:     conn,
+		ctx:      sessionCtx,
+		cancel:   cancel,
+		messages: make(chan []byte),
+	}
+	go session.read()
+	if e.options.PingInterval > 0 {
+		go session.ping()
+	}
+	return session, nil
+}
+
+// decode validates an inbound message against its schema and unmarshals it
+func (e *Endpoint) decode(data []byte, v any) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return &MessageError{Direction: "inbound", Errors: []error{err}}
+	}
+	res := &huma.ValidateResult{}
+	huma.Validate(e.registry, e.inSchema, huma.NewPathBuffer([]byte{}, 0), huma.ModeWriteToServer, raw, res)
+	if len(res.Errors) > 0 {
+		return &MessageError{Direction: "inbound", Errors: res.Errors}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &MessageError{Direction: "inbound", Errors: []error{err}}
+	}
+	return nil
+}
+
+// encode marshals an outbound message and validates it against its schema
+func (e *Endpoint) encode(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: failed to encode message: %w", err)
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("websocket: failed to encode message: %w", err)
+	}
+	res := &huma.ValidateResult{}
+	huma.Validate(e.registry, e.outSchema, huma.NewPathBuffer([]byte{}, 0), huma.ModeReadFromServer, raw, res)
+	if len(res.Errors) > 0 {
+		return nil, &MessageError{Direction: "outbound", Errors: res.Errors}
+	}
+	return data, nil
+}
+
+// Session is an accepted connection. Its context is cancelled when the client disconnects
+type Session struct {
+	endpoint *Endpoint
+	conn     *websocket.Conn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	messages chan []byte
+
+	mu      sync.Mutex
+	readErr error
+}
+
+// Context returns the connection context, cancelled when the connection closes
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Bind returns a new *Conn of the endpoint's type for the session
+func (s *Session) Bind() reflect.Value {
+	conn := reflect.New(s.endpoint.connType.Elem())
+	conn.Interface().(binder).bind(s)
+	return conn
+}
+
+// Finish closes the connection after the handler returned. Huma status errors close it with
+// 4000 plus the HTTP status, other errors with 1011
+func (s *Session) Finish(err error) {
+	code, reason := websocket.StatusNormalClosure, ""
+	if err != nil && !errors.Is(err, ErrClosed) {
+		var se huma.StatusError
+		if errors.As(err, &se) {
+			code, reason = websocket.StatusCode(4000+se.GetStatus()), se.Error()
+		} else {
+			code, reason = websocket.StatusInternalError, "internal error"
+		}
+	}
+	_ = s.close(code, reason)
+}
+
+func (s *Session) close(code websocket.StatusCode, reason string) error {
+	// Close frame reasons are limited to 123 bytes
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	err := s.conn.Close(code, reason)
+	s.cancel()
+	if err != nil && s.ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// read pumps messages to Receive. Reading also handles pings and the close handshake,
+// so a disconnect is noticed even when the handler only sends
+func (s *Session) read() {
+	defer close(s.messages)
+	for {
+		_, data, err := s.conn.Read(s.ctx)
+		if err != nil {
+			s.mu.Lock()
+			s.readErr = err
+			s.mu.Unlock()
+			s.cancel()
+			return
+		}
+		select {
+		case s.messages <- data:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// ping checks the connection periodically and closes it when the client stops answering
+func (s *Session) ping() {
+	ticker := time.NewTicker(s.endpoint.options.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(s.ctx, s.endpoint.options.PingInterval)
+			err := s.conn.Ping(ctx)
+			cancel()
+			if err != nil {
+				s.cancel()
+				_ = s.conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) closedErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readErr != nil {
+		return fmt.Errorf("%w: %v", ErrClosed, s.readErr)
+	}
+	return ErrClosed
+}
+
+// ErrUnsupported is returned by Accept when the router does not expose a net/http response writer
+var ErrUnsupported = errors.New("websocket: the router adapter does not support net/http connection upgrades")
+
+// httpFromContext recovers the net/http response writer and request behind a huma.Context.
+// Adapters built on net/http (humago, humachi, humagin, humaecho) expose the writer as the body writer
+func httpFromContext(ctx huma.Context) (http.ResponseWriter, *http.Request, error) {
+	w, ok := ctx.BodyWriter().(http.ResponseWriter)
+	for !ok {
+		unwrapper, isWrapper := ctx.(interface{ Unwrap() huma.Context })
+		if !isWrapper {
+			return nil, nil, ErrUnsupported
+		}
+		ctx = unwrapper.Unwrap()
+		w, ok = ctx.BodyWriter().(http.ResponseWriter)
+	}
+
+	u := ctx.URL()
+	r, err := http.NewRequestWithContext(ctx.Context(), ctx.Method(), u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+	version := ctx.Version()
+	r.Proto, r.ProtoMajor, r.ProtoMinor = version.Proto, version.ProtoMajor, version.ProtoMinor
+	r.Host = ctx.Host()
+	r.RemoteAddr = ctx.RemoteAddr()
+	r.TLS = ctx.TLS()
+	ctx.EachHeader(func(name, value string) {
+		r.Header.Add(name, value)
+	})
+	return w, r, nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/barisgit/goflux/internal/flags"
+	"github.com/barisgit/goflux/internal/versioning"
 	"github.com/danielgtaylor/huma/v2"
 )
 
@@ -17,7 +20,7 @@ func GenerateSpecToFile(api huma.API, outputPath string) error {
 	}
 
 	// Generate OpenAPI JSON
-	spec, err := api.OpenAPI().MarshalJSON()
+	spec, err := document(api).MarshalJSON()
 	if err != nil {
 		return fmt.Errorf("failed to generate OpenAPI JSON: %w", err)
 	}
@@ -30,14 +33,48 @@ func GenerateSpecToFile(api huma.API, outputPath string) error {
 	return nil
 }
 
+// GenerateVersionSpecsToFiles saves the OpenAPI document of each API version next to outputPath,
+// e.g. build/openapi.v1.json for build/openapi.json, and returns the saved paths
+func GenerateVersionSpecsToFiles(api huma.API, outputPath string) ([]string, error) {
+	ext := filepath.Ext(outputPath)
+	base := strings.TrimSuffix(outputPath, ext)
+
+	var paths []string
+	for _, version := range versioning.Versions(api.OpenAPI()) {
+		versionDocument := versioning.Document(document(api), version)
+		var spec []byte
+		var err error
+		if ext == ".yaml" || ext == ".yml" {
+			spec, err = versionDocument.YAML()
+		} else {
+			spec, err = versionDocument.MarshalJSON()
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to generate OpenAPI document of version %s: %w", version, err)
+		}
+
+		path := base + "." + version + ext
+		if err := os.WriteFile(path, spec, 0644); err != nil {
+			return paths, fmt.Errorf("failed to save OpenAPI spec to %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
 // GenerateSpec generates an OpenAPI spec from a Huma API and returns it as bytes
 func GenerateSpec(api huma.API) ([]byte, error) {
-	return api.OpenAPI().MarshalJSON()
+	return document(api).MarshalJSON()
 }
 
 // GenerateSpecYAML generates an OpenAPI spec in YAML format
 func GenerateSpecYAML(api huma.API) ([]byte, error) {
-	return api.OpenAPI().YAML()
+	return document(api).YAML()
+}
+
+// document returns the spec to generate: operations behind feature flags that are off are left out
+func document(api huma.API) *huma.OpenAPI {
+	return flags.Document(api.OpenAPI(), flags.Default)
 }
 
 // GetRouteCount returns the number of routes in the API